@@ -22,8 +22,6 @@ const (
 // specifically it is used to compare RAS time values
 const Tolerance float64 = 0.000001
 
-const S3BucketTemplate = "https://%s.s3.amazonaws.com%s/%s"
-
 // fileExists checks if a file exists at the specified path.
 //
 // Parameters:
@@ -1,20 +1,21 @@
 package hdf
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"reflect"
 
-	"ras-runner/actions"
+	"ras-runner/eventdb"
 
+	"github.com/parquet-go/parquet-go"
 	"github.com/usace-cloud-compute/cc-go-sdk"
 )
 
 const (
 	breachLocationField string = "SaConn"
+	breachBlockName     string = "breach_records"
 )
 
 func init() {
@@ -44,26 +45,13 @@ type RasBreachExtractAction struct {
 //
 // Returns error if any step fails during execution.
 func (a *RasBreachExtractAction) Run() error {
-	var modelPrefix string
-	var plan string
-	var err error
+	modelResultsPath := localModelResultsPath(a.ActionRunnerBase)
 
-	modelPrefix, err = a.Action.Attributes.GetString("modelPrefix")
-	if err != nil {
-		modelPrefix = a.PluginManager.Attributes.GetStringOrFail("modelPrefix")
-	}
-
-	plan, err = a.Action.Attributes.GetString("plan")
-	if err != nil {
-		plan = a.PluginManager.Attributes.GetStringOrFail("plan")
-	}
-
-	modelResultsPath := fmt.Sprintf("%s/%s.p%s.tmp.hdf", actions.MODEL_DIR, modelPrefix, plan)
-
-	rb, err := NewRasBreachData(modelResultsPath)
+	f, err := openModelResults(a.Action, modelResultsPath)
 	if err != nil {
 		return err
 	}
+	rb := NewRasBreachDataFromFile(f)
 	defer rb.Close()
 
 	flowAreas, err := rb.FlowAreas2D()
@@ -90,22 +78,33 @@ func (a *RasBreachExtractAction) Run() error {
 		}
 	}
 
-	writer := JsonBreachDataExtractWriter{blockname: "breach_records"}
-	writer.Write(breachRecords)
-
-	json, err := json.Marshal(&writerAccumulator)
+	results := PluginRunResults(a.PluginManager)
+	format := RasExtractWriterType(a.Action.Attributes.GetStringOrDefault("outputformat", "json"))
+	var writer BreachDataExtractWriter
+	switch format {
+	case EventDbWriter:
+		writer = EventDbBreachDataExtractWriter{results: results, blockname: breachBlockName}
+	case ParquetWriter:
+		writer = ParquetBreachDataExtractWriter{results: results, blockname: breachBlockName}
+	default:
+		format = JsonWriter
+		writer = JsonBreachDataExtractWriter{results: results, blockname: breachBlockName}
+	}
+	err = writer.Write(breachRecords)
 	if err != nil {
 		return err
 	}
+	if a.Action.Attributes.GetBooleanOrDefault("accumulate-results", false) {
+		return nil
+	}
+	//json blocks share the one extract document, so every pending json block is written with the breach records.
+	//Other formats write only the breach records, blocks accumulated by other actions are left for flush-extract-results
+	names := []string{breachBlockName}
+	if format == JsonWriter {
+		names = nil
+	}
 	outputDataSource := a.Action.Attributes.GetStringOrFail("outputDataSource")
-	_, err = a.Action.Put(cc.PutOpInput{
-		SrcReader: bytes.NewReader(json),
-		DataSourceOpInput: cc.DataSourceOpInput{
-			DataSourceName: outputDataSource,
-			PathKey:        "extract",
-		},
-	})
-	return err
+	return writeExtractResults(a.ActionRunnerBase, results, format, names, nil, outputDataSource)
 }
 
 // BreachDataExtractWriter defines the interface for writing breach data records.
@@ -116,6 +115,7 @@ type BreachDataExtractWriter interface {
 // JsonBreachDataExtractWriter implements the BreachDataExtractWriter interface
 // for writing breach records in JSON format to an accumulator structure.
 type JsonBreachDataExtractWriter struct {
+	results   *ExtractResults
 	blockname string
 }
 
@@ -126,20 +126,128 @@ type JsonBreachDataExtractWriter struct {
 // 2. Extracts the location field (SaConn) as dataset name
 // 3. Formats the dataset path using the breachPathTemplate
 // 4. Creates an output block with the dataset and record data
-// 5. Appends the block to the writer accumulator under the blockname key
+// 5. Appends the block to the json blocks of the results under the blockname key
 //
-// This method populates the global writerAccumulator map for later JSON marshaling.
+// The block is added to the results even when there are no records, so it is
+// written as an empty block.
 func (writer JsonBreachDataExtractWriter) Write(recs []BreachRecord) error {
 	jsonRecs := breachRecordsToJsonAccumulatorMap(recs)
+	writer.results.mu.Lock()
+	defer writer.results.mu.Unlock()
+	block := writer.results.json[writer.blockname]
+	if block == nil {
+		block = []map[string]any{}
+	}
 	for _, br := range jsonRecs {
 		datasetName := br[breachLocationField]
 		dataset := fmt.Sprintf(breachPathTemplate, datasetName.(string))
 		outputBlock := RasExtractorOutputBlock[float32]{Dataset: dataset, Record: br}
-		writerAccumulator[writer.blockname] = append(writerAccumulator[writer.blockname], map[string]any{datasetName.(string): outputBlock})
+		block = append(block, map[string]any{datasetName.(string): outputBlock})
+	}
+	writer.results.json[writer.blockname] = block
+	return nil
+}
+
+// EventDbBreachDataExtractWriter implements the BreachDataExtractWriter interface
+// for writing breach records to the event database accumulator.
+type EventDbBreachDataExtractWriter struct {
+	results   *ExtractResults
+	blockname string
+}
+
+// Write accumulates each breach record under the 2D Hyd Conn dataset path of its
+// connection, with a field per record value.  NaN values are written as NULL.
+func (writer EventDbBreachDataExtractWriter) Write(recs []BreachRecord) error {
+	writer.results.mu.Lock()
+	defer writer.results.mu.Unlock()
+	block := writer.results.eventDbBlock(writer.blockname)
+	for _, br := range breachRecordsToJsonAccumulatorMap(recs) {
+		dataset := fmt.Sprintf(breachPathTemplate, br[breachLocationField].(string))
+		block.records = append(block.records, eventdb.Record{Dataset: dataset, Fields: br})
+	}
+	return nil
+}
+
+// parquetBreachRecord is a breach record with typed parquet columns.  NaN values
+// are written as null.
+type parquetBreachRecord struct {
+	EventID                   string   `parquet:"event_id,dict"`
+	FlowArea2D                string   `parquet:"flow_area_2d,dict"`
+	Connection                string   `parquet:"connection"`
+	Breached                  bool     `parquet:"breached"`
+	BreachStartTime           *float32 `parquet:"breach_start_time,optional"`
+	BreachIndex               int64    `parquet:"breach_index"`
+	MaxHW                     *float32 `parquet:"max_hw,optional"`
+	MaxTW                     *float32 `parquet:"max_tw,optional"`
+	MaxFlow                   *float32 `parquet:"max_flow,optional"`
+	MaxBottomWidth            *float32 `parquet:"max_bottom_width,optional"`
+	BreachProgressionDuration *float64 `parquet:"breach_progression_duration,optional"`
+	HWAtBreach                *float32 `parquet:"hw_at_breach,optional"`
+	TWAtBreach                *float32 `parquet:"tw_at_breach,optional"`
+}
+
+type parquetBreachRecords struct {
+	records []parquetBreachRecord
+}
+
+// write writes the breach records.  The records keep the event of the breach
+// record when it has one.
+func (pr *parquetBreachRecords) write(w io.Writer, eventIdentifier string, codec parquet.WriterOption) error {
+	for i := range pr.records {
+		if pr.records[i].EventID == "" {
+			pr.records[i].EventID = eventIdentifier
+		}
+	}
+	return writeParquet(w, pr.records, codec)
+}
+
+// ParquetBreachDataExtractWriter implements the BreachDataExtractWriter interface
+// for writing breach records to the parquet accumulator with a row per record.
+type ParquetBreachDataExtractWriter struct {
+	results   *ExtractResults
+	blockname string
+}
+
+func (writer ParquetBreachDataExtractWriter) Write(recs []BreachRecord) error {
+	writer.results.mu.Lock()
+	defer writer.results.mu.Unlock()
+	block, ok := writer.results.parquet[writer.blockname]
+	if !ok {
+		block = &parquetBreachRecords{}
+		writer.results.parquet[writer.blockname] = block
+	}
+	records, ok := block.(*parquetBreachRecords)
+	if !ok {
+		return fmt.Errorf("block %s is not a breach record block", writer.blockname)
+	}
+	for _, r := range recs {
+		records.records = append(records.records, parquetBreachRecord{
+			EventID:                   r.Event,
+			FlowArea2D:                r.FlowArea2D,
+			Connection:                r.SaConn,
+			Breached:                  r.Breached,
+			BreachStartTime:           nanSafe(r.BreachStartTime),
+			BreachIndex:               int64(r.BreachIndex),
+			MaxHW:                     nanSafe(r.MaxHW),
+			MaxTW:                     nanSafe(r.MaxTW),
+			MaxFlow:                   nanSafe(r.MaxFlow),
+			MaxBottomWidth:            nanSafe(r.MaxBottomWidth),
+			BreachProgressionDuration: nanSafe(r.BreachProgressionDuration),
+			HWAtBreach:                nanSafe(r.HWAtBreach),
+			TWAtBreach:                nanSafe(r.TWAtBreach),
+		})
 	}
 	return nil
 }
 
+// nanSafe returns nil for NaN values
+func nanSafe[T float32 | float64](val T) *T {
+	if math.IsNaN(float64(val)) {
+		return nil
+	}
+	return &val
+}
+
 // breachRecordsToJsonAccumulatorMap converts a slice of BreachRecord structs
 // into a slice of maps suitable for JSON marshaling.
 //
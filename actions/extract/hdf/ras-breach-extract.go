@@ -108,6 +108,12 @@ func NewRasBreachData(filepath string) (*RasBreach, error) {
 	return &rbd, nil
 }
 
+// NewRasBreachDataFromFile creates a new RasBreach instance from an open HDF5 file.
+// Closing the RasBreach closes the file.
+func NewRasBreachDataFromFile(f *hdf5.File) *RasBreach {
+	return &RasBreach{f: f}
+}
+
 // Close closes the underlying HDF5 file.
 func (rb *RasBreach) Close() {
 	rb.f.Close()
@@ -223,13 +229,18 @@ func (rb *RasBreach) readBreachAttributes(bd *BreachData, datapath string) error
 // It returns a slice of float64 values representing the time steps in days.
 // Returns an error if the time step data cannot be read.
 func (rb *RasBreach) readTimeSteps() ([]float64, error) {
+	return readDays(rb.f, timeStepInDaysPath)
+}
+
+// readDays reads a time dataset in days from the simulation start.
+func readDays(f *hdf5.File, datapath string) ([]float64, error) {
 	options := util.HdfReadOptions{
 		Dtype:        reflect.Float64,
 		ReadOnCreate: true,
-		File:         rb.f,
+		File:         f,
 	}
 
-	data, err := util.NewHdfDataset(timeStepInDaysPath, options)
+	data, err := util.NewHdfDataset(datapath, options)
 	if err != nil {
 		return nil, err
 	}
@@ -2,14 +2,20 @@ package hdf
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"log"
+	"os"
+	"path"
+	"path/filepath"
 	"reflect"
+	"strings"
 
 	"ras-runner/actions"
+	"ras-runner/netcdf"
+	"ras-runner/ras"
 
 	"github.com/usace-cloud-compute/cc-go-sdk"
+	"github.com/usace-cloud-compute/go-hdf5"
 )
 
 func init() {
@@ -41,27 +47,17 @@ type RasExtractAction struct {
 // It handles both immediate writing of results and accumulation for later writing
 // based on the 'accumulate-results' attribute.
 //
-// If 'accumulate-results' is true, the extracted data is stored in an accumulator
-// and can be written later using the specified 'outputDataSource'.
+// If 'accumulate-results' is true, the extracted data is stored in the results of
+// the plugin run and can be written later by an action with an 'outputDataSource',
+// such as flush-extract-results.  Otherwise the action writes its block-name, including
+// rows accumulated earlier in the run under the same block name.
 //
 // Returns an error if configuration is invalid or extraction fails.
 func (a *RasExtractAction) Run() error {
 
-	var modelPrefix string
-	var plan string
 	var err error
 
-	modelPrefix, err = a.Action.Attributes.GetString("modelPrefix")
-	if err != nil {
-		modelPrefix = a.PluginManager.Attributes.GetStringOrFail("modelPrefix")
-	}
-
-	plan, err = a.Action.Attributes.GetString("plan")
-	if err != nil {
-		plan = a.PluginManager.Attributes.GetStringOrFail("plan")
-	}
-
-	modelResultsPath := fmt.Sprintf("%s/%s.p%s.tmp.hdf", actions.MODEL_DIR, modelPrefix, plan)
+	modelResultsPath := localModelResultsPath(a.ActionRunnerBase)
 
 	blockName := a.Action.Attributes.GetStringOrDefault("block-name", "data")
 
@@ -101,6 +97,7 @@ func (a *RasExtractAction) Run() error {
 		WriterType:     RasExtractWriterType(a.Action.Attributes.GetStringOrDefault("outputformat", "console")),
 		WriteBlockName: blockName,
 		Accumulate:     a.Action.Attributes.GetBooleanOrDefault("accumulate-results", false),
+		Results:        PluginRunResults(a.PluginManager),
 	}
 
 	attrpath, err := a.Action.Attributes.GetString("attributepath")
@@ -110,14 +107,21 @@ func (a *RasExtractAction) Run() error {
 		return err
 	}
 
+	f, err := openModelResults(a.Action, modelResultsPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
 	if input.Attributes {
 		aeinput := AttributeExtractInput{
 			AttributePath:  input.DataPath,
 			AttributeNames: input.Colnames,
 			WriteBlockName: input.WriteBlockName,
+			Results:        input.Results,
 		}
 
-		err := AttributeExtract(aeinput, modelResultsPath)
+		err := AttributeExtractFile(aeinput, f)
 		if err != nil {
 			return err
 		}
@@ -132,19 +136,19 @@ func (a *RasExtractAction) Run() error {
 		input.DataType = dt
 		switch dt {
 		case reflect.Float32:
-			err = DataExtract[float32](input, modelResultsPath)
+			err = DataExtractFile[float32](input, f)
 		case reflect.Float64:
-			err = DataExtract[float64](input, modelResultsPath)
+			err = DataExtractFile[float64](input, f)
 		case reflect.Int:
-			err = DataExtract[int](input, modelResultsPath)
+			err = DataExtractFile[int](input, f)
 		case reflect.Int8:
-			err = DataExtract[int8](input, modelResultsPath)
+			err = DataExtractFile[int8](input, f)
 		case reflect.Int16:
-			err = DataExtract[int16](input, modelResultsPath)
+			err = DataExtractFile[int16](input, f)
 		case reflect.Int32:
-			err = DataExtract[int32](input, modelResultsPath)
+			err = DataExtractFile[int32](input, f)
 		case reflect.Int64:
-			err = DataExtract[int64](input, modelResultsPath)
+			err = DataExtractFile[int64](input, f)
 		}
 
 		if err != nil {
@@ -155,22 +159,288 @@ func (a *RasExtractAction) Run() error {
 	if input.Accumulate {
 		//do nothing
 		return nil
-	} else {
-		//refer to ras-extractor-writers for the writerAccumulator
-		json, err := json.Marshal(&writerAccumulator)
+	}
+	//json blocks share the one extract document, so every pending json block is written with it.  Other formats write
+	//only the block of this extract, blocks accumulated under other block names are left for flush-extract-results
+	format := input.WriterType
+	names := []string{blockName}
+	if input.Attributes {
+		//attribute extracts are json blocks whatever the output format
+		format = JsonWriter
+	} else if format == ConsoleWriter {
+		//console extracts are printed and not kept in the results
+		return nil
+	}
+	if format == JsonWriter {
+		names = nil
+	}
+	outputDataSource := a.Action.Attributes.GetStringOrFail("outputDataSource")
+	return writeExtractResults(a.ActionRunnerBase, input.Results, format, names, f, outputDataSource)
+}
+
+// putCsvExtracts writes the CSV of each block name to the path of the output data source with the block name
+func putCsvExtracts(action cc.Action, results *ExtractResults, outputDataSource string) error {
+	ds, err := action.GetOutputDataSource(outputDataSource)
+	if err != nil {
+		return fmt.Errorf("error getting output data source %s: %s", outputDataSource, err)
+	}
+	for _, blockName := range blockNames(results.csv) {
+		if _, ok := ds.Paths[blockName]; !ok {
+			return fmt.Errorf("output data source %s does not have a path for block %s", outputDataSource, blockName)
+		}
+		var buf bytes.Buffer
+		err = writeCsvExtractBlock(&buf, results.csv[blockName])
 		if err != nil {
 			return err
 		}
-		outputDataSource := a.Action.Attributes.GetStringOrFail("outputDataSource")
-		_, err = a.Action.Put(cc.PutOpInput{
-			SrcReader: bytes.NewReader(json),
+		_, err = action.Put(cc.PutOpInput{
+			SrcReader: &buf,
 			DataSourceOpInput: cc.DataSourceOpInput{
 				DataSourceName: outputDataSource,
-				PathKey:        "extract",
+				PathKey:        blockName,
 			},
 		})
-		//reset accumulator
-		writerAccumulator = make(map[string][]map[string]any)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putEventDb writes the event database blocks of the results to the local event database of the event and puts the database to the
+// "eventdb" path of the output data source.  The local database is kept in the model directory, so each extract of an
+// event adds its blocks to the same database.  The event identifier is the eventIdentifier attribute or the event
+// identifier of the plugin manager, and the database file is the eventDbFile attribute, by default eventdb.sqlite.
+func putEventDb(runner cc.ActionRunnerBase, results *ExtractResults, outputDataSource string) error {
+	eventIdentifier, err := extractEventIdentifier(runner)
+	if err != nil {
 		return err
 	}
+	dbName := runner.Action.Attributes.GetStringOrDefault("eventDbFile", "eventdb.sqlite")
+	dbPath := fmt.Sprintf("%v/%v", actions.MODEL_DIR, dbName)
+	err = writeEventDb(dbPath, eventIdentifier, results.eventDb)
+	if err != nil {
+		return fmt.Errorf("error writing the event database %s: %s", dbName, err)
+	}
+	f, err := os.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = runner.Action.Put(cc.PutOpInput{
+		SrcReader: f,
+		DataSourceOpInput: cc.DataSourceOpInput{
+			DataSourceName: outputDataSource,
+			PathKey:        "eventdb",
+		},
+	})
+	return err
+}
+
+// extractEventIdentifier is the eventIdentifier attribute or the event identifier of the plugin manager
+func extractEventIdentifier(runner cc.ActionRunnerBase) (string, error) {
+	eventIdentifier := runner.Action.Attributes.GetStringOrDefault("eventIdentifier", "")
+	if eventIdentifier == "" && runner.PluginManager != nil {
+		eventIdentifier = runner.PluginManager.EventIdentifier
+	}
+	if eventIdentifier == "" {
+		return "", fmt.Errorf("unable to write the extract without an event identifier")
+	}
+	return eventIdentifier, nil
+}
+
+// putParquetExtracts writes the parquet of each block name to the path of the output data source with the block
+// name.  Files are compressed with the compression attribute, by default zstd.
+func putParquetExtracts(runner cc.ActionRunnerBase, results *ExtractResults, outputDataSource string) error {
+	eventIdentifier, err := extractEventIdentifier(runner)
+	if err != nil {
+		return err
+	}
+	codec, err := parquetCompression(runner.Action.Attributes.GetStringOrDefault("compression", "zstd"))
+	if err != nil {
+		return err
+	}
+	ds, err := runner.Action.GetOutputDataSource(outputDataSource)
+	if err != nil {
+		return fmt.Errorf("error getting output data source %s: %s", outputDataSource, err)
+	}
+	for _, blockName := range blockNames(results.parquet) {
+		if _, ok := ds.Paths[blockName]; !ok {
+			return fmt.Errorf("output data source %s does not have a path for block %s", outputDataSource, blockName)
+		}
+		var buf bytes.Buffer
+		err = results.parquet[blockName].write(&buf, eventIdentifier, codec)
+		if err != nil {
+			return err
+		}
+		_, err = runner.Action.Put(cc.PutOpInput{
+			SrcReader: &buf,
+			DataSourceOpInput: cc.DataSourceOpInput{
+				DataSourceName: outputDataSource,
+				PathKey:        blockName,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putNetcdfExtracts writes the NetCDF of each block name to the path of the output data source with the block name.
+// The time coordinate is the Time dataset of the output block of the extracted datasets, in days from the simulation
+// start, and the units of each variable are the Units attribute of its dataset.  The stations attribute sets whether
+// the columns or the datasets of a block are the stations, by default the columns.
+func putNetcdfExtracts(runner cc.ActionRunnerBase, results *ExtractResults, f *hdf5.File, outputDataSource string) error {
+	stations := runner.Action.Attributes.GetStringOrDefault("stations", NetcdfColumnStations)
+	start, err := ras.ReadSimulationStartTime(f)
+	if err != nil {
+		return err
+	}
+	//the event identifier is only an attribute of the file, so it is not required
+	eventIdentifier, _ := extractEventIdentifier(runner)
+	ds, err := runner.Action.GetOutputDataSource(outputDataSource)
+	if err != nil {
+		return fmt.Errorf("error getting output data source %s: %s", outputDataSource, err)
+	}
+	for _, blockName := range blockNames(results.netcdf) {
+		if _, ok := ds.Paths[blockName]; !ok {
+			return fmt.Errorf("output data source %s does not have a path for block %s", outputDataSource, blockName)
+		}
+		ts := netcdfTimeSeries{start: start, units: map[string]string{}, eventIdentifier: eventIdentifier}
+		timePath := ""
+		for _, extract := range results.netcdf[blockName] {
+			extractTimePath, err := netcdfTimePath(f, extract.path)
+			if err != nil {
+				return err
+			}
+			if timePath != "" && extractTimePath != timePath {
+				return fmt.Errorf("the datasets of block %s are in different output blocks", blockName)
+			}
+			timePath = extractTimePath
+			if units, ok := readUnits(f, extract.path); ok && stations == NetcdfColumnStations {
+				ts.units[extract.name] = units
+			}
+		}
+		ts.days, err = readDays(f, timePath)
+		if err != nil {
+			return fmt.Errorf("unable to read the time %s: %s", timePath, err)
+		}
+		nc, err := netcdfExtractFile(blockName, results.netcdf[blockName], stations, ts)
+		if err != nil {
+			return err
+		}
+		err = putNetcdfFile(runner.Action, nc, outputDataSource, blockName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putNetcdfFile writes a NetCDF file to a temporary file, since NetCDF-4 files are written by the HDF5 library, and
+// puts it to a path of the output data source
+func putNetcdfFile(action cc.Action, nc *netcdf.File, outputDataSource string, pathKey string) error {
+	dir, err := os.MkdirTemp("", "netcdf")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	ncPath := filepath.Join(dir, pathKey+".nc")
+	err = nc.Create(ncPath)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(ncPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = action.Put(cc.PutOpInput{
+		SrcReader: f,
+		DataSourceOpInput: cc.DataSourceOpInput{
+			DataSourceName: outputDataSource,
+			PathKey:        pathKey,
+		},
+	})
+	return err
+}
+
+// netcdfTimePath finds the Time dataset of the output block of a dataset, the closest Time dataset in the groups
+// above it
+func netcdfTimePath(f *hdf5.File, datasetPath string) (string, error) {
+	for dir := path.Dir(datasetPath); dir != "/" && dir != "."; dir = path.Dir(dir) {
+		timePath := dir + "/Time"
+		if timePath != datasetPath && f.LinkExists(timePath) {
+			return timePath, nil
+		}
+	}
+	return "", fmt.Errorf("unable to find the Time dataset of %s", datasetPath)
+}
+
+// readUnits reads the Units attribute of a dataset
+func readUnits(f *hdf5.File, datasetPath string) (string, bool) {
+	ds, err := f.OpenDataset(datasetPath)
+	if err != nil {
+		return "", false
+	}
+	defer ds.Close()
+	attr, err := ds.OpenAttribute("Units")
+	if err != nil {
+		return "", false
+	}
+	defer attr.Close()
+	var units string
+	err = attr.Read(&units, hdf5.T_GO_STRING)
+	if err != nil {
+		return "", false
+	}
+	return units, true
+}
+
+// template variable of the key of a Zarr store in the path of the output data source
+const zarrKeyVar string = "zarr_key"
+
+// putZarrExtracts writes the Zarr store of each block name to the output data source.  The path with the block name
+// is the root of the store and must include {VAR::zarr_key}, which is replaced by the key of each metadata document and
+// chunk.  Chunks are compressed with the compression attribute, by default zlib.
+func putZarrExtracts(runner cc.ActionRunnerBase, results *ExtractResults, outputDataSource string) error {
+	compression := runner.Action.Attributes.GetStringOrDefault("compression", "zlib")
+	compressor, ok := zarrCompressors[compression]
+	if !ok {
+		return fmt.Errorf("unsupported zarr compression %q", compression)
+	}
+	//the event identifier is only an attribute of the store, so it is not required
+	eventIdentifier, _ := extractEventIdentifier(runner)
+	ds, err := runner.Action.GetOutputDataSource(outputDataSource)
+	if err != nil {
+		return fmt.Errorf("error getting output data source %s: %s", outputDataSource, err)
+	}
+	for _, blockName := range blockNames(results.zarr) {
+		storePath, ok := ds.Paths[blockName]
+		if !ok {
+			return fmt.Errorf("output data source %s does not have a path for block %s", outputDataSource, blockName)
+		}
+		if !strings.Contains(storePath, "{VAR::"+zarrKeyVar+"}") {
+			return fmt.Errorf("output data source %s path for block %s does not include {VAR::%s}", outputDataSource, blockName, zarrKeyVar)
+		}
+		store, err := zarrExtractStore(blockName, results.zarr[blockName], eventIdentifier, compressor)
+		if err != nil {
+			return err
+		}
+		for _, key := range store.Keys() {
+			_, err = runner.Action.Put(cc.PutOpInput{
+				SrcReader: bytes.NewReader(store[key]),
+				DataSourceOpInput: cc.DataSourceOpInput{
+					DataSourceName: outputDataSource,
+					PathKey:        blockName,
+					TemplateVars:   map[string]string{zarrKeyVar: key},
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("error writing %s of block %s: %s", key, blockName, err)
+			}
+		}
+	}
+	return nil
 }
@@ -0,0 +1,198 @@
+package hdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+	"github.com/usace-cloud-compute/go-hdf5"
+)
+
+/*
+ExtractResults holds the blocks written by the extract writers until they are written to an output data source.
+Results are scoped to a plugin run: every action run by the same plugin manager shares one ExtractResults, so blocks
+accumulated with accumulate-results by one action are written by a later action of the same run, and never by another
+run.  The results of a run are released with ReleasePluginRunResults when its actions have run.  Writers and actions may use the results concurrently.
+
+Each output format keeps its own blocks by block name.  Writing the results of a format removes the written blocks, so
+a block is written once, and a block that fails to write is kept for a later action.
+*/
+type ExtractResults struct {
+	mu      sync.Mutex
+	json    map[string][]map[string]any
+	csv     map[string]*csvExtractBlock
+	eventDb map[string]*eventDbBlock
+	parquet map[string]parquetExtractBlock
+	netcdf  map[string][]netcdfExtractDataset
+	zarr    map[string][]zarrExtract
+}
+
+func NewExtractResults() *ExtractResults {
+	return &ExtractResults{
+		json:    make(map[string][]map[string]any),
+		csv:     make(map[string]*csvExtractBlock),
+		eventDb: make(map[string]*eventDbBlock),
+		parquet: make(map[string]parquetExtractBlock),
+		netcdf:  make(map[string][]netcdfExtractDataset),
+		zarr:    make(map[string][]zarrExtract),
+	}
+}
+
+var (
+	runResultsMu sync.Mutex
+	runResults   map[*cc.PluginManager]*ExtractResults = make(map[*cc.PluginManager]*ExtractResults)
+)
+
+// PluginRunResults returns the results of the plugin run of a plugin manager, creating them for the first action of
+// the run
+func PluginRunResults(pm *cc.PluginManager) *ExtractResults {
+	runResultsMu.Lock()
+	defer runResultsMu.Unlock()
+	results, ok := runResults[pm]
+	if !ok {
+		results = NewExtractResults()
+		runResults[pm] = results
+	}
+	return results
+}
+
+// ReleasePluginRunResults removes the results of the plugin run of a plugin manager after its actions have run.  Blocks
+// that were accumulated but never written are dropped with the results, so they are returned as an error to fail the run.
+func ReleasePluginRunResults(pm *cc.PluginManager) error {
+	runResultsMu.Lock()
+	results, ok := runResults[pm]
+	delete(runResults, pm)
+	runResultsMu.Unlock()
+	if !ok {
+		return nil
+	}
+	if blocks := results.unwritten(); len(blocks) > 0 {
+		return fmt.Errorf("accumulated extract results were not written, add a flush-extract-results action to write them: %s", strings.Join(blocks, ", "))
+	}
+	return nil
+}
+
+// unwritten names the blocks left in the results as format/block
+func (r *ExtractResults) unwritten() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	blocks := []string{}
+	add := func(format RasExtractWriterType, names []string) {
+		for _, name := range names {
+			blocks = append(blocks, fmt.Sprintf("%s/%s", format, name))
+		}
+	}
+	add(JsonWriter, blockNames(r.json))
+	add(CsvWriter, blockNames(r.csv))
+	add(EventDbWriter, blockNames(r.eventDb))
+	add(ParquetWriter, blockNames(r.parquet))
+	add(NetcdfWriter, blockNames(r.netcdf))
+	add(ZarrWriter, blockNames(r.zarr))
+	return blocks
+}
+
+// selectBlocks returns blocks of an output format as new results, and a function that removes the selected blocks
+// from the results.  All of the blocks of the format are selected when no block names are given.  No blocks are
+// selected when a block name is not in the results.  The caller holds the results lock until the blocks are removed.
+func (r *ExtractResults) selectBlocks(format RasExtractWriterType, names []string) (*ExtractResults, func(), error) {
+	selected := NewExtractResults()
+	var remove func()
+	var err error
+	switch format {
+	case JsonWriter:
+		selected.json, remove, err = selectFormatBlocks(r.json, format, names)
+	case CsvWriter:
+		selected.csv, remove, err = selectFormatBlocks(r.csv, format, names)
+	case EventDbWriter:
+		selected.eventDb, remove, err = selectFormatBlocks(r.eventDb, format, names)
+	case ParquetWriter:
+		selected.parquet, remove, err = selectFormatBlocks(r.parquet, format, names)
+	case NetcdfWriter:
+		selected.netcdf, remove, err = selectFormatBlocks(r.netcdf, format, names)
+	case ZarrWriter:
+		selected.zarr, remove, err = selectFormatBlocks(r.zarr, format, names)
+	default:
+		return nil, nil, fmt.Errorf("%s results are not accumulated", format)
+	}
+	return selected, remove, err
+}
+
+func selectFormatBlocks[V any](blocks map[string]V, format RasExtractWriterType, names []string) (map[string]V, func(), error) {
+	if len(names) == 0 {
+		names = blockNames(blocks)
+	}
+	selected := make(map[string]V, len(names))
+	for _, name := range names {
+		block, ok := blocks[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("no %s results for block %s", format, name)
+		}
+		selected[name] = block
+	}
+	remove := func() {
+		for name := range selected {
+			delete(blocks, name)
+		}
+	}
+	return selected, remove, nil
+}
+
+// blockNames are the names of blocks in sorted order
+func blockNames[V any](blocks map[string]V) []string {
+	return slices.Sorted(maps.Keys(blocks))
+}
+
+// writeExtractResults writes blocks of an output format from the results to the output data source and removes the
+// written blocks from the results.  All of the blocks of the format are written when no block names are given.  The
+// blocks are kept in the results when they cannot be written, and writers wait for the write so blocks are not
+// changed while they are written.  The NetCDF writer reads the time and units of its datasets from the model results
+// file f, which is not used by the other formats.
+func writeExtractResults(runner cc.ActionRunnerBase, results *ExtractResults, format RasExtractWriterType, names []string, f *hdf5.File, outputDataSource string) error {
+	results.mu.Lock()
+	defer results.mu.Unlock()
+	selected, remove, err := results.selectBlocks(format, names)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case EventDbWriter:
+		err = putEventDb(runner, selected, outputDataSource)
+	case ZarrWriter:
+		err = putZarrExtracts(runner, selected, outputDataSource)
+	case NetcdfWriter:
+		err = putNetcdfExtracts(runner, selected, f, outputDataSource)
+	case ParquetWriter:
+		err = putParquetExtracts(runner, selected, outputDataSource)
+	case CsvWriter:
+		err = putCsvExtracts(runner.Action, selected, outputDataSource)
+	default:
+		err = putJsonExtracts(runner.Action, selected, outputDataSource)
+	}
+	if err != nil {
+		return err
+	}
+	remove()
+	return nil
+}
+
+// putJsonExtracts writes the JSON blocks to the "extract" path of the output data source
+func putJsonExtracts(action cc.Action, results *ExtractResults, outputDataSource string) error {
+	//refer to ras-extractor-writers for the json blocks
+	json, err := json.Marshal(&results.json)
+	if err != nil {
+		return err
+	}
+	_, err = action.Put(cc.PutOpInput{
+		SrcReader: bytes.NewReader(json),
+		DataSourceOpInput: cc.DataSourceOpInput{
+			DataSourceName: outputDataSource,
+			PathKey:        "extract",
+		},
+	})
+	return err
+}
@@ -1,9 +1,21 @@
 package hdf
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ras-runner/eventdb"
+	"ras-runner/netcdf"
+	"ras-runner/zarr"
+
+	"github.com/parquet-go/parquet-go"
 )
 
 type RasExtractWriterAction string
@@ -17,9 +29,6 @@ type RasDataExtractWriter[T RasExtractDataTypes] interface {
 	Write(WriteRasDataInput[T]) error
 }
 
-// var writerAccumulator map[string][]map[string]any = make(map[string][]map[string]any)
-var writerAccumulator map[string][]map[string]any = make(map[string][]map[string]any)
-
 // =============================================================================
 // JSON writer
 // =============================================================================
@@ -32,12 +41,13 @@ type RasExtractorOutputBlock[T RasExtractDataTypes] struct {
 	RasExtractData RasExtractData[T] `json:"-"`
 }
 
-func NewJsonRasExtractWriter[T RasExtractDataTypes](blockName string, datasetnum int) (RasDataExtractWriter[T], error) {
-	writer := JsonRasExtractWriter[T]{blockName: blockName}
+func NewJsonRasExtractWriter[T RasExtractDataTypes](results *ExtractResults, blockName string, datasetnum int) (RasDataExtractWriter[T], error) {
+	writer := JsonRasExtractWriter[T]{results: results, blockName: blockName}
 	return &writer, nil
 }
 
 type JsonRasExtractWriter[T RasExtractDataTypes] struct {
+	results   *ExtractResults
 	blockName string
 }
 
@@ -78,26 +88,768 @@ func (rw *JsonRasExtractWriter[T]) Write(input WriteRasDataInput[T]) error {
 	}
 
 	//adds a new extract to a block section
-	writerAccumulator[rw.blockName] = append(writerAccumulator[rw.blockName], map[string]any{input.datasetName: block})
+	rw.results.mu.Lock()
+	defer rw.results.mu.Unlock()
+	rw.results.json[rw.blockName] = append(rw.results.json[rw.blockName], map[string]any{input.datasetName: block})
+	return nil
+}
+
+// =============================================================================
+// CSV writer
+// =============================================================================
+
+// csvExtractBlock is the header and rows of the CSV written for a block name
+type csvExtractBlock struct {
+	header []string
+	rows   [][]string
+}
+
+// csv columns written before the dataset columns
+var csvExtractIdColumns []string = []string{"dataset", "summary", "timestep"}
+
+func NewCsvRasExtractWriter[T RasExtractDataTypes](results *ExtractResults, blockName string) (RasDataExtractWriter[T], error) {
+	writer := CsvRasExtractWriter[T]{results: results, blockName: blockName}
+	return &writer, nil
+}
+
+// CsvRasExtractWriter writes a flat CSV for each block name.  Each dataset is written as a row per summary, with an
+// empty timestep, and a row per time step, with an empty summary.  Dataset columns are named by the column names or
+// are numbered when there are none, and every dataset in a block must have the same columns.  NaN values are written
+// as empty cells.
+type CsvRasExtractWriter[T RasExtractDataTypes] struct {
+	results   *ExtractResults
+	blockName string
+}
+
+func (rw *CsvRasExtractWriter[T]) Write(input WriteRasDataInput[T]) error {
+	cols := len(input.Colnames)
+	if cols == 0 {
+		if len(input.Data.data) > 0 {
+			cols = len(input.Data.data[0])
+		}
+		for _, vals := range input.Data.summaries {
+			cols = max(cols, len(vals))
+		}
+	}
+	colnames := input.Colnames
+	if len(colnames) == 0 {
+		colnames = make([]string, cols)
+		for i := range colnames {
+			colnames[i] = strconv.Itoa(i)
+		}
+	}
+	header := append(append([]string{}, csvExtractIdColumns...), colnames...)
+
+	rw.results.mu.Lock()
+	defer rw.results.mu.Unlock()
+	block, ok := rw.results.csv[rw.blockName]
+	if !ok {
+		block = &csvExtractBlock{header: header}
+	} else if !slices.Equal(block.header, header) {
+		return fmt.Errorf("the columns of %s do not match the columns of block %s", input.OutputName, rw.blockName)
+	}
+
+	rows := [][]string{}
+	if input.WriteSummary {
+		names := make([]string, 0, len(input.Data.summaries))
+		for name := range input.Data.summaries {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			row, err := csvExtractRow(input.datasetName, name, "", input.Data.summaries[name], cols)
+			if err != nil {
+				return fmt.Errorf("invalid %s summary of %s: %s", name, input.OutputName, err)
+			}
+			rows = append(rows, row)
+		}
+	}
+	if input.WriteData {
+		for step, vals := range input.Data.data {
+			row, err := csvExtractRow(input.datasetName, "", strconv.Itoa(step), vals, cols)
+			if err != nil {
+				return fmt.Errorf("invalid time step %d of %s: %s", step, input.OutputName, err)
+			}
+			rows = append(rows, row)
+		}
+	}
+	block.rows = append(block.rows, rows...)
+	rw.results.csv[rw.blockName] = block
 	return nil
 }
 
+func csvExtractRow[T RasExtractDataTypes](dataset string, summary string, step string, vals []T, cols int) ([]string, error) {
+	if len(vals) != cols {
+		return nil, fmt.Errorf("%d values for %d columns", len(vals), cols)
+	}
+	row := make([]string, 0, len(csvExtractIdColumns)+len(vals))
+	row = append(row, dataset, summary, step)
+	for _, val := range vals {
+		row = append(row, formatCsvExtractValue(val))
+	}
+	return row, nil
+}
+
+func formatCsvExtractValue[T RasExtractDataTypes](val T) string {
+	switch v := any(val).(type) {
+	case float32:
+		if math.IsNaN(float64(v)) {
+			return ""
+		}
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		if math.IsNaN(v) {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeCsvExtractBlock writes the accumulated CSV of a block
+func writeCsvExtractBlock(w io.Writer, block *csvExtractBlock) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(block.header)
+	if err != nil {
+		return err
+	}
+	err = writer.WriteAll(block.rows)
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// =============================================================================
+// Event database writer
+// =============================================================================
+
+// eventDbBlock is the datasets and records of a block name written to the event database
+type eventDbBlock struct {
+	datasets []eventdb.Dataset
+	records  []eventdb.Record
+}
+
+func NewEventDbRasExtractWriter[T RasExtractDataTypes](results *ExtractResults, blockName string) (RasDataExtractWriter[T], error) {
+	writer := EventDbRasExtractWriter[T]{results: results, blockName: blockName}
+	return &writer, nil
+}
+
+// EventDbRasExtractWriter accumulates datasets for the event database.  Datasets are named by the dataset name and
+// their columns by the column names, or by index when there are none.
+type EventDbRasExtractWriter[T RasExtractDataTypes] struct {
+	results   *ExtractResults
+	blockName string
+}
+
+func (rw *EventDbRasExtractWriter[T]) Write(input WriteRasDataInput[T]) error {
+	ds := eventdb.Dataset{Name: input.datasetName, Columns: input.Colnames}
+	if input.WriteSummary {
+		ds.Summaries = make(map[string][]any, len(input.Data.summaries))
+		for name, vals := range input.Data.summaries {
+			ds.Summaries[name] = eventDbValues(vals)
+		}
+	}
+	if input.WriteData {
+		ds.Data = make([][]any, len(input.Data.data))
+		for step, vals := range input.Data.data {
+			ds.Data[step] = eventDbValues(vals)
+		}
+	}
+	rw.results.mu.Lock()
+	defer rw.results.mu.Unlock()
+	block := rw.results.eventDbBlock(rw.blockName)
+	block.datasets = append(block.datasets, ds)
+	return nil
+}
+
+func eventDbValues[T RasExtractDataTypes](vals []T) []any {
+	out := make([]any, len(vals))
+	for i, val := range vals {
+		out[i] = val
+	}
+	return out
+}
+
+// eventDbBlock returns the event database block of a block name, adding it when the results do not have it.  The
+// caller must hold the results lock.
+func (r *ExtractResults) eventDbBlock(blockName string) *eventDbBlock {
+	block, ok := r.eventDb[blockName]
+	if !ok {
+		block = &eventDbBlock{}
+		r.eventDb[blockName] = block
+	}
+	return block
+}
+
+// writeEventDb writes blocks of an event to the event database at dbPath, replacing the rows the event has for each
+// block
+func writeEventDb(dbPath string, eventIdentifier string, blocks map[string]*eventDbBlock) error {
+	edb, err := eventdb.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer edb.Close()
+	for _, name := range blockNames(blocks) {
+		block := blocks[name]
+		err = edb.WriteBlock(eventIdentifier, name, block.datasets, block.records)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// Parquet writer
+// =============================================================================
+
+// parquetExtractRow is a value of a dataset column in a summary or at a time step.  The value column has the type of
+// the extracted dataset and is null for NaN values.  The event identifier is set when the block is written.
+type parquetExtractRow[T RasExtractDataTypes] struct {
+	EventID     string  `parquet:"event_id,dict"`
+	Dataset     string  `parquet:"dataset,dict"`
+	Summary     *string `parquet:"summary,optional,dict"`
+	Timestep    *int64  `parquet:"timestep,optional"`
+	ColumnIndex int32   `parquet:"column_index"`
+	ColumnName  string  `parquet:"column_name,dict"`
+	Value       *T      `parquet:"value,optional"`
+}
+
+// parquetExtractBlock is the accumulated rows of a block name
+type parquetExtractBlock interface {
+	write(w io.Writer, eventIdentifier string, codec parquet.WriterOption) error
+}
+
+type parquetExtractRows[T RasExtractDataTypes] struct {
+	rows []parquetExtractRow[T]
+}
+
+func (pr *parquetExtractRows[T]) write(w io.Writer, eventIdentifier string, codec parquet.WriterOption) error {
+	for i := range pr.rows {
+		pr.rows[i].EventID = eventIdentifier
+	}
+	return writeParquet(w, pr.rows, codec)
+}
+
+// writeParquet writes rows to a parquet file
+func writeParquet[R any](w io.Writer, rows []R, codec parquet.WriterOption) error {
+	writer := parquet.NewGenericWriter[R](w, codec)
+	_, err := writer.Write(rows)
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// parquet compression codecs by name
+var parquetCompressionCodecs map[string]parquet.WriterOption = map[string]parquet.WriterOption{
+	"none":   parquet.Compression(&parquet.Uncompressed),
+	"snappy": parquet.Compression(&parquet.Snappy),
+	"gzip":   parquet.Compression(&parquet.Gzip),
+	"zstd":   parquet.Compression(&parquet.Zstd),
+}
+
+// parquetCompression returns the writer option of a compression codec name
+func parquetCompression(name string) (parquet.WriterOption, error) {
+	codec, ok := parquetCompressionCodecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported parquet compression %q", name)
+	}
+	return codec, nil
+}
+
+func NewParquetRasExtractWriter[T RasExtractDataTypes](results *ExtractResults, blockName string) (RasDataExtractWriter[T], error) {
+	writer := ParquetRasExtractWriter[T]{results: results, blockName: blockName}
+	return &writer, nil
+}
+
+// ParquetRasExtractWriter writes a parquet file for each block name with a row per dataset column of each summary and
+// time step.  Columns are named by the column names or by index when there are none.  The value column has the type
+// of the extracted data, so every dataset in a block must have the same data type.
+type ParquetRasExtractWriter[T RasExtractDataTypes] struct {
+	results   *ExtractResults
+	blockName string
+}
+
+func (rw *ParquetRasExtractWriter[T]) Write(input WriteRasDataInput[T]) error {
+	rw.results.mu.Lock()
+	defer rw.results.mu.Unlock()
+	var rows *parquetExtractRows[T]
+	block, ok := rw.results.parquet[rw.blockName]
+	if !ok {
+		rows = &parquetExtractRows[T]{}
+		rw.results.parquet[rw.blockName] = rows
+	} else if rows, ok = block.(*parquetExtractRows[T]); !ok {
+		return fmt.Errorf("the data type of %s does not match the data type of block %s", input.OutputName, rw.blockName)
+	}
+
+	if input.WriteSummary {
+		names := make([]string, 0, len(input.Data.summaries))
+		for name := range input.Data.summaries {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			summary := name
+			rows.rows = appendParquetExtractRows(rows.rows, input, &summary, nil, input.Data.summaries[name])
+		}
+	}
+	if input.WriteData {
+		for step, vals := range input.Data.data {
+			timestep := int64(step)
+			rows.rows = appendParquetExtractRows(rows.rows, input, nil, &timestep, vals)
+		}
+	}
+	return nil
+}
+
+func appendParquetExtractRows[T RasExtractDataTypes](rows []parquetExtractRow[T], input WriteRasDataInput[T], summary *string, timestep *int64, vals []T) []parquetExtractRow[T] {
+	for idx, val := range vals {
+		row := parquetExtractRow[T]{
+			Dataset:     input.datasetName,
+			Summary:     summary,
+			Timestep:    timestep,
+			ColumnIndex: int32(idx),
+			ColumnName:  strconv.Itoa(idx),
+		}
+		if idx < len(input.Colnames) {
+			row.ColumnName = input.Colnames[idx]
+		}
+		if !isNaN(val) {
+			v := val
+			row.Value = &v
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func isNaN[T RasExtractDataTypes](val T) bool {
+	switch v := any(val).(type) {
+	case float32:
+		return math.IsNaN(float64(v))
+	case float64:
+		return math.IsNaN(v)
+	}
+	return false
+}
+
+// =============================================================================
+// NetCDF writer
+// =============================================================================
+
+// NetCDF station layouts
+const (
+	// NetcdfColumnStations writes a variable per dataset with a station per column, such as reference lines
+	NetcdfColumnStations string = "columns"
+	// NetcdfDatasetStations writes a variable per column with a station per dataset, such as boundary conditions
+	NetcdfDatasetStations string = "datasets"
+)
+
+// netcdfExtractDataset is the time series of a dataset in row major order of time step and column
+type netcdfExtractDataset struct {
+	name    string
+	path    string
+	columns []string
+	steps   int
+	values  any
+}
+
+// CF standard names of RAS variables by lower case variable name
+var netcdfStandardNames map[string]string = map[string]string{
+	"flow":          "water_volume_transport_in_river_channel",
+	"stage":         "water_surface_height_above_reference_datum",
+	"water surface": "water_surface_height_above_reference_datum",
+}
+
+// UDUNITS of RAS units
+var netcdfUnits map[string]string = map[string]string{
+	"cfs":  "ft3 s-1",
+	"cms":  "m3 s-1",
+	"ft":   "ft",
+	"m":    "m",
+	"ft/s": "ft s-1",
+	"m/s":  "m s-1",
+}
+
+func NewNetcdfRasExtractWriter[T RasExtractDataTypes](results *ExtractResults, blockName string) (RasDataExtractWriter[T], error) {
+	writer := NetcdfRasExtractWriter[T]{results: results, blockName: blockName}
+	return &writer, nil
+}
+
+// NetcdfRasExtractWriter writes a CF time series NetCDF file for each block name.  Only the time series data is
+// written, so writedata must be set, and the datasets must be float32 or float64.
+type NetcdfRasExtractWriter[T RasExtractDataTypes] struct {
+	results   *ExtractResults
+	blockName string
+}
+
+func (rw *NetcdfRasExtractWriter[T]) Write(input WriteRasDataInput[T]) error {
+	if !input.WriteData {
+		return fmt.Errorf("netcdf output writes time series and requires writedata for %s", input.OutputName)
+	}
+	ds := netcdfExtractDataset{
+		name:    input.datasetName,
+		path:    input.OutputName,
+		columns: input.Colnames,
+		steps:   len(input.Data.data),
+	}
+	switch data := any(input.Data.data).(type) {
+	case [][]float32:
+		ds.values = slices.Concat(data...)
+	case [][]float64:
+		ds.values = slices.Concat(data...)
+	default:
+		return fmt.Errorf("netcdf output supports float32 and float64 datasets, %s is %T", input.OutputName, *new(T))
+	}
+	if len(ds.columns) == 0 && ds.steps > 0 {
+		ds.columns = make([]string, len(input.Data.data[0]))
+		for i := range ds.columns {
+			ds.columns[i] = strconv.Itoa(i)
+		}
+	}
+	rw.results.mu.Lock()
+	defer rw.results.mu.Unlock()
+	rw.results.netcdf[rw.blockName] = append(rw.results.netcdf[rw.blockName], ds)
+	return nil
+}
+
+// netcdfTimeSeries is the time coordinate of a block and the attributes of its variables
+type netcdfTimeSeries struct {
+	start           time.Time
+	days            []float64
+	units           map[string]string
+	eventIdentifier string
+}
+
+// netcdfExtractFile builds the CF time series file of a block.  Stations are the columns of each dataset, or the
+// datasets, depending on the stations layout.  Variables have the dimensions (station, time), the orthogonal
+// multidimensional representation of CF discrete sampling geometries.
+func netcdfExtractFile(blockName string, datasets []netcdfExtractDataset, stations string, ts netcdfTimeSeries) (*netcdf.File, error) {
+	if len(datasets) == 0 {
+		return nil, fmt.Errorf("no netcdf extract for block %s", blockName)
+	}
+	first := datasets[0]
+	for _, ds := range datasets {
+		if ds.steps != len(ts.days) {
+			return nil, fmt.Errorf("%s has %d time steps and the time has %d", ds.path, ds.steps, len(ts.days))
+		}
+		if fmt.Sprintf("%T", ds.values) != fmt.Sprintf("%T", first.values) {
+			return nil, fmt.Errorf("the data type of %s does not match the data type of block %s", ds.path, blockName)
+		}
+	}
+
+	var stationNames []string
+	variables := []netcdfVariable{}
+	switch stations {
+	case NetcdfColumnStations:
+		stationNames = first.columns
+		for d, ds := range datasets {
+			if !slices.Equal(ds.columns, first.columns) {
+				return nil, fmt.Errorf("the columns of %s do not match the columns of block %s", ds.path, blockName)
+			}
+			variables = append(variables, netcdfVariable{ds.name, func(station int, step int) (int, int) {
+				return d, step*len(first.columns) + station
+			}})
+		}
+	case NetcdfDatasetStations:
+		for _, ds := range datasets {
+			if !slices.Equal(ds.columns, first.columns) {
+				return nil, fmt.Errorf("the columns of %s do not match the columns of block %s", ds.path, blockName)
+			}
+			stationNames = append(stationNames, ds.name)
+		}
+		for c, col := range first.columns {
+			variables = append(variables, netcdfVariable{col, func(station int, step int) (int, int) {
+				return station, step*len(first.columns) + c
+			}})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported netcdf stations layout %q", stations)
+	}
+
+	f := netcdf.File{}
+	f.AddAttribute("Conventions", "CF-1.8")
+	f.AddAttribute("featureType", "timeSeries")
+	f.AddAttribute("title", blockName)
+	f.AddAttribute("source", "HEC-RAS")
+	if ts.eventIdentifier != "" {
+		f.AddAttribute("event_identifier", ts.eventIdentifier)
+	}
+	nameLength := netcdf.MaxLength(stationNames)
+	for _, dim := range []netcdf.Dimension{{Name: "time", Length: len(ts.days)}, {Name: "station", Length: len(stationNames)}, {Name: "name_strlen", Length: nameLength}} {
+		err := f.AddDimension(dim.Name, dim.Length)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := f.AddVariable(netcdf.Variable{
+		Name:       "time",
+		Dimensions: []string{"time"},
+		Attributes: []netcdf.Attribute{
+			{Name: "standard_name", Value: "time"},
+			{Name: "long_name", Value: "time"},
+			{Name: "units", Value: "days since " + ts.start.Format(time.DateTime)},
+			{Name: "calendar", Value: "standard"},
+			{Name: "axis", Value: "T"},
+		},
+		Values: ts.days,
+	})
+	if err != nil {
+		return nil, err
+	}
+	stationValues, err := netcdf.CharValues(stationNames, nameLength)
+	if err != nil {
+		return nil, err
+	}
+	err = f.AddVariable(netcdf.Variable{
+		Name:       "station_name",
+		Dimensions: []string{"station", "name_strlen"},
+		Attributes: []netcdf.Attribute{
+			{Name: "long_name", Value: "station name"},
+			{Name: "cf_role", Value: "timeseries_id"},
+		},
+		Values: stationValues,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range variables {
+		name, err := netcdf.Name(v.name)
+		if err != nil {
+			return nil, err
+		}
+		attrs := []netcdf.Attribute{{Name: "long_name", Value: v.name}}
+		if standardName, ok := netcdfStandardNames[strings.ToLower(v.name)]; ok {
+			attrs = append(attrs, netcdf.Attribute{Name: "standard_name", Value: standardName})
+		}
+		if units, ok := ts.units[v.name]; ok {
+			if udunits, ok := netcdfUnits[strings.ToLower(units)]; ok {
+				units = udunits
+			}
+			attrs = append(attrs, netcdf.Attribute{Name: "units", Value: units})
+		}
+		attrs = append(attrs, netcdf.Attribute{Name: "coordinates", Value: "time station_name"})
+		var values any
+		switch first.values.(type) {
+		case []float32:
+			attrs = append(attrs, netcdf.Attribute{Name: "_FillValue", Value: float32(math.NaN())})
+			values = netcdfSeries[float32](datasets, len(stationNames), len(ts.days), v.index)
+		case []float64:
+			attrs = append(attrs, netcdf.Attribute{Name: "_FillValue", Value: math.NaN()})
+			values = netcdfSeries[float64](datasets, len(stationNames), len(ts.days), v.index)
+		}
+		err = f.AddVariable(netcdf.Variable{Name: name, Dimensions: []string{"station", "time"}, Attributes: attrs, Values: values})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &f, nil
+}
+
+// netcdfVariable is a variable of a block and the dataset and value index of the dataset of each station at each time
+// step
+type netcdfVariable struct {
+	name  string
+	index func(station int, step int) (int, int)
+}
+
+// netcdfSeries gathers the values of a variable in station then time step order
+func netcdfSeries[F float32 | float64](datasets []netcdfExtractDataset, stations int, steps int, index func(station int, step int) (int, int)) []F {
+	values := make([]F, 0, stations*steps)
+	for station := range stations {
+		for step := range steps {
+			d, idx := index(station, step)
+			values = append(values, datasets[d].values.([]F)[idx])
+		}
+	}
+	return values
+}
+
+// =============================================================================
+// Zarr writer
+// =============================================================================
+
+// target number of values in a chunk of an extracted array, about 1MB of float32 values
+const zarrChunkValues int = 262144
+
+// the most columns in a chunk of an extracted array
+const zarrChunkColumns int = 4096
+
+// zarrExtract adds the arrays of an extracted dataset to the group of the dataset in the store of a block
+type zarrExtract struct {
+	group string
+	attrs map[string]any
+	add   func(s zarr.Store, compressor *zarr.Compressor) error
+}
+
+func NewZarrRasExtractWriter[T RasExtractDataTypes](results *ExtractResults, blockName string) (RasDataExtractWriter[T], error) {
+	writer := ZarrRasExtractWriter[T]{results: results, blockName: blockName}
+	return &writer, nil
+}
+
+// ZarrRasExtractWriter writes a Zarr v2 store for each block name with a group per dataset.  Each group has a data
+// array of the time series, with dimensions time and column, and an array of each summary, with dimension column.
+// Datasets must be numeric.
+type ZarrRasExtractWriter[T RasExtractDataTypes] struct {
+	results   *ExtractResults
+	blockName string
+}
+
+func (rw *ZarrRasExtractWriter[T]) Write(input WriteRasDataInput[T]) error {
+	var add func(zarr.Store, *zarr.Compressor) error
+	group := strings.ReplaceAll(input.datasetName, "/", "_")
+	switch data := any(input.Data).(type) {
+	case *RasExtractData[float32]:
+		add = zarrDatasetArrays(group, input.WriteData, input.WriteSummary, data.data, data.summaries)
+	case *RasExtractData[float64]:
+		add = zarrDatasetArrays(group, input.WriteData, input.WriteSummary, data.data, data.summaries)
+	case *RasExtractData[int8]:
+		add = zarrDatasetArrays(group, input.WriteData, input.WriteSummary, data.data, data.summaries)
+	case *RasExtractData[int16]:
+		add = zarrDatasetArrays(group, input.WriteData, input.WriteSummary, data.data, data.summaries)
+	case *RasExtractData[int32]:
+		add = zarrDatasetArrays(group, input.WriteData, input.WriteSummary, data.data, data.summaries)
+	case *RasExtractData[int64]:
+		add = zarrDatasetArrays(group, input.WriteData, input.WriteSummary, data.data, data.summaries)
+	case *RasExtractData[int]:
+		//zarr arrays have sized integer types
+		rows := make([][]int64, len(data.data))
+		for i, row := range data.data {
+			rows[i] = zarrInt64s(row)
+		}
+		summaries := make(map[string][]int64, len(data.summaries))
+		for name, vals := range data.summaries {
+			summaries[name] = zarrInt64s(vals)
+		}
+		add = zarrDatasetArrays(group, input.WriteData, input.WriteSummary, rows, summaries)
+	default:
+		return fmt.Errorf("zarr output supports numeric datasets, %s is %T", input.OutputName, *new(T))
+	}
+	attrs := map[string]any{"ras_path": input.OutputName}
+	if len(input.Colnames) > 0 {
+		attrs["columns"] = input.Colnames
+	}
+	rw.results.mu.Lock()
+	defer rw.results.mu.Unlock()
+	rw.results.zarr[rw.blockName] = append(rw.results.zarr[rw.blockName], zarrExtract{group: group, attrs: attrs, add: add})
+	return nil
+}
+
+func zarrDatasetArrays[F zarr.Number](group string, writeData bool, writeSummary bool, data [][]F, summaries map[string][]F) func(zarr.Store, *zarr.Compressor) error {
+	return func(s zarr.Store, compressor *zarr.Compressor) error {
+		if writeData {
+			rows := len(data)
+			cols := 0
+			if rows > 0 {
+				cols = len(data[0])
+			}
+			for _, row := range data {
+				if len(row) != cols {
+					return fmt.Errorf("the rows of %s have different lengths", group)
+				}
+			}
+			err := zarr.AddArray(s, group+"/data", slices.Concat(data...), []int{rows, cols}, zarrChunks(rows, cols), compressor, map[string]any{"_ARRAY_DIMENSIONS": []string{"time", "column"}})
+			if err != nil {
+				return err
+			}
+		}
+		if writeSummary {
+			for name, vals := range summaries {
+				err := zarr.AddArray(s, group+"/"+name, vals, []int{len(vals)}, []int{max(1, len(vals))}, compressor, map[string]any{"_ARRAY_DIMENSIONS": []string{"column"}})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func zarrInt64s(vals []int) []int64 {
+	out := make([]int64, len(vals))
+	for i, v := range vals {
+		out[i] = int64(v)
+	}
+	return out
+}
+
+// zarrChunks is the chunk shape of a time series, chunks of whole time steps of up to zarrChunkColumns columns with
+// about zarrChunkValues values
+func zarrChunks(rows int, cols int) []int {
+	chunkCols := max(1, min(cols, zarrChunkColumns))
+	chunkRows := max(1, min(rows, zarrChunkValues/chunkCols))
+	return []int{chunkRows, chunkCols}
+}
+
+// zarrCompressors are the compressors of the compression names
+var zarrCompressors map[string]*zarr.Compressor = map[string]*zarr.Compressor{
+	"none": nil,
+	"zlib": {ID: zarr.ZLIB, Level: 5},
+	"gzip": {ID: zarr.GZIP, Level: 5},
+}
+
+// zarrExtractStore builds the store of a block.  The event identifier is added to the attributes of the root and of
+// each dataset group when it is set.
+func zarrExtractStore(blockName string, extracts []zarrExtract, eventIdentifier string, compressor *zarr.Compressor) (zarr.Store, error) {
+	eventAttrs := map[string]any{}
+	if eventIdentifier != "" {
+		eventAttrs["event_identifier"] = eventIdentifier
+	}
+	s, err := zarr.NewStore(map[string]any{"block": blockName})
+	if err != nil {
+		return nil, err
+	}
+	err = s.UpdateAttributes("", eventAttrs)
+	if err != nil {
+		return nil, err
+	}
+	for _, extract := range extracts {
+		if _, ok := s[extract.group+"/.zgroup"]; ok {
+			return nil, fmt.Errorf("block %s has more than one dataset named %s", blockName, extract.group)
+		}
+		err = s.AddGroup(extract.group, extract.attrs)
+		if err != nil {
+			return nil, err
+		}
+		err = s.UpdateAttributes(extract.group, eventAttrs)
+		if err != nil {
+			return nil, err
+		}
+		err = extract.add(s, compressor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
 // =============================================================================
 // JSON attribute writer
 // =============================================================================
 
-func NewJsonAttributeExtractor(blockname string, dataset string) (*JsonAttributeExtractWriter, error) {
-	writer := JsonAttributeExtractWriter{blockname: blockname, dataset: dataset}
+func NewJsonAttributeExtractor(results *ExtractResults, blockname string, dataset string) (*JsonAttributeExtractWriter, error) {
+	writer := JsonAttributeExtractWriter{results: results, blockname: blockname, dataset: dataset}
 	return &writer, nil
 }
 
 type JsonAttributeExtractWriter struct {
+	results   *ExtractResults
 	blockname string
 	dataset   string
 }
 
+// Write adds the attributes to the block, after the extracts already in the block
 func (jw *JsonAttributeExtractWriter) Write(vals map[string]any) error {
-	writerAccumulator[jw.blockname] = []map[string]any{{"attributes": RasExtractorOutputBlock[float32]{Dataset: jw.dataset, Record: vals}}}
+	jw.results.mu.Lock()
+	defer jw.results.mu.Unlock()
+	jw.results.json[jw.blockname] = append(jw.results.json[jw.blockname], map[string]any{"attributes": RasExtractorOutputBlock[float32]{Dataset: jw.dataset, Record: vals}})
 	return nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"ras-runner/actions/utils"
 	"reflect"
 	"regexp"
+	"strings"
 
 	"github.com/usace-cloud-compute/go-hdf5"
 	"github.com/usace-cloud-compute/go-hdf5/util"
@@ -51,6 +52,12 @@ const (
 	CsvWriter RasExtractWriterType = "csv"
 	// EventDbWriter writes output to event database
 	EventDbWriter RasExtractWriterType = "eventdb"
+	// ParquetWriter writes output to Parquet format
+	ParquetWriter RasExtractWriterType = "parquet"
+	// NetcdfWriter writes time series output to CF NetCDF format
+	NetcdfWriter RasExtractWriterType = "netcdf"
+	// ZarrWriter writes output to a Zarr v2 store
+	ZarrWriter RasExtractWriterType = "zarr"
 	// ByteBuffer writes output to byte buffer
 	ByteBuffer RasExtractWriterType = "bytebuffer"
 )
@@ -94,6 +101,8 @@ type RasExtractInput struct {
 	WriteBlockName string
 	// Accumulate indicates whether to accumulate results rather than write immediately
 	Accumulate bool
+	// Results holds the extracted blocks until they are written.  Console output does not need results.
+	Results *ExtractResults
 	// datasetNames is private field to hold group names during extraction
 	datasetNames []string
 }
@@ -114,13 +123,27 @@ type WriteRasDataInput[T RasExtractDataTypes] struct {
 	datasetName string
 }
 
-// getWriter returns a writer instance based on the specified writer type
-func getWriter[T RasExtractDataTypes](writertype RasExtractWriterType, writeBlockName string, datasetnum int) (RasDataExtractWriter[T], error) {
+// getWriter returns a writer instance based on the specified writer type.  Writers other than the console writer add
+// their blocks to the results.
+func getWriter[T RasExtractDataTypes](results *ExtractResults, writertype RasExtractWriterType, writeBlockName string, datasetnum int) (RasDataExtractWriter[T], error) {
+	if results == nil && writertype != ConsoleWriter {
+		return nil, fmt.Errorf("the %s writer requires extract results", writertype)
+	}
 	switch writertype {
 	case ConsoleWriter:
 		return &ConsoleRasExtractWriter[T]{}, nil
 	case JsonWriter:
-		return NewJsonRasExtractWriter[T](writeBlockName, datasetnum)
+		return NewJsonRasExtractWriter[T](results, writeBlockName, datasetnum)
+	case CsvWriter:
+		return NewCsvRasExtractWriter[T](results, writeBlockName)
+	case EventDbWriter:
+		return NewEventDbRasExtractWriter[T](results, writeBlockName)
+	case ParquetWriter:
+		return NewParquetRasExtractWriter[T](results, writeBlockName)
+	case NetcdfWriter:
+		return NewNetcdfRasExtractWriter[T](results, writeBlockName)
+	case ZarrWriter:
+		return NewZarrRasExtractWriter[T](results, writeBlockName)
 	default:
 		return nil, fmt.Errorf("invalid writer type: %s", writertype)
 	}
@@ -135,33 +158,39 @@ func DataExtract[T RasExtractDataTypes](input RasExtractInput, filepath string)
 	if err != nil {
 		return err
 	}
+	defer extractor.Close()
+	return dataExtract(input, extractor)
+}
 
+// DataExtractFile performs data extraction from an open HDF5 file.  The file is not closed.
+func DataExtractFile[T RasExtractDataTypes](input RasExtractInput, f *hdf5.File) error {
+	return dataExtract(input, NewRasExtractorFromFile[T](f))
+}
+
+func dataExtract[T RasExtractDataTypes](input RasExtractInput, extractor *RasExtractor[T]) error {
 	var datasets []string
 	if input.GroupPath != "" {
 		datasetNames, err := extractor.GroupMembers(input.GroupPath)
-		input.datasetNames = datasetNames
 		if err != nil {
 			return fmt.Errorf("unable to read hdf5 group objects: %s", err)
 		}
+		//names of the included members, in the order of the extracted datasets
+		input.datasetNames = []string{}
 		datasets = []string{}
 		for _, dsname := range datasetNames {
-			var include bool
-
-			if input.MatchPattern == "" && input.ExcludePattern == "" {
-				include = true
-			} else {
-				if input.MatchPattern != "" {
-					re := regexp.MustCompile(input.MatchPattern)
-					include = re.MatchString(dsname)
-				}
-
-				if input.ExcludePattern != "" {
-					re := regexp.MustCompile(input.ExcludePattern)
-					include = !re.MatchString(dsname)
-				}
+			//members must match the match pattern and are then removed by the exclude pattern
+			include := true
+			if input.MatchPattern != "" {
+				re := regexp.MustCompile(input.MatchPattern)
+				include = re.MatchString(dsname)
+			}
+			if include && input.ExcludePattern != "" {
+				re := regexp.MustCompile(input.ExcludePattern)
+				include = !re.MatchString(dsname)
 			}
 
 			if include {
+				input.datasetNames = append(input.datasetNames, dsname)
 				dsname := fmt.Sprintf("%s/%s", input.GroupPath, dsname)
 				if input.GroupSuffix != "" {
 					dsname = fmt.Sprintf("%s/%s", dsname, input.GroupSuffix)
@@ -192,6 +221,11 @@ func NewRasExtractor[T RasExtractDataTypes](filepath string) (*RasExtractor[T],
 	return &extractor, err
 }
 
+// NewRasExtractorFromFile creates a new RasExtractor instance for an open HDF5 file
+func NewRasExtractorFromFile[T RasExtractDataTypes](f *hdf5.File) *RasExtractor[T] {
+	return &RasExtractor[T]{f: f}
+}
+
 // RasExtractor handles HDF5 file operations for data extraction
 type RasExtractor[T RasExtractDataTypes] struct {
 	f *hdf5.File
@@ -244,7 +278,7 @@ func (rer *RasExtractor[T]) RunExtract(datasetnum int, input RasExtractInput) er
 	if err != nil {
 		return err
 	}
-	writer, err := getWriter[T](input.WriterType, input.WriteBlockName, datasetnum)
+	writer, err := getWriter[T](input.Results, input.WriterType, input.WriteBlockName, datasetnum)
 	if err != nil {
 		return err
 	}
@@ -258,7 +292,7 @@ func (rer *RasExtractor[T]) RunExtract(datasetnum int, input RasExtractInput) er
 		datasetName = path.Base(input.DataPath)
 	}
 
-	writer.Write(WriteRasDataInput[T]{
+	return writer.Write(WriteRasDataInput[T]{
 		Data:         out,
 		WriteData:    input.WriteData,
 		WriteSummary: input.WriteSummary,
@@ -266,8 +300,6 @@ func (rer *RasExtractor[T]) RunExtract(datasetnum int, input RasExtractInput) er
 		OutputName:   outputName,
 		datasetName:  datasetName,
 	})
-
-	return nil
 }
 
 // columnNamesPreprocessor handles processing of column names from datasets or direct values
@@ -287,6 +319,10 @@ func (rer *RasExtractor[T]) columnNamesPreprocessor(input *RasExtractInput) erro
 			return err
 		}
 		input.Colnames = flattenArray(cols, 0)
+		//fixed length names are padded with nulls
+		for i, name := range input.Colnames {
+			input.Colnames[i] = strings.TrimRight(name, "\x00")
+		}
 		input.ColNamesDataset = ""
 	}
 	return nil
@@ -373,6 +409,8 @@ type AttributeExtractInput struct {
 	WriterType RasExtractWriterType
 	// WriteBlockName is the name used for identifying the block in output
 	WriteBlockName string
+	// Results holds the extracted attributes until they are written
+	Results *ExtractResults
 
 	// AttributeFailureConditionField string
 	// AttributeFailureConditionValue any
@@ -386,6 +424,16 @@ func AttributeExtract(input AttributeExtractInput, filepath string) error {
 	if err != nil {
 		return err
 	}
+	defer extractor.Close()
+	return attributeExtract(input, extractor)
+}
+
+// AttributeExtractFile extracts attributes from an open HDF5 file.  The file is not closed.
+func AttributeExtractFile(input AttributeExtractInput, f *hdf5.File) error {
+	return attributeExtract(input, NewRasExtractorFromFile[int](f))
+}
+
+func attributeExtract(input AttributeExtractInput, extractor *RasExtractor[int]) error {
 	vals, err := extractor.Attributes(input)
 	if err != nil {
 		return err
@@ -393,7 +441,10 @@ func AttributeExtract(input AttributeExtractInput, filepath string) error {
 
 	//@TODO use the proper writer!!!!!!!!!!!!!!!!!!!!!!!!
 	//writer := ConsoleAttributeExtractWriter{}
-	writer, err := NewJsonAttributeExtractor(input.WriteBlockName, input.AttributePath)
+	if input.Results == nil {
+		return fmt.Errorf("the attribute extract of %s requires extract results", input.AttributePath)
+	}
+	writer, err := NewJsonAttributeExtractor(input.Results, input.WriteBlockName, input.AttributePath)
 	if err != nil {
 		return err
 	}
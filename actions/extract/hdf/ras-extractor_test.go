@@ -1,7 +1,28 @@
 package hdf
 
 import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"ras-runner/eventdb"
+	"ras-runner/netcdf"
+	"ras-runner/zarr"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/usace-cloud-compute/cc-go-sdk"
+	"github.com/usace-cloud-compute/go-hdf5"
 )
 
 const (
@@ -232,3 +253,699 @@ func TestAttributeReader2(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestCsvRasExtractWriter(t *testing.T) {
+	results := NewExtractResults()
+	writer, err := getWriter[float32](results, CsvWriter, "refline-peaks", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nan := float32(math.NaN())
+	datasets := []struct {
+		name string
+		data *RasExtractData[float32]
+	}{
+		{"Flow", &RasExtractData[float32]{
+			data:      [][]float32{{1, 2.5}, {3, nan}},
+			summaries: map[string][]float32{"min": {1, 2.5}, "max": {3, 2.5}},
+		}},
+		{"Water Surface", &RasExtractData[float32]{
+			data:      [][]float32{{10.25, 11}},
+			summaries: map[string][]float32{"max": {10.25, nan}},
+		}},
+	}
+	for _, ds := range datasets {
+		err = writer.Write(WriteRasDataInput[float32]{
+			Data:         ds.data,
+			OutputName:   "/Results/Reference Lines/" + ds.name,
+			Colnames:     []string{"upstream", "downstream"},
+			WriteData:    true,
+			WriteSummary: true,
+			datasetName:  ds.name,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if names := blockNames(results.csv); !reflect.DeepEqual(names, []string{"refline-peaks"}) {
+		t.Fatalf("unexpected csv blocks %v", names)
+	}
+	var buf bytes.Buffer
+	err = writeCsvExtractBlock(&buf, results.csv["refline-peaks"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `dataset,summary,timestep,upstream,downstream
+Flow,max,,3,2.5
+Flow,min,,1,2.5
+Flow,,0,1,2.5
+Flow,,1,3,
+Water Surface,max,,10.25,
+Water Surface,,0,10.25,11
+`
+	if buf.String() != want {
+		t.Errorf("unexpected csv:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	err = writer.Write(WriteRasDataInput[float32]{
+		Data:        &RasExtractData[float32]{data: [][]float32{{1, 2, 3}}},
+		OutputName:  "/Results/Reference Lines/Velocity",
+		WriteData:   true,
+		datasetName: "Velocity",
+	})
+	if err == nil {
+		t.Error("expected an error for a dataset with different columns")
+	}
+
+	numbered, err := getWriter[int32](results, CsvWriter, "cells", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = numbered.Write(WriteRasDataInput[int32]{Data: &RasExtractData[int32]{data: [][]int32{{4, 5}}}, WriteData: true, datasetName: "Cells"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := results.csv["cells"].header; !reflect.DeepEqual(header, []string{"dataset", "summary", "timestep", "0", "1"}) {
+		t.Errorf("unexpected numbered header %v", header)
+	}
+}
+
+func TestEventDbRasExtractWriter(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "eventdb.sqlite")
+	extract := func(peak float32) {
+		results := NewExtractResults()
+		writer, err := getWriter[float32](results, EventDbWriter, "refline-peaks", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writer.Write(WriteRasDataInput[float32]{
+			Data: &RasExtractData[float32]{
+				data:      [][]float32{{1, 2.5}, {peak, float32(math.NaN())}},
+				summaries: map[string][]float32{"max": {peak, 2.5}},
+			},
+			OutputName:   "/Results/Reference Lines/Flow",
+			Colnames:     []string{"upstream", "downstream"},
+			WriteData:    true,
+			WriteSummary: true,
+			datasetName:  "Flow",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		breach := EventDbBreachDataExtractWriter{results: results, blockname: "breach_records"}
+		breach.Write([]BreachRecord{{Event: "7", FlowArea2D: "Area", SaConn: "Dam", Breached: true, MaxFlow: peak, HWAtBreach: float32(math.NaN())}})
+		err = writeEventDb(dbPath, "7", results.eventDb)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	extract(3)
+	//a rerun of the event replaces its rows
+	extract(4)
+
+	db, err := sql.Open(eventdb.DRIVER, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM timeseries WHERE event_id = '7' AND block = 'refline-peaks' AND dataset = 'Flow'").Scan(&count)
+	if err != nil || count != 4 {
+		t.Errorf("expected 4 time series values, got %d %v", count, err)
+	}
+	var peak float64
+	err = db.QueryRow("SELECT value FROM summaries WHERE dataset = 'Flow' AND summary = 'max' AND column_name = 'upstream'").Scan(&peak)
+	if err != nil || peak != 4 {
+		t.Errorf("unexpected peak %f %v", peak, err)
+	}
+	var maxFlow float64
+	err = db.QueryRow("SELECT value FROM records WHERE block = 'breach_records' AND dataset = ? AND field = 'MaxFlow'", fmt.Sprintf(breachPathTemplate, "Dam")).Scan(&maxFlow)
+	if err != nil || maxFlow != 4 {
+		t.Errorf("unexpected breach max flow %f %v", maxFlow, err)
+	}
+	err = db.QueryRow("SELECT COUNT(*) FROM records WHERE field = 'HWAtBreach' AND value IS NULL").Scan(&count)
+	if err != nil || count != 1 {
+		t.Errorf("expected a NULL head water at breach, got %d %v", count, err)
+	}
+}
+
+func TestParquetRasExtractWriter(t *testing.T) {
+	results := NewExtractResults()
+	writer, err := getWriter[float32](results, ParquetWriter, "refline-peaks", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Write(WriteRasDataInput[float32]{
+		Data: &RasExtractData[float32]{
+			data:      [][]float32{{1, float32(math.NaN())}},
+			summaries: map[string][]float32{"max": {3, 2.5}},
+		},
+		OutputName:   "/Results/Reference Lines/Flow",
+		Colnames:     []string{"upstream", "downstream"},
+		WriteData:    true,
+		WriteSummary: true,
+		datasetName:  "Flow",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ints, err := getWriter[int32](results, ParquetWriter, "refline-peaks", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ints.Write(WriteRasDataInput[int32]{Data: &RasExtractData[int32]{data: [][]int32{{4}}}, WriteData: true, datasetName: "Cells"})
+	if err == nil {
+		t.Error("expected an error for a dataset with a different data type")
+	}
+
+	codec, err := parquetCompression("zstd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = results.parquet["refline-peaks"].write(&buf, "7", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := parquet.Read[parquetExtractRow[float32]](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(rows))
+	}
+	if rows[0].EventID != "7" || *rows[0].Summary != "max" || rows[0].ColumnName != "upstream" || *rows[0].Value != 3 {
+		t.Errorf("unexpected summary row %+v", rows[0])
+	}
+	if rows[3].Summary != nil || *rows[3].Timestep != 0 || rows[3].ColumnName != "downstream" || rows[3].Value != nil {
+		t.Errorf("unexpected time step row %+v", rows[3])
+	}
+
+	breach := ParquetBreachDataExtractWriter{results: results, blockname: "breach_records"}
+	breach.Write([]BreachRecord{{FlowArea2D: "Area", SaConn: "Dam", Breached: true, BreachIndex: 12, MaxFlow: 1200, HWAtBreach: float32(math.NaN())}})
+	buf.Reset()
+	err = results.parquet["breach_records"].write(&buf, "7", codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := parquet.Read[parquetBreachRecord](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].EventID != "7" || records[0].Connection != "Dam" || *records[0].MaxFlow != 1200 || records[0].HWAtBreach != nil {
+		t.Errorf("unexpected breach records %+v", records)
+	}
+
+	if _, err = parquetCompression("lzo"); err == nil {
+		t.Error("expected an error for an unsupported compression")
+	}
+}
+
+func TestNetcdfRasExtractWriter(t *testing.T) {
+	results := NewExtractResults()
+	writer, err := getWriter[float32](results, NetcdfWriter, "refline-series", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nan := float32(math.NaN())
+	for _, ds := range []struct {
+		name string
+		data [][]float32
+	}{
+		{"Flow", [][]float32{{1, 2}, {3, 4}, {5, nan}}},
+		{"Water Surface", [][]float32{{10, 20}, {11, 21}, {12, 22}}},
+	} {
+		err = writer.Write(WriteRasDataInput[float32]{
+			Data:        &RasExtractData[float32]{data: ds.data},
+			OutputName:  "/Results/Unsteady/Output/Output Blocks/Base Output/Unsteady Time Series/Reference Lines/" + ds.name,
+			Colnames:    []string{"upstream", "downstream"},
+			WriteData:   true,
+			datasetName: ds.name,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts := netcdfTimeSeries{
+		start:           time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		days:            []float64{0, 0.25, 0.5},
+		units:           map[string]string{"Flow": "cfs"},
+		eventIdentifier: "7",
+	}
+	nc, err := netcdfExtractFile("refline-series", results.netcdf["refline-series"], NetcdfColumnStations, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, v := range nc.Variables {
+		names = append(names, v.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"time", "station_name", "Flow", "Water_Surface"}) {
+		t.Fatalf("unexpected variables %v", names)
+	}
+	if units := nc.Variables[0].Attributes[2].Value; units != "days since 2000-01-01 00:00:00" {
+		t.Errorf("unexpected time units %v", units)
+	}
+	flow := nc.Variables[2]
+	if !reflect.DeepEqual(flow.Dimensions, []string{"station", "time"}) {
+		t.Errorf("unexpected flow dimensions %v", flow.Dimensions)
+	}
+	if values := flow.Values.([]float32); !reflect.DeepEqual(values[:5], []float32{1, 3, 5, 2, 4}) || !math.IsNaN(float64(values[5])) {
+		t.Errorf("unexpected flow values %v", values)
+	}
+	if !slices.Contains(flow.Attributes, netcdf.Attribute{Name: "units", Value: "ft3 s-1"}) || !slices.Contains(flow.Attributes, netcdf.Attribute{Name: "standard_name", Value: "water_volume_transport_in_river_channel"}) {
+		t.Errorf("unexpected flow attributes %v", flow.Attributes)
+	}
+	if err = nc.Create(filepath.Join(t.TempDir(), "refline-series.nc")); err != nil {
+		t.Fatal(err)
+	}
+
+	bySt, err := netcdfExtractFile("refline-series", results.netcdf["refline-series"], NetcdfDatasetStations, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if station := bySt.Variables[1].Values.([]byte); string(station) != "Flow\x00\x00\x00\x00\x00\x00\x00\x00\x00Water Surface" {
+		t.Errorf("unexpected stations %q", station)
+	}
+	if upstream := bySt.Variables[2]; upstream.Name != "upstream" || !reflect.DeepEqual(upstream.Values.([]float32), []float32{1, 3, 5, 10, 11, 12}) {
+		t.Errorf("unexpected upstream variable %v", upstream)
+	}
+
+	ts.days = ts.days[:2]
+	if _, err = netcdfExtractFile("refline-series", results.netcdf["refline-series"], NetcdfColumnStations, ts); err == nil {
+		t.Error("expected an error for a time with fewer steps than the datasets")
+	}
+	err = writer.Write(WriteRasDataInput[float32]{Data: &RasExtractData[float32]{}, WriteSummary: true, datasetName: "Flow"})
+	if err == nil {
+		t.Error("expected an error for summary only output")
+	}
+}
+
+func TestZarrRasExtractWriter(t *testing.T) {
+	results := NewExtractResults()
+	writer, err := getWriter[float32](results, ZarrWriter, "cells", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Write(WriteRasDataInput[float32]{
+		Data: &RasExtractData[float32]{
+			data:      [][]float32{{1, 2, 3}, {4, 5, 6}},
+			summaries: map[string][]float32{"max": {4, 5, 6}},
+		},
+		OutputName:   "/Results/Unsteady/Output/Output Blocks/Base Output/Unsteady Time Series/2D Flow Areas/area/Water Surface",
+		Colnames:     []string{"c0", "c1", "c2"},
+		WriteData:    true,
+		WriteSummary: true,
+		datasetName:  "area/Water Surface",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := zarrExtractStore("cells", results.zarr["cells"], "7", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{
+		".zattrs", ".zgroup",
+		"area_Water Surface/.zattrs", "area_Water Surface/.zgroup",
+		"area_Water Surface/data/.zarray", "area_Water Surface/data/.zattrs", "area_Water Surface/data/0.0",
+		"area_Water Surface/max/.zarray", "area_Water Surface/max/.zattrs", "area_Water Surface/max/0",
+	}
+	if !reflect.DeepEqual(store.Keys(), keys) {
+		t.Fatalf("unexpected keys %v", store.Keys())
+	}
+	var attrs map[string]any
+	if err = json.Unmarshal(store["area_Water Surface/.zattrs"], &attrs); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"ras_path":         "/Results/Unsteady/Output/Output Blocks/Base Output/Unsteady Time Series/2D Flow Areas/area/Water Surface",
+		"columns":          []any{"c0", "c1", "c2"},
+		"event_identifier": "7",
+	}
+	if !reflect.DeepEqual(attrs, expected) {
+		t.Errorf("unexpected dataset attributes %v", attrs)
+	}
+	expectedData := zarr.Store{}
+	err = zarr.AddArray(expectedData, "data", []float32{1, 2, 3, 4, 5, 6}, []int{2, 3}, []int{2, 3}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(store["area_Water Surface/data/0.0"], expectedData["data/0.0"]) {
+		t.Errorf("unexpected data chunk %v", store["area_Water Surface/data/0.0"])
+	}
+
+	strWriter, _ := getWriter[string](results, ZarrWriter, "cells", 0)
+	err = strWriter.Write(WriteRasDataInput[string]{Data: &RasExtractData[string]{data: [][]string{{"a"}}}, WriteData: true, datasetName: "names"})
+	if err == nil {
+		t.Error("expected an error for a string dataset")
+	}
+}
+
+func TestZarrChunks(t *testing.T) {
+	for _, tc := range []struct {
+		rows, cols int
+		chunks     []int
+	}{
+		{100, 3, []int{100, 3}},
+		{0, 0, []int{1, 1}},
+		{100000, 10, []int{26214, 10}},
+		{1000, 10000, []int{64, 4096}},
+	} {
+		if chunks := zarrChunks(tc.rows, tc.cols); !reflect.DeepEqual(chunks, tc.chunks) {
+			t.Errorf("zarrChunks(%d, %d) = %v, expected %v", tc.rows, tc.cols, chunks, tc.chunks)
+		}
+	}
+}
+
+// writeGroupExtractFixture writes a results file with a group of reference line flows, one of them per face, and a
+// stage, and a dataset of the reference line names
+func writeGroupExtractFixture(t *testing.T) *hdf5.File {
+	f, err := hdf5.CreateFile(filepath.Join(t.TempDir(), "results.hdf"), hdf5.F_ACC_TRUNC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	group, err := f.CreateGroup("Reference Lines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer group.Close()
+	space, err := hdf5.CreateSimpleDataspace([]uint{2, 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer space.Close()
+	for i, name := range []string{"Flow A", "Flow B", "Flow per Face", "Stage"} {
+		ds, err := group.CreateDataset(name, hdf5.T_NATIVE_FLOAT, space)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vals := []float32{float32(i), 1, 2, float32(10 * i)}
+		err = ds.Write(&vals)
+		ds.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	nameType, err := hdf5.T_C_S1.Copy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nameType.Close()
+	if err = nameType.SetSize(10); err != nil {
+		t.Fatal(err)
+	}
+	nameSpace, err := hdf5.CreateSimpleDataspace([]uint{2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nameSpace.Close()
+	names, err := f.CreateDataset("Reference Line Names", nameType, nameSpace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer names.Close()
+	nameBytes := []byte("upstream\x00\x00downstream")
+	if err = names.Write(&nameBytes); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// TestGroupExtractWriters extracts the flows of a group, excluding the per face flow, with columns named by coldata
+// and checks the dataset and column names kept by each writer
+func TestGroupExtractWriters(t *testing.T) {
+	f := writeGroupExtractFixture(t)
+	datasets := []string{"Flow A", "Flow B"}
+	columns := []string{"upstream", "downstream"}
+	tests := []struct {
+		writer RasExtractWriterType
+		check  func(t *testing.T, results *ExtractResults)
+	}{
+		{CsvWriter, func(t *testing.T, results *ExtractResults) {
+			block := results.csv["flows"]
+			if want := append(slices.Clone(csvExtractIdColumns), columns...); !reflect.DeepEqual(block.header, want) {
+				t.Errorf("unexpected header %v", block.header)
+			}
+			names := []string{}
+			for _, row := range block.rows {
+				if !slices.Contains(names, row[0]) {
+					names = append(names, row[0])
+				}
+			}
+			if !reflect.DeepEqual(names, datasets) {
+				t.Errorf("unexpected datasets %v", names)
+			}
+		}},
+		{EventDbWriter, func(t *testing.T, results *ExtractResults) {
+			names := []string{}
+			for _, ds := range results.eventDb["flows"].datasets {
+				names = append(names, ds.Name)
+				if !reflect.DeepEqual(ds.Columns, columns) {
+					t.Errorf("unexpected %s columns %v", ds.Name, ds.Columns)
+				}
+			}
+			if !reflect.DeepEqual(names, datasets) {
+				t.Errorf("unexpected datasets %v", names)
+			}
+		}},
+		{ParquetWriter, func(t *testing.T, results *ExtractResults) {
+			names, cols := []string{}, []string{}
+			for _, row := range results.parquet["flows"].(*parquetExtractRows[float32]).rows {
+				if !slices.Contains(names, row.Dataset) {
+					names = append(names, row.Dataset)
+				}
+				if !slices.Contains(cols, row.ColumnName) {
+					cols = append(cols, row.ColumnName)
+				}
+			}
+			if !reflect.DeepEqual(names, datasets) || !reflect.DeepEqual(cols, columns) {
+				t.Errorf("unexpected datasets %v and columns %v", names, cols)
+			}
+		}},
+		{NetcdfWriter, func(t *testing.T, results *ExtractResults) {
+			names := []string{}
+			for _, ds := range results.netcdf["flows"] {
+				names = append(names, ds.name)
+				if !reflect.DeepEqual(ds.columns, columns) {
+					t.Errorf("unexpected %s columns %v", ds.name, ds.columns)
+				}
+			}
+			if !reflect.DeepEqual(names, datasets) {
+				t.Errorf("unexpected datasets %v", names)
+			}
+		}},
+		{ZarrWriter, func(t *testing.T, results *ExtractResults) {
+			names := []string{}
+			for _, extract := range results.zarr["flows"] {
+				names = append(names, extract.group)
+				if !reflect.DeepEqual(extract.attrs["columns"], columns) {
+					t.Errorf("unexpected %s columns %v", extract.group, extract.attrs["columns"])
+				}
+			}
+			if !reflect.DeepEqual(names, []string{"Flow A", "Flow B"}) {
+				t.Errorf("unexpected groups %v", names)
+			}
+		}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.writer), func(t *testing.T) {
+			results := NewExtractResults()
+			err := DataExtractFile[float32](RasExtractInput{
+				GroupPath:       "/Reference Lines",
+				MatchPattern:    "^Flow",
+				ExcludePattern:  "per Face",
+				ColNamesDataset: "/Reference Line Names",
+				Postprocess:     []string{"max"},
+				WriteData:       true,
+				WriteSummary:    true,
+				WriterType:      test.writer,
+				WriteBlockName:  "flows",
+				Results:         results,
+			}, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.check(t, results)
+		})
+	}
+}
+
+func TestPluginRunResults(t *testing.T) {
+	run, other := &cc.PluginManager{}, &cc.PluginManager{}
+	results := PluginRunResults(run)
+	if PluginRunResults(run) != results {
+		t.Error("expected the actions of a run to share results")
+	}
+	if PluginRunResults(other) == results {
+		t.Error("expected each run to have its own results")
+	}
+}
+
+// testStore is an output store that keeps the bytes put to each path, or fails every put
+type testStore struct {
+	puts map[string][]byte
+	err  error
+}
+
+func (ts *testStore) Put(srcReader io.Reader, destPath string, destDataPath string) (int, error) {
+	if ts.err != nil {
+		return 0, ts.err
+	}
+	data, err := io.ReadAll(srcReader)
+	if err != nil {
+		return 0, err
+	}
+	ts.puts[destPath] = data
+	return len(data), nil
+}
+
+func testOutputRunner(store *testStore) cc.ActionRunnerBase {
+	var runner cc.ActionRunnerBase
+	runner.Action.Stores = []cc.DataStore{{Name: "test", Session: store}}
+	runner.Action.Outputs = []cc.DataSource{{Name: "out", StoreName: "test", Paths: map[string]string{"extract": "extract.json"}}}
+	return runner
+}
+
+func TestWriteExtractResults(t *testing.T) {
+	results := NewExtractResults()
+	for _, block := range []string{"peaks", "series"} {
+		writer, err := getWriter[float32](results, JsonWriter, block, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writer.Write(WriteRasDataInput[float32]{Data: &RasExtractData[float32]{data: [][]float32{{1}}}, WriteData: true, datasetName: "Flow"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := &testStore{puts: map[string][]byte{}}
+	runner := testOutputRunner(store)
+	if err := writeExtractResults(runner, results, JsonWriter, []string{"peaks", "missing"}, nil, "out"); err == nil {
+		t.Error("expected an error for a block that is not in the results")
+	}
+	if names := blockNames(results.json); !reflect.DeepEqual(names, []string{"peaks", "series"}) {
+		t.Fatalf("expected a failed selection to leave the blocks, got %v", names)
+	}
+
+	//blocks that fail to write are kept for a later write
+	store.err = fmt.Errorf("store unavailable")
+	if err := writeExtractResults(runner, results, JsonWriter, []string{"peaks"}, nil, "out"); err == nil {
+		t.Error("expected the store error")
+	}
+	if names := blockNames(results.json); !reflect.DeepEqual(names, []string{"peaks", "series"}) {
+		t.Fatalf("expected a failed write to leave the blocks, got %v", names)
+	}
+
+	store.err = nil
+	if err := writeExtractResults(runner, results, JsonWriter, []string{"peaks"}, nil, "out"); err != nil {
+		t.Fatal(err)
+	}
+	written := map[string]any{}
+	if err := json.Unmarshal(store.puts["extract.json"], &written); err != nil {
+		t.Fatal(err)
+	}
+	if names := slices.Sorted(maps.Keys(written)); !reflect.DeepEqual(names, []string{"peaks"}) {
+		t.Errorf("unexpected written blocks %v", names)
+	}
+	if names := blockNames(results.json); !reflect.DeepEqual(names, []string{"series"}) {
+		t.Errorf("unexpected remaining blocks %v", names)
+	}
+	if err := writeExtractResults(runner, results, JsonWriter, nil, nil, "out"); err != nil {
+		t.Fatal(err)
+	}
+	if len(results.json) != 0 {
+		t.Errorf("expected every block to be written, remaining %v", blockNames(results.json))
+	}
+	if err := writeExtractResults(runner, results, ConsoleWriter, nil, nil, "out"); err == nil {
+		t.Error("expected an error for console results")
+	}
+	if _, err := getWriter[float32](nil, CsvWriter, "peaks", 0); err == nil {
+		t.Error("expected an error for a writer without results")
+	}
+}
+
+func TestReleasePluginRunResults(t *testing.T) {
+	run := &cc.PluginManager{}
+	results := PluginRunResults(run)
+	writer, err := getWriter[float32](results, CsvWriter, "peaks", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Write(WriteRasDataInput[float32]{Data: &RasExtractData[float32]{data: [][]float32{{1}}}, WriteData: true, datasetName: "Flow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unwritten := results.unwritten(); !reflect.DeepEqual(unwritten, []string{"csv/peaks"}) {
+		t.Errorf("unexpected unwritten blocks %v", unwritten)
+	}
+	if err = ReleasePluginRunResults(run); err == nil || !strings.Contains(err.Error(), "csv/peaks") {
+		t.Errorf("expected an error naming the unwritten block, got %v", err)
+	}
+	if PluginRunResults(run) == results {
+		t.Error("expected the released results to be removed from the run")
+	}
+	if err = ReleasePluginRunResults(run); err != nil {
+		t.Errorf("expected written results to release, got %v", err)
+	}
+}
+
+func TestJsonAttributeExtractWriter(t *testing.T) {
+	results := NewExtractResults()
+	for _, dataset := range []string{"/Plan Data/Plan Information", "/Geometry"} {
+		writer, err := NewJsonAttributeExtractor(results, "attributes", dataset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writer.Write(map[string]any{"Title": dataset})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if block := results.json["attributes"]; len(block) != 2 {
+		t.Errorf("expected the attribute extracts to be added to the block, got %v", block)
+	}
+}
+
+func TestConcurrentExtractWrites(t *testing.T) {
+	results := NewExtractResults()
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, writerType := range []RasExtractWriterType{JsonWriter, CsvWriter, EventDbWriter, ParquetWriter, ZarrWriter} {
+				writer, err := getWriter[float32](results, writerType, "peaks", 0)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				err = writer.Write(WriteRasDataInput[float32]{
+					Data:        &RasExtractData[float32]{data: [][]float32{{float32(i)}}},
+					WriteData:   true,
+					datasetName: fmt.Sprintf("Flow %d", i),
+				})
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := len(results.json["peaks"]); n != 8 {
+		t.Errorf("expected 8 json extracts, got %d", n)
+	}
+	if n := len(results.csv["peaks"].rows); n != 8 {
+		t.Errorf("expected 8 csv rows, got %d", n)
+	}
+	if n := len(results.eventDb["peaks"].datasets); n != 8 {
+		t.Errorf("expected 8 event database datasets, got %d", n)
+	}
+	if n := len(results.parquet["peaks"].(*parquetExtractRows[float32]).rows); n != 8 {
+		t.Errorf("expected 8 parquet rows, got %d", n)
+	}
+	if n := len(results.zarr["peaks"]); n != 8 {
+		t.Errorf("expected 8 zarr extracts, got %d", n)
+	}
+}
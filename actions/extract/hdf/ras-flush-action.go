@@ -0,0 +1,42 @@
+package hdf
+
+import (
+	"github.com/usace-cloud-compute/cc-go-sdk"
+	"github.com/usace-cloud-compute/go-hdf5"
+)
+
+func init() {
+	cc.ActionRegistry.RegisterAction("flush-extract-results", &FlushExtractResultsAction{})
+}
+
+// FlushExtractResultsAction writes blocks accumulated by the extract actions of the plugin run to an output data
+// source.  The written blocks are removed from the results of the run.
+type FlushExtractResultsAction struct {
+	cc.ActionRunnerBase
+}
+
+// Run writes the blocks named by the blocks attribute, or every block, of the outputformat attribute to the
+// outputDataSource.  The format attributes of the writers, such as compression or eventDbFile, are read from this
+// action.  NetCDF blocks read their time and units from the model results, so the model results are opened for
+// NetCDF output.
+//
+// Returns an error if a block is not in the results or the blocks cannot be written.
+func (a *FlushExtractResultsAction) Run() error {
+	format := RasExtractWriterType(a.Action.Attributes.GetStringOrDefault("outputformat", "json"))
+	blocks, err := a.Action.Attributes.GetStringSlice("blocks")
+	if err != nil {
+		//without block names every block of the output format is written
+		blocks = nil
+	}
+	outputDataSource := a.Action.Attributes.GetStringOrFail("outputDataSource")
+
+	var f *hdf5.File
+	if format == NetcdfWriter {
+		f, err = openModelResults(a.Action, localModelResultsPath(a.ActionRunnerBase))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+	return writeExtractResults(a.ActionRunnerBase, PluginRunResults(a.PluginManager), format, blocks, f, outputDataSource)
+}
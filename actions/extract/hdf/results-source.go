@@ -0,0 +1,41 @@
+package hdf
+
+import (
+	"fmt"
+
+	"ras-runner/actions"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+	"github.com/usace-cloud-compute/go-hdf5"
+	"github.com/usace-cloud-compute/go-hdf5/util"
+)
+
+const resultsDataSourceAttr string = "resultsDataSource"
+
+// openModelResults opens the model results named by the resultsDataSource attribute, which may be local or
+// remote.  Without a resultsDataSource the local plan results in the model directory are opened.
+func openModelResults(action cc.Action, modelResultsPath string) (*hdf5.File, error) {
+	resultsDataSource, err := action.Attributes.GetString(resultsDataSourceAttr)
+	if err != nil {
+		return util.OpenFile(modelResultsPath)
+	}
+	return actions.OpenHdfSource(action, actions.HdfSourceInput{
+		DataSourceName: resultsDataSource,
+		PathKey:        actions.HDF_PATHKEY,
+		Access:         actions.HdfAccessFromAttributes(action.Attributes),
+	})
+}
+
+// localModelResultsPath is the local plan results in the model directory.  The modelPrefix and plan attributes of the
+// action override the plugin attributes.
+func localModelResultsPath(runner cc.ActionRunnerBase) string {
+	modelPrefix, err := runner.Action.Attributes.GetString("modelPrefix")
+	if err != nil {
+		modelPrefix = runner.PluginManager.Attributes.GetStringOrFail("modelPrefix")
+	}
+	plan, err := runner.Action.Attributes.GetString("plan")
+	if err != nil {
+		plan = runner.PluginManager.Attributes.GetStringOrFail("plan")
+	}
+	return fmt.Sprintf("%s/%s.p%s.tmp.hdf", actions.MODEL_DIR, modelPrefix, plan)
+}
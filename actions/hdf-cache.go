@@ -0,0 +1,373 @@
+package actions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"ras-runner/ras"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+	filestore "github.com/usace-cloud-compute/filesapi"
+)
+
+const (
+	HDF_CACHE_DIR_ENV     = "HDF_CACHE_DIR"    //overrides the cache directory
+	HDF_CACHE_MAX_MB_ENV  = "HDF_CACHE_MAX_MB" //overrides the cache size cap in megabytes
+	defaultHdfCacheMaxMB  = 20 * 1024
+	hdfCacheIndexFile     = "index.json"
+	hdfCacheObjectsDir    = "objects"
+	hdfCacheWorkspaceName = "cache"
+)
+
+// RemoteObjectInfo is the store metadata used to check that a cached copy is still current.
+// Empty values are unknown and are not compared.
+type RemoteObjectInfo struct {
+	Size int64
+	ETag string
+}
+
+// HdfCacheEntry records a cached copy of a remote file.  Entries point to content addressed objects so a
+// file referenced by more than one source is stored once.
+type HdfCacheEntry struct {
+	Source   string    `json:"source"`
+	Sha256   string    `json:"sha256"`
+	Size     int64     `json:"size"`
+	ETag     string    `json:"etag,omitempty"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// HdfCache is a local content addressed cache of remote hdf files shared by the actions in a job.
+// Cached copies are checked against the store size and ETag when the store provides them and against
+// their sha256 otherwise.  The least recently used objects are evicted when the cache grows past MaxBytes.
+type HdfCache struct {
+	Dir      string
+	MaxBytes int64
+	mu       sync.Mutex             //guards the index, the objects and sources
+	sources  map[string]*sync.Mutex //held by a fetch of each source from the lookup through the download
+}
+
+var defaultHdfCache *HdfCache
+var defaultHdfCacheOnce sync.Once
+
+// DefaultHdfCache returns the job hdf cache in the workspace, the parent of the model directory, so cached copies
+// share the job volume rather than the system temp directory.  The location and size cap can be set with the
+// HDF_CACHE_DIR and HDF_CACHE_MAX_MB environment variables.
+func DefaultHdfCache() *HdfCache {
+	defaultHdfCacheOnce.Do(func() {
+		dir := os.Getenv(HDF_CACHE_DIR_ENV)
+		if dir == "" {
+			dir = defaultHdfCacheDir()
+		}
+		maxMB := int64(defaultHdfCacheMaxMB)
+		if val := os.Getenv(HDF_CACHE_MAX_MB_ENV); val != "" {
+			mb, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || mb <= 0 {
+				log.Printf("invalid %s %s, using %d\n", HDF_CACHE_MAX_MB_ENV, val, maxMB)
+			} else {
+				maxMB = mb
+			}
+		}
+		defaultHdfCache = &HdfCache{Dir: dir, MaxBytes: maxMB * 1024 * 1024}
+	})
+	return defaultHdfCache
+}
+
+// defaultHdfCacheDir is the cache directory in the workspace beside the model directory
+func defaultHdfCacheDir() string {
+	return filepath.Join(filepath.Dir(MODEL_DIR), hdfWorkspaceDir, hdfCacheWorkspaceName)
+}
+
+// Lookup returns the local path of a current cached copy of source, or false when there is none
+func (c *HdfCache) Lookup(source string, remote RemoteObjectInfo) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lookup(source, remote)
+}
+
+// lookup finds a current cached copy of source.  The caller holds the cache lock.
+func (c *HdfCache) lookup(source string, remote RemoteObjectInfo) (string, bool) {
+	index, err := c.readIndex()
+	if err != nil {
+		log.Printf("unable to read the hdf cache index: %s\n", err)
+		return "", false
+	}
+	entry, ok := index[source]
+	if !ok || !c.valid(entry, remote) {
+		return "", false
+	}
+	entry.LastUsed = time.Now()
+	index[source] = entry
+	err = c.writeIndex(index)
+	if err != nil {
+		log.Printf("unable to update the hdf cache index: %s\n", err)
+	}
+	return c.objectPath(entry.Sha256), true
+}
+
+// Fetch returns the local path of a cached copy of source, calling fetch to download it when there is no current
+// copy.  Downloads are checked against the remote size before they are added to the cache.  The source is locked from
+// the lookup through the download, so concurrent fetches of a source download it once while other sources download
+// in parallel.
+func (c *HdfCache) Fetch(source string, remote RemoteObjectInfo, fetch func() (io.ReadCloser, error)) (string, error) {
+	lock := c.sourceLock(source)
+	lock.Lock()
+	defer lock.Unlock()
+	if localpath, ok := c.Lookup(source, remote); ok {
+		return localpath, nil
+	}
+	objdir := filepath.Join(c.Dir, hdfCacheObjectsDir)
+	err := os.MkdirAll(objdir, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	reader, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	tmp, err := os.CreateTemp(objdir, "download.*.tmp")
+	if err != nil {
+		return "", err
+	}
+	sha := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, sha), reader)
+	cerr := tmp.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err == nil && remote.Size > 0 && size != remote.Size {
+		err = fmt.Errorf("downloaded %d bytes, expected %d", size, remote.Size)
+	}
+	if err != nil {
+		return "", errors.Join(err, os.Remove(tmp.Name()))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sum := hex.EncodeToString(sha.Sum(nil))
+	objpath := c.objectPath(sum)
+	if FileExists(objpath) {
+		err = os.Remove(tmp.Name())
+	} else {
+		err = os.Rename(tmp.Name(), objpath)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	index, err := c.readIndex()
+	if err != nil {
+		log.Printf("unable to read the hdf cache index, starting a new index: %s\n", err)
+		index = map[string]HdfCacheEntry{}
+	}
+	previous, replaced := index[source]
+	index[source] = HdfCacheEntry{
+		Source:   source,
+		Sha256:   sum,
+		Size:     size,
+		ETag:     remote.ETag,
+		LastUsed: time.Now(),
+	}
+	if replaced && previous.Sha256 != sum {
+		c.removeUnreferenced(index, previous.Sha256)
+	}
+	c.evict(index, sum)
+	return objpath, c.writeIndex(index)
+}
+
+// sourceLock returns the lock held while fetching source
+func (c *HdfCache) sourceLock(source string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sources == nil {
+		c.sources = map[string]*sync.Mutex{}
+	}
+	lock, ok := c.sources[source]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.sources[source] = lock
+	}
+	return lock
+}
+
+// Size returns the total size of the cached objects
+func (c *HdfCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	index, err := c.readIndex()
+	if err != nil {
+		return 0
+	}
+	size, _ := objectSizes(index)
+	return size
+}
+
+// valid checks a cache entry against the remote metadata.  Without remote metadata the object is re-hashed and
+// compared to its content address.
+func (c *HdfCache) valid(entry HdfCacheEntry, remote RemoteObjectInfo) bool {
+	info, err := os.Stat(c.objectPath(entry.Sha256))
+	if err != nil || info.Size() != entry.Size {
+		return false
+	}
+	if remote.Size > 0 && remote.Size != entry.Size {
+		return false
+	}
+	if remote.ETag != "" && entry.ETag != "" {
+		return remote.ETag == entry.ETag
+	}
+	if remote.Size > 0 && remote.ETag == "" && entry.ETag == "" {
+		return true
+	}
+	sum, err := fileSha256(c.objectPath(entry.Sha256))
+	return err == nil && sum == entry.Sha256
+}
+
+// evict removes the least recently used entries until the cache fits in MaxBytes.  The object just added is kept
+// even when it is larger than the cap so the current action can read it.
+func (c *HdfCache) evict(index map[string]HdfCacheEntry, keep string) {
+	if c.MaxBytes <= 0 {
+		return
+	}
+	size, lastUsed := objectSizes(index)
+	if size <= c.MaxBytes {
+		return
+	}
+	objects := make([]string, 0, len(lastUsed))
+	for sum := range lastUsed {
+		objects = append(objects, sum)
+	}
+	sort.Slice(objects, func(i, j int) bool { return lastUsed[objects[i]].Before(lastUsed[objects[j]]) })
+	for _, sum := range objects {
+		if size <= c.MaxBytes {
+			break
+		}
+		if sum == keep {
+			continue
+		}
+		var objsize int64
+		for source, entry := range index {
+			if entry.Sha256 == sum {
+				delete(index, source)
+				objsize = entry.Size
+			}
+		}
+		size -= objsize
+		err := os.Remove(c.objectPath(sum))
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("unable to evict %s from the hdf cache: %s\n", sum, err)
+		}
+	}
+}
+
+// removeUnreferenced deletes an object that is no longer referenced by any entry
+func (c *HdfCache) removeUnreferenced(index map[string]HdfCacheEntry, sum string) {
+	for _, entry := range index {
+		if entry.Sha256 == sum {
+			return
+		}
+	}
+	err := os.Remove(c.objectPath(sum))
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("unable to remove %s from the hdf cache: %s\n", sum, err)
+	}
+}
+
+// objectSizes returns the total size of the distinct objects in the index and the last use of each object
+func objectSizes(index map[string]HdfCacheEntry) (int64, map[string]time.Time) {
+	var size int64
+	lastUsed := map[string]time.Time{}
+	for _, entry := range index {
+		used, ok := lastUsed[entry.Sha256]
+		if !ok {
+			size += entry.Size
+		}
+		if !ok || entry.LastUsed.After(used) {
+			lastUsed[entry.Sha256] = entry.LastUsed
+		}
+	}
+	return size, lastUsed
+}
+
+func (c *HdfCache) objectPath(sum string) string {
+	return filepath.Join(c.Dir, hdfCacheObjectsDir, sum)
+}
+
+func (c *HdfCache) readIndex() (map[string]HdfCacheEntry, error) {
+	index := map[string]HdfCacheEntry{}
+	data, err := os.ReadFile(filepath.Join(c.Dir, hdfCacheIndexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &index)
+	return index, err
+}
+
+func (c *HdfCache) writeIndex(index map[string]HdfCacheEntry) error {
+	err := os.MkdirAll(c.Dir, 0700)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ras.WriteFileAtomic(filepath.Join(c.Dir, hdfCacheIndexFile), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+func fileSha256(filepath string) (string, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sha := sha256.New()
+	_, err = io.Copy(sha, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sha.Sum(nil)), nil
+}
+
+// hdfCacheSource is the cache key of a data source path.  It identifies the file by store type, profile and full
+// path so payloads that name the same store differently share cached copies.
+func hdfCacheSource(store *cc.DataStore, srcpath string) string {
+	root := store.Parameters.GetStringOrDefault(s3RootParam, "")
+	return fmt.Sprintf("%s:%s:%s", store.StoreType, store.DsProfile, filepath.ToSlash(filepath.Join(root, srcpath)))
+}
+
+// remoteObjectInfo reads the size and ETag of a data source path from the store.  Stores that cannot provide the
+// metadata return an empty RemoteObjectInfo.
+func remoteObjectInfo(store *cc.DataStore, srcpath string) RemoteObjectInfo {
+	fds, ok := store.Session.(cc.FileDataStoreInterface)
+	if !ok {
+		return RemoteObjectInfo{}
+	}
+	info, err := fds.GetFilestore().GetObjectInfo(filestore.PathConfig{Path: fds.GetAbsolutePath(srcpath)})
+	if err != nil {
+		log.Printf("unable to read store metadata for %s: %s\n", srcpath, err)
+		return RemoteObjectInfo{}
+	}
+	remote := RemoteObjectInfo{Size: info.Size()}
+	if s3info, ok := info.(*filestore.S3AttributesFileInfo); ok && s3info.GetObjectAttributesOutput != nil && s3info.ETag != nil {
+		remote.ETag = strings.Trim(*s3info.ETag, "\"")
+	}
+	return remote
+}
@@ -0,0 +1,189 @@
+package actions
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func cacheFetcher(content string, calls *int) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		*calls++
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+}
+
+func TestHdfCacheFetchOnce(t *testing.T) {
+	cache := &HdfCache{Dir: t.TempDir(), MaxBytes: 1024}
+	calls := 0
+	remote := RemoteObjectInfo{Size: 5, ETag: "abc"}
+	first, err := cache.Fetch("S3:FFRD:results/p01.hdf", remote, cacheFetcher("hello", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := cache.Fetch("S3:FFRD:results/p01.hdf", remote, cacheFetcher("hello", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || first != second {
+		t.Errorf("expected one download and the same cached file, got %d downloads, %s and %s", calls, first, second)
+	}
+	data, err := os.ReadFile(first)
+	if err != nil || string(data) != "hello" {
+		t.Errorf("unexpected cached content %q, %v", data, err)
+	}
+
+	//a new etag invalidates the cached copy and the stale copy is removed
+	_, err = cache.Fetch("S3:FFRD:results/p01.hdf", RemoteObjectInfo{Size: 5, ETag: "def"}, cacheFetcher("world", &calls))
+	if err != nil || calls != 2 {
+		t.Errorf("expected a second download after the etag changed, got %d downloads, %v", calls, err)
+	}
+	if FileExists(first) {
+		t.Error("expected the stale cached copy to be removed")
+	}
+
+	//identical content from another source is stored once
+	_, err = cache.Fetch("S3:FFRD:copy/p01.hdf", RemoteObjectInfo{}, cacheFetcher("world", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Size() != 5 {
+		t.Errorf("expected 5 cached bytes, got %d", cache.Size())
+	}
+}
+
+func TestHdfCacheConcurrentFetch(t *testing.T) {
+	cache := &HdfCache{Dir: t.TempDir(), MaxBytes: 1024}
+	var calls atomic.Int32
+	fetch := func() (io.ReadCloser, error) {
+		calls.Add(1)
+		return io.NopCloser(strings.NewReader("hello")), nil
+	}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.Fetch("S3:FFRD:results/p01.hdf", RemoteObjectInfo{Size: 5, ETag: "abc"}, fetch)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("expected one download for concurrent fetches, got %d", calls.Load())
+	}
+}
+
+func TestHdfCacheParallelSources(t *testing.T) {
+	cache := &HdfCache{Dir: t.TempDir(), MaxBytes: 1024}
+	//the download of a waits for the download of b, so a cache locked for every source would time out
+	bDone := make(chan struct{})
+	fetchA := func() (io.ReadCloser, error) {
+		select {
+		case <-bDone:
+			return io.NopCloser(strings.NewReader("aaaaa")), nil
+		case <-time.After(5 * time.Second):
+			return nil, errors.New("the download of b was blocked by the download of a")
+		}
+	}
+	fetchB := func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("bbbbb")), nil
+	}
+	errs := make(chan error, 1)
+	go func() {
+		_, err := cache.Fetch("a", RemoteObjectInfo{Size: 5}, fetchA)
+		errs <- err
+	}()
+	//wait until a holds its source lock and is downloading
+	for {
+		cache.mu.Lock()
+		_, started := cache.sources["a"]
+		cache.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	_, err := cache.Fetch("b", RemoteObjectInfo{Size: 5}, fetchB)
+	close(bDone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if cache.Size() != 10 {
+		t.Errorf("expected 10 cached bytes, got %d", cache.Size())
+	}
+}
+
+func TestDefaultHdfCacheDir(t *testing.T) {
+	if dir := defaultHdfCacheDir(); !strings.HasPrefix(dir, filepath.Dir(MODEL_DIR)+string(filepath.Separator)) || strings.HasPrefix(dir, MODEL_DIR) {
+		t.Errorf("expected the default cache in the workspace beside the model directory, got %s", dir)
+	}
+}
+
+func TestHdfCacheRejectsShortDownload(t *testing.T) {
+	cache := &HdfCache{Dir: t.TempDir()}
+	calls := 0
+	_, err := cache.Fetch("src", RemoteObjectInfo{Size: 10}, cacheFetcher("short", &calls))
+	if err == nil {
+		t.Fatal("expected a size mismatch error")
+	}
+	if _, ok := cache.Lookup("src", RemoteObjectInfo{}); ok {
+		t.Error("a failed download should not be cached")
+	}
+}
+
+func TestHdfCacheChecksum(t *testing.T) {
+	cache := &HdfCache{Dir: t.TempDir()}
+	calls := 0
+	localpath, err := cache.Fetch("src", RemoteObjectInfo{}, cacheFetcher("hello", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(localpath, []byte("jello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Lookup("src", RemoteObjectInfo{}); ok {
+		t.Error("a modified cached copy should fail the checksum")
+	}
+}
+
+func TestHdfCacheEviction(t *testing.T) {
+	cache := &HdfCache{Dir: t.TempDir(), MaxBytes: 12}
+	calls := 0
+	for _, src := range []string{"a", "b"} {
+		_, err := cache.Fetch(src, RemoteObjectInfo{}, cacheFetcher("12345"+src, &calls))
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := cache.Lookup("a", RemoteObjectInfo{}); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	time.Sleep(time.Millisecond)
+	//b is now the least recently used and is evicted to make room for c
+	_, err := cache.Fetch("c", RemoteObjectInfo{}, cacheFetcher("123456", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Lookup("b", RemoteObjectInfo{}); ok {
+		t.Error("expected b to be evicted")
+	}
+	if _, ok := cache.Lookup("a", RemoteObjectInfo{}); !ok {
+		t.Error("expected a to be kept")
+	}
+	if cache.Size() > cache.MaxBytes {
+		t.Errorf("cache size %d is over the cap %d", cache.Size(), cache.MaxBytes)
+	}
+}
@@ -0,0 +1,237 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+	"github.com/usace-cloud-compute/go-hdf5"
+)
+
+// HdfAccess is the method used to read an hdf file from a data source
+type HdfAccess string
+
+const (
+	HdfAccessAuto     HdfAccess = "auto"     //local if the file is present, then the job cache, then s3 or http range reads
+	HdfAccessLocal    HdfAccess = "local"    //direct access to a local or mounted file
+	HdfAccessS3       HdfAccess = "s3"       //ROS3 range reads against S3 or an S3 compatible store such as MinIO
+	HdfAccessHttp     HdfAccess = "http"     //unauthenticated range reads against an http(s) url
+	HdfAccessDownload HdfAccess = "download" //download the file to the workspace and read it locally
+	HdfAccessCache    HdfAccess = "cache"    //read a cached copy, downloading it into the job cache when there is none
+)
+
+const (
+	HDF_ACCESS_ATTR   = "hdf-access"
+	HDF_PATHKEY       = "hdf"
+	AWSENDPOINT       = "AWS_ENDPOINT"
+	AWSREGION         = "AWS_DEFAULT_REGION"
+	AWSACCESSKEY      = "AWS_ACCESS_KEY_ID"
+	AWSSECRETKEY      = "AWS_SECRET_ACCESS_KEY"
+	defaultAwsRegion  = "us-east-1"
+	s3RootParam       = "root"
+	fsbRootPathEnvVar = "FSB_ROOT_PATH"
+	hdfWorkspaceDir   = "ras-runner-hdf"
+)
+
+// HdfDataSourceProvider is the part of the CC IOManager needed to open hdf data sources.
+// Both cc.Action and cc.PluginManager satisfy it.
+type HdfDataSourceProvider interface {
+	GetInputDataSource(name string) (cc.DataSource, error)
+	GetStore(name string) (*cc.DataStore, error)
+	GetReader(input cc.DataSourceOpInput) (io.ReadCloser, error)
+}
+
+// HdfSourceInput identifies an hdf file in a CC input data source
+type HdfSourceInput struct {
+	DataSourceName string
+	PathKey        string    //defaults to "hdf"
+	Access         HdfAccess //defaults to auto
+}
+
+// OpenHdfSource opens an hdf file in an input data source for reading.  The file is opened read only using the
+// requested access method.  Auto access uses a local copy when one is present and otherwise fetches the file into
+// the job cache on first use, so later actions reuse the cached copy.  Range reads for S3 and http stores are only
+// used when the file cannot be downloaded.
+func OpenHdfSource(provider HdfDataSourceProvider, input HdfSourceInput) (*hdf5.File, error) {
+	if input.PathKey == "" {
+		input.PathKey = HDF_PATHKEY
+	}
+	if input.Access == "" {
+		input.Access = HdfAccessAuto
+	}
+	ds, err := provider.GetInputDataSource(input.DataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting input source %s: %s", input.DataSourceName, err)
+	}
+	srcpath, ok := ds.Paths[input.PathKey]
+	if !ok {
+		return nil, fmt.Errorf("input source %s does not include a %s path", input.DataSourceName, input.PathKey)
+	}
+	store, err := provider.GetStore(ds.StoreName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting input store %s: %s", ds.StoreName, err)
+	}
+
+	download := func() (*hdf5.File, error) {
+		return downloadHdf(provider, input, store, srcpath)
+	}
+
+	switch input.Access {
+	case HdfAccessLocal:
+		return openLocalHdf(localHdfCopy(store, srcpath))
+	case HdfAccessS3:
+		return openS3Hdf(store, srcpath)
+	case HdfAccessHttp:
+		return openHttpHdf(srcpath)
+	case HdfAccessDownload, HdfAccessCache:
+		return download()
+	case HdfAccessAuto:
+		localpath := LocalHdfPath(store, srcpath)
+		if FileExists(localpath) {
+			return openLocalHdf(localpath)
+		}
+		f, err := download()
+		if err == nil {
+			return f, nil
+		}
+		switch {
+		case store.StoreType == cc.FSS3:
+			log.Printf("%s, using range reads\n", err)
+			return openS3Hdf(store, srcpath)
+		case isHttpUrl(srcpath):
+			log.Printf("%s, using range reads\n", err)
+			return openHttpHdf(srcpath)
+		default:
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid hdf access method: %s", input.Access)
+	}
+}
+
+// HdfAccessFromAttributes reads the hdf access method from the action attributes, defaulting to auto
+func HdfAccessFromAttributes(attributes cc.PayloadAttributes) HdfAccess {
+	return HdfAccess(attributes.GetStringOrDefault(HDF_ACCESS_ATTR, string(HdfAccessAuto)))
+}
+
+// LocalHdfPath returns the local file path of a data source path.  Paths in file system stores are resolved
+// against the store root.
+func LocalHdfPath(store *cc.DataStore, srcpath string) string {
+	if store.StoreType == cc.FSB && !filepath.IsAbs(srcpath) {
+		root := store.Parameters.GetStringOrDefault(s3RootParam, os.Getenv(fsbRootPathEnvVar))
+		return filepath.Join(root, srcpath)
+	}
+	return srcpath
+}
+
+// localHdfCopy returns the local path of the data source file, or of a copy of the file in the model directory
+// made by copy-inputs
+func localHdfCopy(store *cc.DataStore, srcpath string) string {
+	localpath := LocalHdfPath(store, srcpath)
+	if FileExists(localpath) {
+		return localpath
+	}
+	modelcopy := filepath.Join(MODEL_DIR, filepath.Base(srcpath))
+	if FileExists(modelcopy) {
+		return modelcopy
+	}
+	return localpath
+}
+
+// S3HdfUrl builds the ROS3 url of a data source path in an S3 store.  When the store profile includes an
+// AWS_ENDPOINT, for example a MinIO server, a path style url on that endpoint is returned.
+func S3HdfUrl(store *cc.DataStore, srcpath string) (string, error) {
+	bucket := os.Getenv(profileEnv(store.DsProfile, AWSBUCKET))
+	if bucket == "" {
+		return "", fmt.Errorf("%s is not set", profileEnv(store.DsProfile, AWSBUCKET))
+	}
+	root := strings.Trim(store.Parameters.GetStringOrDefault(s3RootParam, ""), "/")
+	key := EncodeUrlPath(strings.TrimPrefix(path.Join(root, srcpath), "/"))
+	if endpoint := os.Getenv(profileEnv(store.DsProfile, AWSENDPOINT)); endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimRight(endpoint, "/"), bucket, key), nil
+	}
+	if region := os.Getenv(profileEnv(store.DsProfile, AWSREGION)); region != "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, key), nil
+	}
+	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", bucket, key), nil
+}
+
+func openLocalHdf(localpath string) (*hdf5.File, error) {
+	if !FileExists(localpath) {
+		return nil, fmt.Errorf("hdf file %s does not exist", localpath)
+	}
+	return hdf5.OpenFile(localpath, hdf5.F_ACC_RDONLY)
+}
+
+func openS3Hdf(store *cc.DataStore, srcpath string) (*hdf5.File, error) {
+	url, err := S3HdfUrl(store, srcpath)
+	if err != nil {
+		return nil, err
+	}
+	profile := store.DsProfile
+	region := os.Getenv(profileEnv(profile, AWSREGION))
+	if region == "" {
+		region = defaultAwsRegion
+	}
+	accessKey := os.Getenv(profileEnv(profile, AWSACCESSKEY))
+	return openRos3Hdf(url, hdf5.H5FD_ROS3_FAPL{
+		Version:               1,
+		Authenticate:          accessKey != "",
+		AWS_REGION:            region,
+		AWS_ACCESS_KEY_ID:     accessKey,
+		AWS_SECRET_ACCESS_KEY: os.Getenv(profileEnv(profile, AWSSECRETKEY)),
+	})
+}
+
+func openHttpHdf(url string) (*hdf5.File, error) {
+	if !isHttpUrl(url) {
+		return nil, fmt.Errorf("%s is not an http url", url)
+	}
+	return openRos3Hdf(url, hdf5.H5FD_ROS3_FAPL{Version: 1})
+}
+
+// openRos3Hdf opens a remote file with the ROS3 driver, which reads the file with http range requests
+func openRos3Hdf(url string, ros3 hdf5.H5FD_ROS3_FAPL) (*hdf5.File, error) {
+	fapl, err := hdf5.NewPropList(hdf5.P_FILE_ACCESS)
+	if err != nil {
+		return nil, err
+	}
+	defer fapl.Close()
+	err = hdf5.H5PsetFaplRos3d(fapl, ros3)
+	if err != nil {
+		return nil, err
+	}
+	return hdf5.OpenFileWithProp(url, hdf5.F_ACC_RDONLY, fapl)
+}
+
+// downloadHdf copies the data source file into the job cache and opens the cached copy.  The cache is kept out of
+// the model directory so downloads never replace model files with the same name.
+func downloadHdf(provider HdfDataSourceProvider, input HdfSourceInput, store *cc.DataStore, srcpath string) (*hdf5.File, error) {
+	localpath, err := DefaultHdfCache().Fetch(hdfCacheSource(store, srcpath), remoteObjectInfo(store, srcpath), func() (io.ReadCloser, error) {
+		return provider.GetReader(cc.DataSourceOpInput{
+			DataSourceName: input.DataSourceName,
+			PathKey:        input.PathKey,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to download %s: %s", srcpath, err)
+	}
+	return openLocalHdf(localpath)
+}
+
+func isHttpUrl(val string) bool {
+	return strings.HasPrefix(val, "http://") || strings.HasPrefix(val, "https://")
+}
+
+// profileEnv returns the name of a profile scoped environment variable, following the CC store convention
+func profileEnv(profile string, name string) string {
+	if profile == "" {
+		return name
+	}
+	return fmt.Sprintf("%s_%s", profile, name)
+}
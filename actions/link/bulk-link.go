@@ -0,0 +1,232 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"ras-runner/actions"
+	"ras-runner/ras"
+	"strconv"
+	"strings"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+	"github.com/usace-cloud-compute/go-hdf5"
+)
+
+func init() {
+	cc.ActionRegistry.RegisterAction("bulk-link-boundary-conditions", &BulkLinkAction{})
+}
+
+const (
+	mappingSourceName string = "mapping" //default input data source of the mapping table
+	mappingPathKey    string = "mapping"
+)
+
+// LinkMapping maps a single source column or reference line to a destination boundary condition.
+// Mappings with a Refline are migrated as reference lines, all others are migrated by Column.
+type LinkMapping struct {
+	Source   string `json:"source"`   //name of the input data source holding the source hdf file
+	DataPath string `json:"datapath"` //dataset (column) or reference line group (refline) in the source file
+	Column   int    `json:"column"`   //1-based source column index
+	Refline  string `json:"refline"`  //reference line name
+	BcName   string `json:"bcname"`   //destination boundary condition name or datapath
+}
+
+func (lm LinkMapping) String() string {
+	if lm.Refline != "" {
+		return fmt.Sprintf("%s:%s[%s] -> %s", lm.Source, lm.DataPath, lm.Refline, lm.BcName)
+	}
+	return fmt.Sprintf("%s:%s[%d] -> %s", lm.Source, lm.DataPath, lm.Column, lm.BcName)
+}
+
+// BulkLinkAction applies every mapping in a mapping table to the boundary conditions of a plan HDF file.
+// Each source file and the destination plan are opened once for the entire set of mappings.  Mappings that
+// fail to resolve or migrate are reported and the remaining mappings are still applied.
+type BulkLinkAction struct {
+	cc.ActionRunnerBase
+	ModelDir string
+}
+
+func (a *BulkLinkAction) Run() error {
+	log.Printf("Ready to bulk link boundary conditions %s\n", a.Action.Description)
+	if a.ModelDir == "" {
+		a.ModelDir = actions.MODEL_DIR
+	}
+
+	mappingSource := a.Action.Attributes.GetStringOrDefault("mappingSource", mappingSourceName)
+	mappings, err := ReadLinkMappings(a.PluginManager, mappingSource)
+	if err != nil {
+		return err
+	}
+
+	planFileName, err := a.Action.Attributes.GetString("planHdfFile")
+	if err != nil {
+		return fmt.Errorf("action attributes do not include a planHdfFile")
+	}
+	planFilePath := fmt.Sprintf("%v/%v", a.ModelDir, planFileName)
+	if !actions.FileExists(planFilePath) {
+		return fmt.Errorf("input source %s, was not found in local directory. Run copy-inputs first", planFilePath)
+	}
+
+	destfile, err := hdf5.OpenFile(planFilePath, hdf5.F_ACC_RDWR)
+	if err != nil {
+		return err
+	}
+	defer destfile.Close()
+
+	catalog, err := ras.BoundaryConditionsFromFile(destfile)
+	if err != nil {
+		return err
+	}
+
+	srcfiles := make(map[string]*hdf5.File)
+	defer func() {
+		for _, f := range srcfiles {
+			f.Close()
+		}
+	}()
+
+	failures := []string{}
+	for _, mapping := range mappings {
+		err = a.applyMapping(mapping, catalog, srcfiles, destfile)
+		if err != nil {
+			log.Printf("unable to link %s: %s\n", mapping, err)
+			failures = append(failures, mapping.String())
+		}
+	}
+
+	log.Printf("linked %d of %d boundary conditions\n", len(mappings)-len(failures), len(mappings))
+	if len(failures) > 0 && !a.Action.Attributes.GetBooleanOrDefault("allowUnresolved", false) {
+		return fmt.Errorf("%d mappings were not linked: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (a *BulkLinkAction) applyMapping(mapping LinkMapping, catalog ras.BoundaryConditionCatalog, srcfiles map[string]*hdf5.File, destfile *hdf5.File) error {
+	bc, err := catalog.Find(mapping.BcName)
+	if err != nil {
+		return err
+	}
+	srcfile, ok := srcfiles[mapping.Source]
+	if !ok {
+		srcfile, err = actions.OpenHdfSource(a.PluginManager, actions.HdfSourceInput{
+			DataSourceName: mapping.Source,
+			PathKey:        srcPathField,
+			Access:         actions.HdfAccessFromAttributes(a.Action.Attributes),
+		})
+		if err != nil {
+			return err
+		}
+		srcfiles[mapping.Source] = srcfile
+	}
+	if mapping.Refline != "" {
+		return migrateRefline(srcfile, mapping.DataPath, mapping.Refline, destfile, bc.DataPath)
+	}
+	return migrateColumn(srcfile, mapping.DataPath, destfile, bc.DataPath, mapping.Column)
+}
+
+// ReadLinkMappings reads the mapping table from the "mapping" path of an input data source.  The format is selected
+// by the extension of the path.
+func ReadLinkMappings(provider actions.HdfDataSourceProvider, sourceName string) ([]LinkMapping, error) {
+	src, err := provider.GetInputDataSource(sourceName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the mapping data source %s: %s", sourceName, err)
+	}
+	mappingPath, ok := src.Paths[mappingPathKey]
+	if !ok {
+		return nil, fmt.Errorf("the mapping data source %s does not have a %s path", sourceName, mappingPathKey)
+	}
+	reader, err := provider.GetReader(cc.DataSourceOpInput{
+		DataSourceName: sourceName,
+		PathKey:        mappingPathKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the mapping file %s: %s", mappingPath, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the mapping file %s: %s", mappingPath, err)
+	}
+	mappings, err := ParseLinkMappings(data, filepath.Ext(mappingPath))
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping file %s: %s", mappingPath, err)
+	}
+	return mappings, nil
+}
+
+// ParseLinkMappings reads a mapping table from CSV or JSON.  The format is selected by the file extension.
+// CSV tables require a header row using the LinkMapping json field names.
+func ParseLinkMappings(data []byte, ext string) ([]LinkMapping, error) {
+	var mappings []LinkMapping
+	var err error
+	switch strings.ToLower(ext) {
+	case ".json":
+		err = json.Unmarshal(data, &mappings)
+	case ".csv":
+		mappings, err = parseCsvLinkMappings(data)
+	default:
+		return nil, fmt.Errorf("unsupported mapping file type: %s", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range mappings {
+		if m.Source == "" || m.DataPath == "" || m.BcName == "" {
+			return nil, fmt.Errorf("mapping %d must include a source, datapath and bcname", i+1)
+		}
+		if m.Refline == "" && m.Column < 1 {
+			return nil, fmt.Errorf("mapping %d must include a refline or a column index greater than zero", i+1)
+		}
+	}
+	return mappings, nil
+}
+
+func parseCsvLinkMappings(data []byte) ([]LinkMapping, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("unable to read the mapping header")
+	}
+	fields := make(map[string]int)
+	for i, h := range header {
+		fields[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	value := func(record []string, field string) string {
+		if i, ok := fields[field]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	mappings := []LinkMapping{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		mapping := LinkMapping{
+			Source:   value(record, "source"),
+			DataPath: value(record, "datapath"),
+			Refline:  value(record, "refline"),
+			BcName:   value(record, "bcname"),
+		}
+		if col := value(record, "column"); col != "" {
+			mapping.Column, err = strconv.Atoi(col)
+			if err != nil {
+				return nil, fmt.Errorf("invalid column index: %s", col)
+			}
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
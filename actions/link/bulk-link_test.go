@@ -0,0 +1,93 @@
+package actions
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+)
+
+// mappingProvider serves a single mapping table from an input data source
+type mappingProvider struct {
+	source  cc.DataSource
+	content string
+}
+
+func (p mappingProvider) GetInputDataSource(name string) (cc.DataSource, error) {
+	if name != p.source.Name {
+		return cc.DataSource{}, errors.New("data source not found")
+	}
+	return p.source, nil
+}
+
+func (p mappingProvider) GetStore(name string) (*cc.DataStore, error) {
+	return nil, errors.New("no stores")
+}
+
+func (p mappingProvider) GetReader(input cc.DataSourceOpInput) (io.ReadCloser, error) {
+	if input.DataSourceName != p.source.Name {
+		return nil, errors.New("data source not found")
+	}
+	return io.NopCloser(strings.NewReader(p.content)), nil
+}
+
+func TestReadLinkMappings(t *testing.T) {
+	provider := mappingProvider{
+		source: cc.DataSource{
+			Name:  "links",
+			Paths: map[string]string{"mapping": "model/links.json"},
+		},
+		content: `[{"source":"upstream","datapath":"/Results/a","column":1,"bcname":"Upstream Q"}]`,
+	}
+	mappings, err := ReadLinkMappings(provider, "links")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mappings) != 1 || mappings[0].BcName != "Upstream Q" {
+		t.Errorf("unexpected mappings %v", mappings)
+	}
+	if _, err = ReadLinkMappings(provider, "mapping"); err == nil {
+		t.Error("expected an error for a missing data source")
+	}
+	provider.source.Paths = map[string]string{"hdf": "model/links.json"}
+	if _, err = ReadLinkMappings(provider, "links"); err == nil {
+		t.Error("expected an error for a data source without a mapping path")
+	}
+}
+
+func TestParseLinkMappingsCsv(t *testing.T) {
+	data := []byte(`source,datapath,column,refline,bcname
+upstream,/Results/Unsteady/Output/Output Blocks/Base Output/Unsteady Time Series/2D Flow Areas/Perimeter 1/Boundary Conditions/Outflow,2,,Upstream Q
+upstream,/Results/Unsteady/Output/Output Blocks/Base Output/Unsteady Time Series/Reference Lines,,Main Channel,Lateral 1
+`)
+	mappings, err := ParseLinkMappings(data, ".csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mappings) != 2 {
+		t.Fatalf("expected 2 mappings, got %d", len(mappings))
+	}
+	if mappings[0].Column != 2 || mappings[0].BcName != "Upstream Q" {
+		t.Errorf("unexpected column mapping %v", mappings[0])
+	}
+	if mappings[1].Refline != "Main Channel" || mappings[1].Column != 0 {
+		t.Errorf("unexpected refline mapping %v", mappings[1])
+	}
+}
+
+func TestParseLinkMappingsJson(t *testing.T) {
+	data := []byte(`[{"source":"upstream","datapath":"/Results/a","column":1,"bcname":"Upstream Q"}]`)
+	mappings, err := ParseLinkMappings(data, ".JSON")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mappings) != 1 || mappings[0].Column != 1 {
+		t.Errorf("unexpected mappings %v", mappings)
+	}
+	_, err = ParseLinkMappings([]byte(`[{"source":"upstream","datapath":"/Results/a","bcname":"Upstream Q"}]`), ".json")
+	if err == nil {
+		t.Error("expected an error for a mapping without a column or refline")
+	}
+}
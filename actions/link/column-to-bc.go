@@ -6,6 +6,7 @@ import (
 	"math"
 	"os"
 	"ras-runner/actions"
+	"ras-runner/ras"
 	"reflect"
 	"strconv"
 
@@ -30,7 +31,7 @@ import (
 //   Destination Dataset Structure: 2D array with time as first column and boundary condition values as second column
 //
 // Supported Store Types:
-//   S3 stores only (other store types will be added in future releases)
+//   Any store supported by actions.OpenHdfSource
 //
 // Error Handling:
 //   Returns descriptive error messages for invalid parameters, file access errors,
@@ -48,6 +49,7 @@ const (
 	colindexField = "column_index"
 	nameField     = "name"
 	dataPathField = "datapath"
+	bcNameField   = "bcname"
 	srcPathField  = "hdf"
 )
 
@@ -74,19 +76,30 @@ func (a *ColumnToBcAction) Run() error {
 	srcname := srcconfig[nameField].(string)
 	srcdatapath := srcconfig[dataPathField].(string)
 	destname := destconfig[nameField].(string)
-	destdatapath := destconfig[dataPathField].(string)
-
-	src, err := a.PluginManager.GetInputDataSource(srcname)
-	if err != nil {
-		return fmt.Errorf("error getting input source %s: %s", srcname, err)
+	destdatapath, ok := destconfig[dataPathField].(string)
+	if !ok {
+		//no explicit datapath, so look the boundary condition up by name in the destination plan
+		bcname, ok := destconfig[bcNameField].(string)
+		if !ok {
+			return fmt.Errorf("dest attribute data must include a %s or %s", dataPathField, bcNameField)
+		}
+		destdatapath, err = ras.ResolveBoundaryConditionPath(fmt.Sprintf("%s/%s", actions.MODEL_DIR, destname), bcname)
+		if err != nil {
+			return fmt.Errorf("unable to resolve boundary condition %s: %s", bcname, err)
+		}
 	}
 
-	srcstore, err := a.PluginManager.GetStore(src.StoreName)
+	srcfile, err := actions.OpenHdfSource(a.PluginManager, actions.HdfSourceInput{
+		DataSourceName: srcname,
+		PathKey:        srcPathField,
+		Access:         actions.HdfAccessFromAttributes(a.Action.Attributes),
+	})
 	if err != nil {
-		return fmt.Errorf("error getting input store %s: %s", src.StoreName, err)
+		return fmt.Errorf("unable to open input source %s: %s", srcname, err)
 	}
+	defer srcfile.Close()
 
-	err = MigrateColumnData(src.Paths[srcPathField], srcstore, srcdatapath, destname, destdatapath, readcol)
+	err = MigrateColumnData(srcfile, srcdatapath, destname, destdatapath, readcol)
 	if err != nil {
 		return fmt.Errorf("unable to migrate column data: %s", err)
 	}
@@ -98,26 +111,14 @@ func (a *ColumnToBcAction) Run() error {
 
 // MigrateColumnData transfers columnar data from source to destination HDF5 files
 // Parameters:
-//   - src: Source file path
-//   - srcstore: Data store for the source file
+//   - srcfile: Source file opened with actions.OpenHdfSource
 //   - src_datapath: Path to dataset within source file
 //   - dest: Destination file name
 //   - dest_datapath: Path to dataset within destination file
 //   - readcol: Column index to read from source (1-based)
-func MigrateColumnData(src string, srcstore *cc.DataStore, src_datapath string, dest string, dest_datapath string, readcol int) error {
-	if srcstore.StoreType == "S3" {
-		profile := srcstore.DsProfile
-		bucket := os.Getenv(fmt.Sprintf("%s_%s", profile, actions.AWSBUCKET))
-		src = fmt.Sprintf(actions.S3BucketTemplate, bucket, srcstore.Parameters["root"], actions.EncodeUrlPath(src))
-	}
-	srcfile, err := util.OpenFile(src, srcstore.DsProfile)
-	if err != nil {
-		return err
-	}
-	defer srcfile.Close()
-
+func MigrateColumnData(srcfile *hdf5.File, src_datapath string, dest string, dest_datapath string, readcol int) error {
 	destpath := fmt.Sprintf("%s/%s", actions.MODEL_DIR, dest)
-	_, err = os.Stat(destpath)
+	_, err := os.Stat(destpath)
 	if err != nil {
 		return err
 	}
@@ -130,6 +131,12 @@ func MigrateColumnData(src string, srcstore *cc.DataStore, src_datapath string,
 	}
 	defer destfile.Close()
 
+	return migrateColumn(srcfile, src_datapath, destfile, dest_datapath, readcol)
+}
+
+// migrateColumn writes a source column into a destination boundary condition using already open files
+func migrateColumn(srcfile *hdf5.File, src_datapath string, destfile *hdf5.File, dest_datapath string, readcol int) error {
+	var err error
 	//Get the data values from the source file
 	//this is the RAS model output
 	options := util.HdfReadOptions{
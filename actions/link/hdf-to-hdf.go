@@ -1,13 +1,16 @@
 package actions
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"reflect"
+	"path"
+	"ras-runner/actions"
+	"ras-runner/hdfext"
+	"strings"
 
 	"github.com/usace-cloud-compute/cc-go-sdk"
 	"github.com/usace-cloud-compute/go-hdf5"
-	"github.com/usace-cloud-compute/go-hdf5/util"
 )
 
 const (
@@ -18,14 +21,25 @@ func init() {
 	cc.ActionRegistry.RegisterAction("hdf-to-hdf", &HdftoHdfDatasetAction{})
 }
 
-/*
-	copies content of one hdf5 data file into another one that is local
-*/
-
+// HdftoHdfDatasetAction copies datasets or whole groups from one hdf5 file into a local hdf5 file.
+//
+// The source is opened read only and may be local or remote through the source store.  Data and attributes are
+// copied in the source dtype.  Destination datasets that do not exist are created when the "create" attribute is
+// true, and chunked destination datasets are resized to the source shape when the "resize" attribute is true.
+// Groups are copied recursively and the datasets in a group can be filtered with the "include" and "exclude" glob
+// patterns.
 type HdftoHdfDatasetAction struct {
 	cc.ActionRunnerBase
 }
 
+// HdfCopyOptions control how datasets are copied between hdf5 files
+type HdfCopyOptions struct {
+	Create  bool     //create destination datasets that do not exist
+	Resize  bool     //resize chunked destination datasets to the source shape
+	Include []string //glob patterns of the datasets to copy when copying a group
+	Exclude []string //glob patterns of the datasets to skip when copying a group
+}
+
 func (a *HdftoHdfDatasetAction) Run() error {
 	log.Printf("Ready to %s\n", a.Action.Description)
 
@@ -43,97 +57,302 @@ func (a *HdftoHdfDatasetAction) Run() error {
 		return fmt.Errorf("src and dest datapath lengths do not match")
 	}
 
+	options := HdfCopyOptions{
+		Create: a.Action.Attributes.GetBooleanOrDefault("create", false),
+		Resize: a.Action.Attributes.GetBooleanOrDefault("resize", false),
+	}
+	if _, ok := a.Action.Attributes["include"]; ok {
+		options.Include, err = a.Action.Attributes.GetStringSlice("include")
+		if err != nil {
+			return fmt.Errorf("invalid include patterns: %s", err)
+		}
+	}
+	if _, ok := a.Action.Attributes["exclude"]; ok {
+		options.Exclude, err = a.Action.Attributes.GetStringSlice("exclude")
+		if err != nil {
+			return fmt.Errorf("invalid exclude patterns: %s", err)
+		}
+	}
+
+	srcfile, err := actions.OpenHdfSource(a.Action, actions.HdfSourceInput{
+		DataSourceName: "src",
+		PathKey:        srcDataSourcePath,
+		Access:         actions.HdfAccessFromAttributes(a.Action.Attributes),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to open source %s: %s", src.Paths[srcDataSourcePath], err)
+	}
+	defer srcfile.Close()
+
+	destfile, err := hdf5.OpenFile(dest.Paths[srcDataSourcePath], hdf5.F_ACC_RDWR)
+	if err != nil {
+		return fmt.Errorf("unable to open destination %s: %s", dest.Paths[srcDataSourcePath], err)
+	}
+	defer destfile.Close()
+
 	for srckey, srcdatapath := range src.DataPaths {
-		err = CopyHdf5Dataset(src.Paths["hdf"], srcdatapath, dest.Paths["hdf"], dest.DataPaths[srckey])
+		err = CopyHdf5(srcfile, srcdatapath, destfile, dest.DataPaths[srckey], options)
 		if err != nil {
-			return fmt.Errorf("error copying from src to dest %s: %s", srcdatapath, dest.DataPaths[srckey])
+			return fmt.Errorf("error copying from src %s to dest %s: %s", srcdatapath, dest.DataPaths[srckey], err)
 		}
 	}
 	return nil
 }
 
+// CopyHdf5Dataset copies a single dataset between two local hdf5 files.
 func CopyHdf5Dataset(src string, srcdataset string, dest string, destdataset string) error {
-
-	srcfile, err := hdf5.OpenFile(src, hdf5.F_ACC_RDWR)
+	srcfile, err := hdf5.OpenFile(src, hdf5.F_ACC_RDONLY)
 	if err != nil {
 		return err
 	}
 	defer srcfile.Close()
 
-	destpath := dest //fmt.Sprintf("%s/%s", actions.MODEL_DIR, dest)
-
-	destfile, err := hdf5.OpenFile(destpath, hdf5.F_ACC_RDWR)
+	destfile, err := hdf5.OpenFile(dest, hdf5.F_ACC_RDWR)
 	if err != nil {
 		return err
 	}
 	defer destfile.Close()
 
-	//need to perform a read/write rather than a copy to.
+	return copyDataset(srcfile, srcdataset, destfile, destdataset, HdfCopyOptions{})
+}
 
-	var srcVals *util.HdfDataset
-	err = func() error {
-		srcoptions := util.HdfReadOptions{
-			Dtype:        reflect.Float32,
-			File:         srcfile,
-			ReadOnCreate: true,
-		}
-		srcVals, err = util.NewHdfDataset(srcdataset, srcoptions)
+// CopyHdf5 copies a dataset, or every dataset in a group, from the source file to the destination file.
+// Group copies are recursive and keep the relative paths of the source datasets under the destination path.
+func CopyHdf5(srcfile *hdf5.File, srcpath string, destfile *hdf5.File, destpath string, options HdfCopyOptions) error {
+	objType, err := hdfObjectType(srcfile, srcpath)
+	if err != nil {
+		return err
+	}
+	switch objType {
+	case hdf5.H5G_DATASET:
+		return copyDataset(srcfile, srcpath, destfile, destpath, options)
+	case hdf5.H5G_GROUP:
+		datasets, err := hdfDatasets(srcfile, srcpath)
 		if err != nil {
 			return err
 		}
-		defer srcVals.Close()
+		for _, ds := range datasets {
+			rel := strings.TrimPrefix(strings.TrimPrefix(ds, path.Clean(srcpath)), "/")
+			if !includeDataset(rel, options) {
+				continue
+			}
+			err = copyDataset(srcfile, ds, destfile, path.Join(destpath, rel), options)
+			if err != nil {
+				return fmt.Errorf("%s: %s", ds, err)
+			}
+		}
 		return nil
-	}()
-	if err != nil {
-		return err
+	default:
+		return fmt.Errorf("%s is not a dataset or group", srcpath)
 	}
+}
 
-	var dstVals *util.HdfDataset
-	err = func() error {
-		dstoptions := util.HdfReadOptions{
-			Dtype:        reflect.Float32,
-			File:         destfile,
-			ReadOnCreate: true,
+// copyDataset copies the data and attributes of a source dataset to a destination dataset of the same dtype and
+// shape.  Source attributes replace destination attributes of the same name.  Missing destination datasets are copied
+// whole when options.Create is true, and destination datasets of a different shape are resized to the source shape
+// when options.Resize is true and their maximum dimensions allow it.
+func copyDataset(srcfile *hdf5.File, srcpath string, destfile *hdf5.File, destpath string, options HdfCopyOptions) error {
+	if !hdfLinkExists(destfile, destpath) {
+		if !options.Create {
+			return fmt.Errorf("destination dataset %s does not exist", destpath)
 		}
-		dstVals, err = util.NewHdfDataset(destdataset, dstoptions)
+		err := createHdfGroups(destfile, path.Dir(destpath))
 		if err != nil {
 			return err
 		}
-		defer dstVals.Close()
-		return nil
-	}()
+		return srcfile.CopyTo(srcpath, destfile, destpath)
+	}
+
+	srcds, err := srcfile.OpenDataset(srcpath)
+	if err != nil {
+		return err
+	}
+	defer srcds.Close()
+	destds, err := destfile.OpenDataset(destpath)
 	if err != nil {
 		return err
 	}
+	defer destds.Close()
 
-	if srcVals.Cols() != dstVals.Cols() {
-		return fmt.Errorf("source column count doesnt equal dest column count")
+	srctype, err := srcds.Datatype()
+	if err != nil {
+		return err
+	}
+	defer srctype.Close()
+	desttype, err := destds.Datatype()
+	if err != nil {
+		return err
+	}
+	defer desttype.Close()
+	if !srctype.Equal(desttype) {
+		return errors.New("source and destination dtypes do not match")
+	}
+	if srctype.Class() == hdf5.T_VLEN || (&hdf5.VarLenType{Datatype: *srctype}).IsVariableStr() {
+		return errors.New("variable length data can only be copied to a new dataset")
 	}
 
-	if srcVals.Rows() != dstVals.Rows() {
-		return fmt.Errorf("source row count doesnt equal dest row count")
+	srcspace := srcds.Space()
+	defer srcspace.Close()
+	destspace := destds.Space()
+	defer destspace.Close()
+	srcdims, _, err := srcspace.SimpleExtentDims()
+	if err != nil {
+		return err
 	}
-	writer, err := destfile.OpenDataset(destdataset)
+	destdims, destmaxdims, err := destspace.SimpleExtentDims()
 	if err != nil {
 		return err
 	}
-	data := make([]float32, dstVals.Rows()*dstVals.Cols())
-	index := 0
-	for i := 0; i < dstVals.Rows(); i++ {
+	if fmt.Sprint(srcdims) != fmt.Sprint(destdims) {
+		if !options.Resize {
+			return fmt.Errorf("source shape %v does not match destination shape %v", srcdims, destdims)
+		}
+		if !extentFits(srcdims, destmaxdims) {
+			return fmt.Errorf("destination dataset with maximum shape %v cannot be resized to the source shape %v. Only chunked datasets can be resized", destmaxdims, srcdims)
+		}
+		err = hdfext.SetExtent(destds.ID(), srcdims)
+		if err != nil {
+			return err
+		}
+	}
 
-		srcRow := make([]float32, dstVals.Cols())
-		err := srcVals.ReadRow(i, &srcRow)
+	//copy the raw bytes in the file dtype so any fixed size dtype is preserved
+	data := make([]byte, srcspace.SimpleExtentNPoints()*int(srctype.Size()))
+	if len(data) > 0 {
+		err = srcds.Read(&data)
+		if err != nil {
+			return err
+		}
+		err = destds.Write(&data)
 		if err != nil {
 			return err
 		}
-		for j := 0; j < dstVals.Cols(); j++ {
-			data[index] = srcRow[j]
-			index++
+	}
+	return hdfext.CopyAttributes(srcds.ID(), destds.ID())
+}
+
+// extentFits reports whether a dataset with maximum dimensions maxdims can be resized to dims
+func extentFits(dims []uint, maxdims []uint) bool {
+	if len(dims) != len(maxdims) {
+		return false
+	}
+	for i, d := range dims {
+		if maxdims[i] != hdfext.Unlimited && d > maxdims[i] {
+			return false
 		}
 	}
-	err = writer.Write(data)
+	return true
+}
+
+// hdfObjectType returns the type of the object at a path
+func hdfObjectType(f *hdf5.File, objpath string) (hdf5.GType, error) {
+	objpath = path.Clean("/" + objpath)
+	if objpath == "/" {
+		return hdf5.H5G_GROUP, nil
+	}
+	parent, err := f.OpenGroup(path.Dir(objpath))
 	if err != nil {
-		return err
+		return hdf5.H5G_UNKNOWN, err
+	}
+	defer parent.Close()
+	numobj, err := parent.NumObjects()
+	if err != nil {
+		return hdf5.H5G_UNKNOWN, err
+	}
+	for i := uint(0); i < numobj; i++ {
+		name, err := parent.ObjectNameByIndex(i)
+		if err != nil {
+			return hdf5.H5G_UNKNOWN, err
+		}
+		if name == path.Base(objpath) {
+			return parent.ObjectTypeByIndex(i)
+		}
+	}
+	return hdf5.H5G_UNKNOWN, fmt.Errorf("%s was not found", objpath)
+}
+
+// hdfDatasets returns the full path of every dataset in a group and its sub groups
+func hdfDatasets(f *hdf5.File, grouppath string) ([]string, error) {
+	group, err := f.OpenGroup(grouppath)
+	if err != nil {
+		return nil, err
+	}
+	defer group.Close()
+	numobj, err := group.NumObjects()
+	if err != nil {
+		return nil, err
+	}
+	datasets := []string{}
+	for i := uint(0); i < numobj; i++ {
+		name, err := group.ObjectNameByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		objType, err := group.ObjectTypeByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		objpath := path.Join(grouppath, name)
+		switch objType {
+		case hdf5.H5G_DATASET:
+			datasets = append(datasets, objpath)
+		case hdf5.H5G_GROUP:
+			children, err := hdfDatasets(f, objpath)
+			if err != nil {
+				return nil, err
+			}
+			datasets = append(datasets, children...)
+		}
+	}
+	return datasets, nil
+}
+
+// hdfLinkExists checks each part of a path so missing intermediate groups are not an error
+func hdfLinkExists(f *hdf5.File, objpath string) bool {
+	current := ""
+	for _, part := range strings.Split(strings.Trim(objpath, "/"), "/") {
+		current = current + "/" + part
+		if !f.LinkExists(current) {
+			return false
+		}
+	}
+	return true
+}
+
+func createHdfGroups(f *hdf5.File, grouppath string) error {
+	current := ""
+	for _, part := range strings.Split(strings.Trim(grouppath, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		current = current + "/" + part
+		if !f.LinkExists(current) {
+			group, err := f.CreateGroup(current)
+			if err != nil {
+				return err
+			}
+			group.Close()
+		}
 	}
 	return nil
 }
+
+// includeDataset matches a dataset path, relative to the group being copied, against the include and exclude patterns.
+// Patterns match either the full relative path or the dataset name.
+func includeDataset(rel string, options HdfCopyOptions) bool {
+	matches := func(patterns []string) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, rel); ok {
+				return true
+			}
+			if ok, _ := path.Match(p, path.Base(rel)); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if len(options.Include) > 0 && !matches(options.Include) {
+		return false
+	}
+	return !matches(options.Exclude)
+}
@@ -0,0 +1,41 @@
+package actions
+
+import (
+	"ras-runner/hdfext"
+	"testing"
+)
+
+func TestIncludeDataset(t *testing.T) {
+	options := HdfCopyOptions{
+		Include: []string{"Boundary Conditions/*", "Time"},
+		Exclude: []string{"*Precipitation*"},
+	}
+	for rel, want := range map[string]bool{
+		"Boundary Conditions/Flow":          true,
+		"Boundary Conditions/Precipitation": false,
+		"Unsteady Time Series/Time":         true,
+		"Unsteady Time Series/Depth":        false,
+	} {
+		if got := includeDataset(rel, options); got != want {
+			t.Errorf("includeDataset(%s) = %t; want %t", rel, got, want)
+		}
+	}
+	if !includeDataset("any/dataset", HdfCopyOptions{}) {
+		t.Error("datasets should be included when there are no patterns")
+	}
+}
+
+func TestExtentFits(t *testing.T) {
+	if !extentFits([]uint{20, 3}, []uint{hdfext.Unlimited, 3}) {
+		t.Error("an unlimited dimension should fit any size")
+	}
+	if !extentFits([]uint{5, 3}, []uint{10, 3}) {
+		t.Error("a smaller shape should fit")
+	}
+	if extentFits([]uint{20, 3}, []uint{10, 3}) {
+		t.Error("a shape larger than the maximum should not fit")
+	}
+	if extentFits([]uint{20}, []uint{20, 3}) {
+		t.Error("a shape of a different rank should not fit")
+	}
+}
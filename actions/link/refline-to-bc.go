@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"ras-runner/actions"
 	"ras-runner/actions/utils"
+	"ras-runner/ras"
 	"reflect"
 
 	"github.com/usace-cloud-compute/cc-go-sdk"
@@ -48,23 +48,45 @@ func (a *ReflineToBc) Run() error {
 	log.Printf("Updating refline to boundary condition %s\n", a.Action.Description)
 	refline := a.Action.Attributes["refline"].(string)
 
-	useRemote := a.Action.Attributes.GetBooleanOrDefault("use-remote-reads", true)
+	access := actions.HdfAccessFromAttributes(a.Action.Attributes)
+	if !a.Action.Attributes.GetBooleanOrDefault("use-remote-reads", true) {
+		//use-remote-reads=false predates hdf-access and reads the copy-inputs copy in the model directory
+		access = actions.HdfAccessLocal
+	}
 	src, err := a.Action.IOManager.GetInputDataSource("source")
 	if err != nil {
 		return fmt.Errorf("error getting input source %s: %s", "source", err)
 	}
 
-	srcstore, err := a.Action.IOManager.GetStore(src.StoreName)
+	dest, err := a.Action.IOManager.GetOutputDataSource("destination")
 	if err != nil {
-		return fmt.Errorf("error getting input store %s: %s", src.StoreName, err)
+		return fmt.Errorf("error getting input source %s: %s", "source", err)
 	}
 
-	dest, err := a.Action.IOManager.GetOutputDataSource("destination")
+	bcline := dest.DataPaths["bcline"]
+	if bcline == "" {
+		//no explicit bcline datapath, so look the boundary condition up by name in the destination plan
+		bcname, err := a.Action.Attributes.GetString(bcNameField)
+		if err != nil {
+			return fmt.Errorf("destination must include a bcline datapath or the action must include a %s attribute", bcNameField)
+		}
+		bcline, err = ras.ResolveBoundaryConditionPath(fmt.Sprintf("%s/%s", actions.MODEL_DIR, dest.Paths["hdf"]), bcname)
+		if err != nil {
+			return fmt.Errorf("unable to resolve boundary condition %s: %s", bcname, err)
+		}
+	}
+
+	srcfile, err := actions.OpenHdfSource(a.Action, actions.HdfSourceInput{
+		DataSourceName: "source",
+		PathKey:        srcPathField,
+		Access:         access,
+	})
 	if err != nil {
-		return fmt.Errorf("error getting input source %s: %s", "source", err)
+		return fmt.Errorf("unable to open input source %s: %s", "source", err)
 	}
+	defer srcfile.Close()
 
-	err = MigrateRefLineData(src.Paths["hdf"], srcstore, src.DataPaths["refline"], dest.Paths["hdf"], dest.DataPaths["bcline"], refline, useRemote)
+	err = MigrateRefLineData(srcfile, src.DataPaths["refline"], dest.Paths["hdf"], bcline, refline)
 	if err != nil {
 		return fmt.Errorf("failed to migrate refline data: %s", err)
 	}
@@ -74,22 +96,28 @@ func (a *ReflineToBc) Run() error {
 	return nil
 }
 
-func MigrateRefLineData(src string, srcstore *cc.DataStore, src_datapath string, dest string, dest_datapath string, refline string, useRemote bool) error {
-	if useRemote {
-		profile := srcstore.DsProfile
-		bucket := os.Getenv(fmt.Sprintf("%s_%s", profile, actions.AWSBUCKET))
-		template := os.Getenv("HDF_AWS_S3_TEMPLATE")
-		src = fmt.Sprintf(template, bucket, srcstore.Parameters["root"], actions.EncodeUrlPath(src))
-	} else {
-		src = fmt.Sprintf("%s/%s", actions.MODEL_DIR, filepath.Base(src))
+// MigrateRefLineData writes a reference line flow from a source file, opened with actions.OpenHdfSource,
+// into a boundary condition in the destination plan in the model directory
+func MigrateRefLineData(srcfile *hdf5.File, src_datapath string, dest string, dest_datapath string, refline string) error {
+	destpath := fmt.Sprintf("%s/%s", actions.MODEL_DIR, dest)
+	_, err := os.Stat(destpath)
+	if err != nil {
+		return err
 	}
 
-	srcfile, err := util.OpenFile(src, srcstore.DsProfile)
+	var destfile *hdf5.File
+
+	destfile, err = hdf5.OpenFile(destpath, hdf5.F_ACC_RDWR)
 	if err != nil {
 		return err
 	}
-	defer srcfile.Close()
+	defer destfile.Close()
+
+	return migrateRefline(srcfile, src_datapath, refline, destfile, dest_datapath)
+}
 
+// migrateRefline writes a reference line flow into a destination boundary condition using already open files
+func migrateRefline(srcfile *hdf5.File, src_datapath string, refline string, destfile *hdf5.File, dest_datapath string) error {
 	srcTime, err := util.NewHdfDataset(actions.TimePath(src_datapath), util.HdfReadOptions{
 		Dtype:        reflect.Float64,
 		File:         srcfile,
@@ -148,20 +176,6 @@ func MigrateRefLineData(src string, srcstore *cc.DataStore, src_datapath string,
 		return fmt.Errorf("invalid reference line: %s", refline)
 	}
 
-	destpath := fmt.Sprintf("%s/%s", actions.MODEL_DIR, dest)
-	_, err = os.Stat(destpath)
-	if err != nil {
-		return err
-	}
-
-	var destfile *hdf5.File
-
-	destfile, err = hdf5.OpenFile(destpath, hdf5.F_ACC_RDWR)
-	if err != nil {
-		return err
-	}
-	defer destfile.Close()
-
 	//get a copy of the destination data
 	var destVals *util.HdfDataset
 
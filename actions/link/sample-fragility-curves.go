@@ -0,0 +1,85 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"ras-runner/actions"
+	"ras-runner/fragilitycurve"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+)
+
+func init() {
+	cc.ActionRegistry.RegisterAction("sample-fragility-curves", &SampleFragilityCurvesAction{})
+}
+
+// SampleFragilityCurvesAction samples a failure elevation for each structure in a fragility curve file and writes the
+// results in the fcFile format read by update-breach-bfile.  Samples are seeded from the event identifier, so
+// rerunning an event samples the same failure elevations.
+type SampleFragilityCurvesAction struct {
+	cc.ActionRunnerBase
+	ModelDir string
+}
+
+func (a *SampleFragilityCurvesAction) Run() error {
+	// Assumes the curve file was copied local with the CopyLocal a.Action.
+	log.Printf("Ready to sample fragility curves.")
+	if a.ModelDir == "" {
+		a.ModelDir = actions.MODEL_DIR
+	}
+
+	curveFileName, err := a.Action.Attributes.GetString("curveFile")
+	if err != nil {
+		return fmt.Errorf("action attributes do not include a curveFile")
+	}
+	curveFilePath := fmt.Sprintf("%v/%v", a.ModelDir, curveFileName)
+	if !actions.FileExists(curveFilePath) {
+		return fmt.Errorf("input source %s, was not found in local directory. Run copy-local first", curveFilePath)
+	}
+
+	fcFileName, err := a.Action.Attributes.GetString("fcFile")
+	if err != nil {
+		return fmt.Errorf("action attributes do not include a fcFile")
+	}
+
+	eventIdentifier := a.Action.Attributes.GetStringOrDefault("eventIdentifier", "")
+	if eventIdentifier == "" && a.PluginManager != nil {
+		eventIdentifier = a.PluginManager.EventIdentifier
+	}
+	if eventIdentifier == "" {
+		return fmt.Errorf("unable to seed the fragility curves without an event identifier")
+	}
+	seed := a.Action.Attributes.GetInt64OrDefault("seed", 0)
+
+	log.Printf("Sampling fragility curves for event %s\n", eventIdentifier)
+	return SampleFragilityCurves(curveFilePath, fmt.Sprintf("%v/%v", a.ModelDir, fcFileName), eventIdentifier, uint64(seed))
+}
+
+// SampleFragilityCurves samples the fragility curves in curveFilePath and writes the failure elevations to fcFilePath
+func SampleFragilityCurves(curveFilePath string, fcFilePath string, eventIdentifier string, seed uint64) error {
+	curveBytes, err := os.ReadFile(curveFilePath)
+	if err != nil {
+		return fmt.Errorf("unable to read the curveFile: %s", err)
+	}
+	var curves fragilitycurve.ModelFragilityCurves
+	err = json.Unmarshal(curveBytes, &curves)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling fragility curves from %s: %s", filepath.Base(curveFilePath), err)
+	}
+	result, err := curves.Sample(eventIdentifier, seed)
+	if err != nil {
+		return err
+	}
+	resultBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(fcFilePath, resultBytes, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to write the fcFile: %s", err)
+	}
+	return nil
+}
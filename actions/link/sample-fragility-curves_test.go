@@ -0,0 +1,57 @@
+package actions
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"ras-runner/fragilitycurve"
+	"testing"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+)
+
+func TestSampleFragilityCurvesAction(t *testing.T) {
+	dir := t.TempDir()
+	curves := `{"locations":[
+		{"location":"Dam","curve":{"type":"lognormal","mean":2.3,"standard_deviation":0.1,"shift":660}},
+		{"location":"Saddle","curve":{"type":"tabular","ordinates":[{"elevation":670,"probability":0},{"elevation":680,"probability":1}]}}
+	]}`
+	err := os.WriteFile(filepath.Join(dir, "curves.json"), []byte(curves), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func() fragilitycurve.ModelResult {
+		runner := SampleFragilityCurvesAction{
+			ActionRunnerBase: cc.ActionRunnerBase{
+				Action: cc.Action{IOManager: cc.IOManager{Attributes: map[string]any{
+					"curveFile":       "curves.json",
+					"fcFile":          "failure_elevations.json",
+					"eventIdentifier": "17",
+				}}},
+			},
+			ModelDir: dir,
+		}
+		err := runner.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, "failure_elevations.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result fragilitycurve.ModelResult
+		err = json.Unmarshal(b, &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	first := run()
+	if len(first.Results) != 2 || first.Results[0].Name != "Dam" || first.Results[0].FailureElevation <= 660 {
+		t.Fatalf("unexpected results %+v", first)
+	}
+	second := run()
+	if first.Results[0] != second.Results[0] || first.Results[1] != second.Results[1] {
+		t.Errorf("event 17 sampled different elevations %v %v", first, second)
+	}
+}
@@ -0,0 +1,326 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"ras-runner/actions"
+	"ras-runner/ras"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+	"github.com/usace-cloud-compute/go-hdf5"
+	"github.com/usace-cloud-compute/go-hdf5/util"
+)
+
+func init() {
+	cc.ActionRegistry.RegisterAction("timeseries-to-boundary-condition", &TimeSeriesToBcAction{})
+}
+
+const (
+	defaultTimeSeriesPathKey = "timeseries"
+	defaultTimeField         = "time"
+	defaultValueField        = "value"
+)
+
+// date time layouts tried, in order, when the action does not include a time_format
+var timeSeriesLayouts []string = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"01/02/2006",
+}
+
+// TimeSeries is a date time stamped series of values
+type TimeSeries struct {
+	Times  []time.Time
+	Values []float64
+}
+
+// TimeSeriesToBcAction reads a CSV or JSON time series from a CC input data source and writes it to a boundary condition
+// in a plan HDF file.  Time stamps are converted to the plan simulation time base and the series is linearly
+// interpolated onto the time ordinates of the destination boundary condition.
+type TimeSeriesToBcAction struct {
+	cc.ActionRunnerBase
+	ModelDir string
+}
+
+func (a *TimeSeriesToBcAction) Run() error {
+	log.Printf("Updating boundary condition from time series %s\n", a.Action.Description)
+	if a.ModelDir == "" {
+		a.ModelDir = actions.MODEL_DIR
+	}
+
+	srcconfig, err := a.Action.Attributes.GetMap("src")
+	if err != nil {
+		return fmt.Errorf("missing src attribute data")
+	}
+	destconfig, err := a.Action.Attributes.GetMap("dest")
+	if err != nil {
+		return fmt.Errorf("missing dest attribute data")
+	}
+
+	srcname, ok := srcconfig[nameField].(string)
+	if !ok {
+		return fmt.Errorf("src attribute data must include a %s", nameField)
+	}
+	pathkey := stringOrDefault(srcconfig, "pathkey", defaultTimeSeriesPathKey)
+	timeField := stringOrDefault(srcconfig, "time_field", defaultTimeField)
+	valueField := stringOrDefault(srcconfig, "value_field", defaultValueField)
+	timeFormat := stringOrDefault(srcconfig, "time_format", "")
+
+	src, err := a.Action.IOManager.GetInputDataSource(srcname)
+	if err != nil {
+		return fmt.Errorf("error getting input source %s: %s", srcname, err)
+	}
+	format := strings.ToLower(stringOrDefault(srcconfig, "format", filepath.Ext(src.Paths[pathkey])))
+
+	data, err := a.Action.Get(cc.DataSourceOpInput{
+		DataSourceName: srcname,
+		PathKey:        pathkey,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to read time series %s: %s", srcname, err)
+	}
+
+	ts, err := ParseTimeSeries(data, format, timeField, valueField, timeFormat)
+	if err != nil {
+		return fmt.Errorf("invalid time series %s: %s", srcname, err)
+	}
+
+	destname, ok := destconfig[nameField].(string)
+	if !ok {
+		return fmt.Errorf("dest attribute data must include a %s", nameField)
+	}
+	destpath := fmt.Sprintf("%s/%s", a.ModelDir, destname)
+	if !actions.FileExists(destpath) {
+		return fmt.Errorf("input source %s, was not found in local directory. Run copy-inputs first", destpath)
+	}
+	destfile, err := hdf5.OpenFile(destpath, hdf5.F_ACC_RDWR)
+	if err != nil {
+		return err
+	}
+	defer destfile.Close()
+
+	destdatapath, ok := destconfig[dataPathField].(string)
+	if !ok {
+		bcname, ok := destconfig[bcNameField].(string)
+		if !ok {
+			return fmt.Errorf("dest attribute data must include a %s or %s", dataPathField, bcNameField)
+		}
+		catalog, err := ras.BoundaryConditionsFromFile(destfile)
+		if err != nil {
+			return err
+		}
+		bc, err := catalog.Find(bcname)
+		if err != nil {
+			return fmt.Errorf("unable to resolve boundary condition %s: %s", bcname, err)
+		}
+		destdatapath = bc.DataPath
+	}
+
+	err = MigrateTimeSeries(ts, destfile, destdatapath)
+	if err != nil {
+		return fmt.Errorf("unable to migrate time series data: %s", err)
+	}
+
+	log.Printf("finished updating boundary condition %s\n", a.Action.Description)
+	return nil
+}
+
+// MigrateTimeSeries converts a time series to the plan time base and writes it to the destination boundary condition,
+// interpolating the series onto the existing boundary condition time ordinates.
+func MigrateTimeSeries(ts TimeSeries, destfile *hdf5.File, dest_datapath string) error {
+	start, err := ras.ReadSimulationStartTime(destfile)
+	if err != nil {
+		return err
+	}
+	srcTimes := make([]float64, len(ts.Times))
+	for i, t := range ts.Times {
+		srcTimes[i] = ras.DaysSince(start, t)
+	}
+
+	var destVals *util.HdfDataset
+	err = func() error {
+		destVals, err = util.NewHdfDataset(dest_datapath, util.HdfReadOptions{
+			Dtype:        reflect.Float32,
+			File:         destfile,
+			ReadOnCreate: true,
+		})
+		if err != nil {
+			return err
+		}
+		defer destVals.Close()
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	boundaryConditionData := make([]float32, destVals.Rows()*2)
+	for i := 0; i < destVals.Rows(); i++ {
+		destRow := make([]float32, 2)
+		err := destVals.ReadRow(i, &destRow)
+		if err != nil {
+			return err
+		}
+		val, err := interpolate(srcTimes, ts.Values, float64(destRow[0]))
+		if err != nil {
+			return err
+		}
+		boundaryConditionData[i*2] = destRow[0]
+		boundaryConditionData[i*2+1] = float32(val)
+	}
+
+	destWriter, err := destfile.OpenDataset(dest_datapath)
+	if err != nil {
+		return err
+	}
+	defer destWriter.Close()
+	return destWriter.Write(&boundaryConditionData)
+}
+
+// interpolate linearly interpolates the value at time t.  times must be sorted in ascending order.
+func interpolate(times []float64, values []float64, t float64) (float64, error) {
+	return ras.Interpolate(times, values, t)
+}
+
+// ParseTimeSeries reads a time series from CSV or JSON.
+//
+// CSV data requires a header row and the time and value columns are selected by header name.
+// JSON data is an array of objects and the time and value are selected by key.
+// Time stamps are parsed with timeFormat, a go time layout, or with a set of common layouts when timeFormat is empty.
+// The returned series is sorted by time.
+func ParseTimeSeries(data []byte, format string, timeField string, valueField string, timeFormat string) (TimeSeries, error) {
+	var times []string
+	var values []float64
+	var err error
+	switch strings.TrimPrefix(format, ".") {
+	case "csv":
+		times, values, err = parseCsvTimeSeries(data, timeField, valueField)
+	case "json":
+		times, values, err = parseJsonTimeSeries(data, timeField, valueField)
+	default:
+		return TimeSeries{}, fmt.Errorf("unsupported time series format: %s", format)
+	}
+	if err != nil {
+		return TimeSeries{}, err
+	}
+	if len(times) == 0 {
+		return TimeSeries{}, errors.New("time series is empty")
+	}
+	ts := TimeSeries{
+		Times:  make([]time.Time, len(times)),
+		Values: values,
+	}
+	for i, t := range times {
+		ts.Times[i], err = parseTimeStamp(t, timeFormat)
+		if err != nil {
+			return TimeSeries{}, err
+		}
+	}
+	sort.Sort(ts)
+	return ts, nil
+}
+
+func (ts TimeSeries) Len() int           { return len(ts.Times) }
+func (ts TimeSeries) Less(i, j int) bool { return ts.Times[i].Before(ts.Times[j]) }
+func (ts TimeSeries) Swap(i, j int) {
+	ts.Times[i], ts.Times[j] = ts.Times[j], ts.Times[i]
+	ts.Values[i], ts.Values[j] = ts.Values[j], ts.Values[i]
+}
+
+func parseTimeStamp(val string, timeFormat string) (time.Time, error) {
+	if timeFormat != "" {
+		return time.Parse(timeFormat, val)
+	}
+	for _, layout := range timeSeriesLayouts {
+		t, err := time.Parse(layout, val)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return ras.ParseRasDateTime(val)
+}
+
+func parseCsvTimeSeries(data []byte, timeField string, valueField string) ([]string, []float64, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, errors.New("unable to read the time series header")
+	}
+	timeCol, valueCol := -1, -1
+	for i, h := range header {
+		switch strings.TrimSpace(h) {
+		case timeField:
+			timeCol = i
+		case valueField:
+			valueCol = i
+		}
+	}
+	if timeCol < 0 || valueCol < 0 {
+		return nil, nil, fmt.Errorf("time series header must include %s and %s columns", timeField, valueField)
+	}
+	times := []string{}
+	values := []float64{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		val, err := strconv.ParseFloat(strings.TrimSpace(record[valueCol]), 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid value: %s", record[valueCol])
+		}
+		times = append(times, strings.TrimSpace(record[timeCol]))
+		values = append(values, val)
+	}
+	return times, values, nil
+}
+
+func parseJsonTimeSeries(data []byte, timeField string, valueField string) ([]string, []float64, error) {
+	records := []map[string]any{}
+	err := json.Unmarshal(data, &records)
+	if err != nil {
+		return nil, nil, err
+	}
+	times := make([]string, len(records))
+	values := make([]float64, len(records))
+	for i, record := range records {
+		t, ok := record[timeField].(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("record %d does not include a %s", i+1, timeField)
+		}
+		v, ok := record[valueField].(float64)
+		if !ok {
+			return nil, nil, fmt.Errorf("record %d does not include a numeric %s", i+1, valueField)
+		}
+		times[i] = t
+		values[i] = v
+	}
+	return times, values, nil
+}
+
+func stringOrDefault(config map[string]any, key string, defaultValue string) string {
+	if val, ok := config[key].(string); ok && val != "" {
+		return val
+	}
+	return defaultValue
+}
@@ -0,0 +1,49 @@
+package actions
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseTimeSeriesCsv(t *testing.T) {
+	data := []byte(`date,flow
+2020-01-01 06:00,20
+2020-01-01 00:00,10
+`)
+	ts, err := ParseTimeSeries(data, ".csv", "date", "flow", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.Len() != 2 || ts.Values[0] != 10 || !ts.Times[0].Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time series %v", ts)
+	}
+}
+
+func TestParseTimeSeriesJson(t *testing.T) {
+	data := []byte(`[{"time":"01Jan2020 2400","value":5.5}]`)
+	ts, err := ParseTimeSeries(data, "json", "time", "value", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ts.Times[0].Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)) || ts.Values[0] != 5.5 {
+		t.Errorf("unexpected time series %v", ts)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	times := []float64{0, 0.25, 0.5}
+	values := []float64{10, 20, 40}
+	for _, c := range []struct{ t, want float64 }{{0, 10}, {0.125, 15}, {0.25, 20}, {0.375, 30}, {0.5, 40}} {
+		got, err := interpolate(times, values, c.t)
+		if err != nil || math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("interpolate(%f) = %f, %v; want %f", c.t, got, err, c.want)
+		}
+	}
+	if _, err := interpolate(times, values, 0.75); err == nil {
+		t.Error("expected an error after the end of the time series")
+	}
+	if _, err := interpolate(times, values, -0.1); err == nil {
+		t.Error("expected an error before the start of the time series")
+	}
+}
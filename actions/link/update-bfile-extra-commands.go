@@ -0,0 +1,73 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"ras-runner/actions"
+	"ras-runner/ras"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+)
+
+func init() {
+	cc.ActionRegistry.RegisterAction("update-bfile-extra-commands", &UpdateBfileExtraCommandsAction{})
+}
+
+// UpdateBfileExtraCommandsAction adds and removes engine extra commands in a bfile
+type UpdateBfileExtraCommandsAction struct {
+	cc.ActionRunnerBase
+	ModelDir string
+}
+
+func (a *UpdateBfileExtraCommandsAction) Run() error {
+	// Assumes bFile was copied local with the CopyLocal a.Action.
+	log.Printf("Ready to update bFile extra commands.")
+	if a.ModelDir == "" {
+		a.ModelDir = actions.MODEL_DIR
+	}
+
+	bFileName, err := a.Action.Attributes.GetString("bFile")
+	if err != nil {
+		return fmt.Errorf("action attributes do not include a bFile")
+	}
+
+	var add, remove []string
+	if _, ok := a.Action.Attributes["add"]; ok {
+		add, err = a.Action.Attributes.GetStringSlice("add")
+		if err != nil {
+			return fmt.Errorf("invalid add commands: %s", err)
+		}
+	}
+	if _, ok := a.Action.Attributes["remove"]; ok {
+		remove, err = a.Action.Attributes.GetStringSlice("remove")
+		if err != nil {
+			return fmt.Errorf("invalid remove commands: %s", err)
+		}
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return errors.New("action attributes do not include commands to add or remove")
+	}
+
+	return UpdateBfileExtraCommands(fmt.Sprintf("%v/%v", a.ModelDir, bFileName), add, remove)
+}
+
+// UpdateBfileExtraCommands adds and then removes extra commands in the bfile, writing the bfile in place
+func UpdateBfileExtraCommands(bfilePath string, add []string, remove []string) error {
+	if !actions.FileExists(bfilePath) {
+		return fmt.Errorf("input source %s, was not found in local directory. Run copy-local first", bfilePath)
+	}
+	bf, err := ras.InitBFile(bfilePath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize and read the b-file: %s", err)
+	}
+	err = bf.UpdateExtraCommands(add, remove)
+	if err != nil {
+		return err
+	}
+	err = bf.WriteFile(bfilePath)
+	if err != nil {
+		return fmt.Errorf("error writing b file: %s", err)
+	}
+	return nil
+}
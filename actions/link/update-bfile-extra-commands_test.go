@@ -0,0 +1,59 @@
+package actions
+
+import (
+	"os"
+	"path/filepath"
+	"ras-runner/ras"
+	"reflect"
+	"testing"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+)
+
+func TestSkipDssIsIdempotent(t *testing.T) {
+	modelDir := t.TempDir()
+	data, err := os.ReadFile(MULTI_BREACH_BFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bfilePath := filepath.Join(modelDir, filepath.Base(MULTI_BREACH_BFILE))
+	err = os.WriteFile(bfilePath, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := UpdateBfileSkipDSSAction{
+		ActionRunnerBase: cc.ActionRunnerBase{
+			ActionName: "update-bfile-skip-dss",
+			Action: cc.Action{
+				IOManager: cc.IOManager{Attributes: map[string]any{"bFile": filepath.Base(bfilePath)}},
+			},
+		},
+		ModelDir: modelDir,
+	}
+	for i := 0; i < 2; i++ {
+		err = runner.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = UpdateBfileExtraCommands(bfilePath, []string{"OTHER_COMMAND"}, []string{ras.SKIP_HDF_DSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf, err := ras.InitBFile(bfilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := 0
+	for _, block := range bf.BfileBlocks {
+		if extra, ok := block.(*ras.ExtraCommands); ok {
+			blocks++
+			if !reflect.DeepEqual(extra.Commands, []string{"OTHER_COMMAND"}) {
+				t.Errorf("unexpected extra commands %v", extra.Commands)
+			}
+		}
+	}
+	if blocks != 1 {
+		t.Errorf("expected one extra commands block, found %d", blocks)
+	}
+}
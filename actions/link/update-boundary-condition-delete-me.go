@@ -29,17 +29,17 @@ func (a *UpdateBoundaryConditionAction) Run() error {
 	dest := a.Action.Attributes["dest"].(map[string]any)["name"].(string)
 	destdatapath := a.Action.Attributes["dest"].(map[string]any)["datapath"].(string)
 
-	src, err := a.PluginManager.GetInputDataSource(srcname)
+	srcfile, err := actions.OpenHdfSource(a.PluginManager, actions.HdfSourceInput{
+		DataSourceName: srcname,
+		PathKey:        "0",
+		Access:         actions.HdfAccessFromAttributes(a.Action.Attributes),
+	})
 	if err != nil {
-		return fmt.Errorf("error getting input source %s: %s", srcname, err)
-	}
-
-	srcstore, err := a.PluginManager.GetStore(src.StoreName)
-	if err != nil {
-		return fmt.Errorf("error getting input store %s: %s", src.StoreName, err)
+		return fmt.Errorf("unable to open input source %s: %s", srcname, err)
 	}
+	defer srcfile.Close()
 
-	err = MigrateBoundaryConditionData(src.Paths["0"], srcstore, srcdatapath, dest, destdatapath)
+	err = MigrateBoundaryConditionData(srcfile, srcdatapath, dest, destdatapath)
 	if err != nil {
 		return fmt.Errorf("unable to migrate boundary condition: %s", err)
 	}
@@ -48,20 +48,9 @@ func (a *UpdateBoundaryConditionAction) Run() error {
 	return nil
 }
 
-func MigrateBoundaryConditionData(src string, srcstore *cc.DataStore, src_datapath string, dest string, dest_datapath string) error {
-	if srcstore.StoreType == "S3" {
-		profile := srcstore.DsProfile
-		bucket := os.Getenv(fmt.Sprintf("%s_%s", profile, actions.AWSBUCKET))
-		src = fmt.Sprintf(actions.S3BucketTemplate, bucket, srcstore.Parameters["root"], actions.EncodeUrlPath(src))
-	}
-	srcfile, err := util.OpenFile(src, srcstore.DsProfile)
-	if err != nil {
-		return err
-	}
-	defer srcfile.Close()
-
+func MigrateBoundaryConditionData(srcfile *hdf5.File, src_datapath string, dest string, dest_datapath string) error {
 	destpath := fmt.Sprintf("%s/%s", actions.MODEL_DIR, dest)
-	_, err = os.Stat(destpath)
+	_, err := os.Stat(destpath)
 	if err != nil {
 		return fmt.Errorf("path %s does not exist", destpath)
 	}
@@ -1,13 +1,17 @@
 package actions
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"ras-runner/actions"
 	"ras-runner/fragilitycurve"
 	"ras-runner/ras"
+	"slices"
+	"strings"
 
 	"github.com/usace-cloud-compute/cc-go-sdk"
 )
@@ -16,9 +20,10 @@ func init() {
 	cc.ActionRegistry.RegisterAction("update-breach-bfile", &UpdateBfileAction{})
 }
 
-// UpdateBfileAction is an action that updates breach elevations in a bfile based on fragility curve results.
-// It reads a fragility curve output file, amends the breach elevations in the bfile, and writes the updated
-// bfile back to disk.
+// UpdateBfileAction is an action that updates breach triggers in a bfile based on fragility curve results.
+// It reads a fragility curve output file, amends the breach trigger and its elevations or time in the bfile, and
+// writes the updated bfile back to disk.  Results that can not be reconciled with the geometry and bfile are skipped,
+// or fail the action in strict mode.
 type UpdateBfileAction struct {
 	cc.ActionRunnerBase
 	ModelDir string
@@ -27,10 +32,11 @@ type UpdateBfileAction struct {
 // Run executes the UpdateBfileAction. It performs the following steps:
 // 1. Retrieves the path to the bFile and checks if it exists locally.
 // 2. Initializes the BFile using ras.InitBFile.
-// 3. Creates an SNET ID to name map from a specified geometry file.
+// 3. Reads the structures of a specified geometry file or structure catalog, if there is one.
 // 4. Loads fragility curve results from a JSON file.
-// 5. Amends the breach elevations in the bfile based on the fragility curve results.
-// 6. Writes the updated bfile back to disk.
+// 5. Amends the breach triggers in the bfile based on the fragility curve results.
+// 6. Writes the reconciliation report to the output data source, when there is one.
+// 7. Writes the updated bfile back to disk, unless strict mode found results that could not be reconciled.
 func (uba *UpdateBfileAction) Run() error {
 	// Assumes bFile and fragility curve file  were copied local with the CopyLocal uba.Action.
 	log.Printf("Ready to update bFile.")
@@ -48,17 +54,11 @@ func (uba *UpdateBfileAction) Run() error {
 		return fmt.Errorf("failed to initialized and read the b-file")
 	}
 
-	log.Print("Creating SNET ID to name map from geometry file")
-	hdfFileName, err := uba.Action.Attributes.GetString("geoHdfFile")
+	structures, err := readStructures(uba.Action.Attributes, uba.ModelDir)
 	if err != nil {
-		return fmt.Errorf("action attributes do not include a geoHdfFile")
-	}
-
-	hdfFilePath := fmt.Sprintf("%v/%v", uba.ModelDir, hdfFileName)
-	err = bf.SetSNetIDToNameFromGeoHDF(hdfFilePath)
-	if err != nil {
-		return fmt.Errorf("unable to set the snetID from the geohdf: %s", err)
+		return err
 	}
+	resolver := ras.NewStructureResolver(structures)
 
 	log.Print("Loading Fragility Curve Results")
 	fcFileName, err := uba.Action.Attributes.GetString("fcFile")
@@ -77,19 +77,262 @@ func (uba *UpdateBfileAction) Run() error {
 		return fmt.Errorf("error unmarshaling fragility curve from %s", fcFileName)
 	}
 
-	for _, fclr := range fcResult.Results {
-		err = bf.AmmendBreachElevations(fclr.Name, fclr.FailureElevation)
+	report := UpdateBreachTriggers(bf, resolver, fcResult)
+	log.Printf("updated %d of %d fragility curve locations\n", len(report.Updated), len(fcResult.Results))
+	reconcileErr := report.Err()
+	if reconcileErr != nil {
+		log.Println(reconcileErr)
+	}
+
+	outputDataSource, err := uba.Action.Attributes.GetString("outputDataSource")
+	if err == nil {
+		reportBytes, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = uba.Action.Put(cc.PutOpInput{
+			SrcReader: bytes.NewReader(reportBytes),
+			DataSourceOpInput: cc.DataSourceOpInput{
+				DataSourceName: outputDataSource,
+				PathKey:        "default",
+			},
+		})
 		if err != nil {
-			//@TODO...dont like this...doe we really skip on fail?
-			log.Printf("failed to ammend breach elevations: %s %f: %s\n", fclr.Name, fclr.FailureElevation, err)
+			return fmt.Errorf("unable to write the reconciliation report: %s", err)
 		}
 	}
+	if reconcileErr != nil && uba.Action.Attributes.GetBooleanOrDefault("strict", false) {
+		return reconcileErr
+	}
 
-	resultBytes, err := bf.Write()
+	err = bf.WriteFile(bfilePath)
 	if err != nil {
 		return fmt.Errorf("error writing b file: %s", err)
 	}
+	return nil
+
+}
+
+// readStructures reads the structures from the geoHdfFile or the structureCatalog, written by the structure-catalog
+// action, of an action.  Without either, only SNET IDs can identify structures and the structures are nil.
+func readStructures(attrs cc.PayloadAttributes, modelDir string) ([]ras.Structure, error) {
+	if hdfFileName, err := attrs.GetString("geoHdfFile"); err == nil {
+		log.Print("Reading structures from geometry file")
+		structures, err := ras.ReadStructureCatalog(fmt.Sprintf("%v/%v", modelDir, hdfFileName))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the structures from the geohdf: %s", err)
+		}
+		return structures, nil
+	}
+	if catalogFileName, err := attrs.GetString("structureCatalog"); err == nil {
+		log.Print("Reading structures from structure catalog")
+		catalogBytes, err := os.ReadFile(fmt.Sprintf("%v/%v", modelDir, catalogFileName))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the structureCatalog: %s", err)
+		}
+		structures := []ras.Structure{}
+		err = json.Unmarshal(catalogBytes, &structures)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshaling the structure catalog from %s: %s", catalogFileName, err)
+		}
+		return structures, nil
+	}
+	log.Print("No geometry or structure catalog, structures must be identified by SNET ID")
+	return nil, nil
+}
+
+// BreachUpdate is a structure whose breach trigger was updated from a fragility curve result
+type BreachUpdate struct {
+	Location    string `json:"location"`
+	SNetID      int    `json:"snet_id"`
+	FailureMode string `json:"failure_mode,omitempty"`
+	Trigger     string `json:"trigger"`
+}
+
+// BreachUpdateFailure is a fragility curve result that matched a structure but could not be applied to the bfile
+type BreachUpdateFailure struct {
+	Location string `json:"location"`
+	Error    string `json:"error"`
+}
 
-	return os.WriteFile(bfilePath, resultBytes, 0600)
+// BreachReconciliation reports how fragility curve results were matched to the structures of the geometry and the
+// breach data of the bfile.
+//   - Updated are the structures whose breach trigger was updated.
+//   - Superseded are results of a structure that were not applied because a result with another trigger governs.
+//   - NotFound are fragility curve locations that do not identify a structure in the geometry.
+//   - Failed are locations that matched a structure that has no breach data in the bfile, or an invalid trigger.
+//   - NotUpdated are the structures with breach data that no result updated.
+//   - DuplicateNames are locations that identify more than one structure, or structures identified by more than one
+//     result.  Results for these locations are not applied.
+type BreachReconciliation struct {
+	Updated        []BreachUpdate        `json:"updated"`
+	Superseded     []BreachUpdate        `json:"superseded"`
+	NotFound       []string              `json:"not_found"`
+	Failed         []BreachUpdateFailure `json:"failed"`
+	NotUpdated     []string              `json:"not_updated"`
+	DuplicateNames []string              `json:"duplicate_names"`
+}
 
+// Err summarizes the results that could not be reconciled, nil when every result updated a structure and every
+// breach structure was updated.
+func (br BreachReconciliation) Err() error {
+	problems := []string{}
+	if len(br.NotFound) > 0 {
+		problems = append(problems, fmt.Sprintf("locations not found in the geometry: %s", strings.Join(br.NotFound, ", ")))
+	}
+	if len(br.Failed) > 0 {
+		failed := make([]string, len(br.Failed))
+		for idx, f := range br.Failed {
+			failed[idx] = fmt.Sprintf("%s (%s)", f.Location, f.Error)
+		}
+		problems = append(problems, fmt.Sprintf("locations not updated: %s", strings.Join(failed, ", ")))
+	}
+	if len(br.NotUpdated) > 0 {
+		problems = append(problems, fmt.Sprintf("breach structures without a result: %s", strings.Join(br.NotUpdated, ", ")))
+	}
+	if len(br.DuplicateNames) > 0 {
+		problems = append(problems, fmt.Sprintf("duplicate names: %s", strings.Join(br.DuplicateNames, ", ")))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("fragility curve results do not reconcile with the bfile: %s", strings.Join(problems, "; "))
+}
+
+// UpdateBreachTriggers applies fragility curve results to the bfile, resolving the structure of each result by its
+// connection name, SNET ID or river, reach and RS, and reports the results and breach structures that could not be
+// reconciled.  A structure with results for several triggers is updated with the governing trigger of
+// fragilitycurve.GoverningResult and the other results are reported as superseded.  The breach data of every structure
+// known to the resolver is named.
+func UpdateBreachTriggers(bf *ras.Bfile, resolver *ras.StructureResolver, fcResult fragilitycurve.ModelResult) BreachReconciliation {
+	report := BreachReconciliation{
+		Updated:        []BreachUpdate{},
+		Superseded:     []BreachUpdate{},
+		NotFound:       []string{},
+		Failed:         []BreachUpdateFailure{},
+		NotUpdated:     []string{},
+		DuplicateNames: []string{},
+	}
+	bf.NameBreachData(resolver)
+
+	//results of each structure, in the order the structures are first listed
+	snetIDs := []int{}
+	structureResults := map[int][]fragilitycurve.FragilityCurveLocationResult{}
+	triggerCounts := map[int]map[string]int{}
+	duplicates := map[string]bool{}
+	for _, fclr := range fcResult.Results {
+		label := fclr.Label()
+		structure, err := resolver.Resolve(FragilityCurveStructureIdentifier(fclr))
+		switch {
+		case errors.Is(err, ras.ErrAmbiguousStructure):
+			duplicates[label] = true
+			continue
+		case errors.Is(err, ras.ErrStructureNotFound):
+			report.NotFound = append(report.NotFound, label)
+			continue
+		case err != nil:
+			report.Failed = append(report.Failed, BreachUpdateFailure{Location: label, Error: err.Error()})
+			continue
+		}
+		if _, ok := structureResults[structure.SNetID]; !ok {
+			snetIDs = append(snetIDs, structure.SNetID)
+			triggerCounts[structure.SNetID] = map[string]int{}
+		}
+		structureResults[structure.SNetID] = append(structureResults[structure.SNetID], fclr)
+		triggerCounts[structure.SNetID][fclr.TriggerType()]++
+	}
+
+	updated := map[int]bool{}
+	for _, snetID := range snetIDs {
+		results := structureResults[snetID]
+		duplicated := false
+		for _, count := range triggerCounts[snetID] {
+			duplicated = duplicated || count > 1
+		}
+		if duplicated {
+			for _, fclr := range results {
+				duplicates[fclr.Label()] = true
+			}
+			continue
+		}
+		fclr, err := fragilitycurve.GoverningResult(results)
+		label := fclr.Label()
+		var params ras.BreachParameters
+		if err == nil {
+			params, err = FragilityCurveBreachParameters(fclr)
+		}
+		var bd *ras.BreachData
+		if err == nil {
+			bd, err = bf.FindBreachDataBySNetID(snetID)
+		}
+		if err == nil {
+			err = bd.UpdateParameters(params)
+		}
+		if err != nil {
+			report.Failed = append(report.Failed, BreachUpdateFailure{Location: label, Error: err.Error()})
+			continue
+		}
+		updated[snetID] = true
+		report.Updated = append(report.Updated, BreachUpdate{Location: label, SNetID: snetID, FailureMode: fclr.FailureMode, Trigger: fclr.TriggerType()})
+		for _, r := range results {
+			if r.TriggerType() != fclr.TriggerType() {
+				report.Superseded = append(report.Superseded, BreachUpdate{Location: r.Label(), SNetID: snetID, FailureMode: r.FailureMode, Trigger: r.TriggerType()})
+			}
+		}
+	}
+	for name := range duplicates {
+		report.DuplicateNames = append(report.DuplicateNames, name)
+	}
+	slices.Sort(report.DuplicateNames)
+
+	for _, block := range bf.BfileBlocks {
+		bd, ok := block.(*ras.BreachData)
+		if !ok || updated[bd.SNetID] {
+			continue
+		}
+		name := bd.Name
+		if name == "" {
+			name = fmt.Sprintf("SNET ID %d", bd.SNetID)
+		}
+		report.NotUpdated = append(report.NotUpdated, name)
+	}
+	return report
+}
+
+// FragilityCurveStructureIdentifier identifies the structure of a fragility curve result, the location is the
+// connection name
+func FragilityCurveStructureIdentifier(fclr fragilitycurve.FragilityCurveLocationResult) ras.StructureIdentifier {
+	return ras.StructureIdentifier{
+		SNetID:     fclr.SNetID,
+		River:      fclr.River,
+		Reach:      fclr.Reach,
+		RS:         fclr.RS,
+		Connection: fclr.Name,
+	}
+}
+
+// FragilityCurveBreachParameters are the breach trigger settings of a fragility curve result.  Only the settings used
+// by the trigger are set, so the other trigger settings in the bfile are left unchanged.
+func FragilityCurveBreachParameters(fclr fragilitycurve.FragilityCurveLocationResult) (ras.BreachParameters, error) {
+	trigger := ras.BreachTrigger(fclr.TriggerType())
+	params := ras.BreachParameters{Trigger: &trigger}
+	switch fclr.TriggerType() {
+	case fragilitycurve.TRIGGER_ELEVATION:
+		params.FailureElevation = &fclr.FailureElevation
+	case fragilitycurve.TRIGGER_DURATION:
+		if fclr.ThresholdElevation == nil || fclr.ThresholdDuration == nil {
+			return params, fmt.Errorf("duration triggers require a threshold elevation and duration")
+		}
+		params.FailureElevation = &fclr.FailureElevation
+		params.ThresholdElevation = fclr.ThresholdElevation
+		params.ThresholdDuration = fclr.ThresholdDuration
+	case fragilitycurve.TRIGGER_TIME:
+		if fclr.FailureTime == nil {
+			return params, fmt.Errorf("time triggers require a failure time")
+		}
+		params.FailureTime = fclr.FailureTime
+	default:
+		return params, fmt.Errorf("unsupported trigger %q", fclr.Trigger)
+	}
+	return params, nil
 }
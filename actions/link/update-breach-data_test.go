@@ -3,6 +3,9 @@ package actions
 import (
 	"fmt"
 	"path/filepath"
+	"ras-runner/fragilitycurve"
+	"ras-runner/ras"
+	"reflect"
 	"testing"
 
 	"github.com/usace-cloud-compute/cc-go-sdk"
@@ -40,3 +43,201 @@ func TestBfileAction(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestFragilityCurveBreachParameters(t *testing.T) {
+	bf, err := ras.InitBFile(MULTI_BREACH_BFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf.SNETidToStructName = map[string]int{"Dam": 3, "Saddle": 6}
+	threshold, duration, failureTime := 640.0, 8.0, 12.5
+	results := []fragilitycurve.FragilityCurveLocationResult{
+		{Name: "Dam", FailureMode: fragilitycurve.PIPING, Trigger: fragilitycurve.TRIGGER_DURATION, FailureElevation: 680, ThresholdElevation: &threshold, ThresholdDuration: &duration},
+		{Name: "Saddle", FailureMode: fragilitycurve.SEISMIC, Trigger: fragilitycurve.TRIGGER_TIME, FailureTime: &failureTime},
+	}
+	for _, result := range results {
+		params, err := FragilityCurveBreachParameters(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = bf.AmmendBreachParameters(result.Name, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dam, err := bf.FindBreachData("Dam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	damParams, err := dam.Parameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *damParams.Trigger != ras.BREACH_TRIGGER_DURATION || *damParams.FailureElevation != 680 || *damParams.ThresholdElevation != 640 || *damParams.ThresholdDuration != 8 {
+		t.Errorf("unexpected Dam trigger %+v", damParams)
+	}
+	saddle, err := bf.FindBreachData("Saddle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigger, err := saddle.Trigger()
+	if err != nil || trigger != ras.BREACH_TRIGGER_TIME {
+		t.Errorf("unexpected Saddle trigger %s %v", trigger, err)
+	}
+	if got, err := saddle.GetFloat(ras.BreachFailureTime); err != nil || got != 12.5 {
+		t.Errorf("unexpected Saddle failure time %f %v", got, err)
+	}
+	if got, err := saddle.GetFloat(ras.BreachFailureElevation); err != nil || got != 572 {
+		t.Errorf("a time trigger changed the Saddle failure elevation to %f %v", got, err)
+	}
+
+	if _, err := FragilityCurveBreachParameters(fragilitycurve.FragilityCurveLocationResult{Name: "Dam", Trigger: fragilitycurve.TRIGGER_TIME}); err == nil {
+		t.Error("expected an error for a time trigger without a failure time")
+	}
+}
+
+func TestUpdateBreachTriggers(t *testing.T) {
+	bf, err := ras.InitBFile(MULTI_BREACH_BFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structures := []ras.Structure{
+		{Index: 0, Type: "Connection", Connection: "Dam", SNetID: 3},
+		{Index: 1, Type: "Connection", Connection: "Saddle", SNetID: 6},
+		{Index: 2, Type: "Connection", Connection: "Levee", SNetID: 8},
+		{Index: 3, Type: "Connection", Connection: "Levee", SNetID: 9},
+		{Index: 4, Type: "Connection", Connection: "Culvert", SNetID: 10},
+	}
+	fcResult := fragilitycurve.ModelResult{Results: []fragilitycurve.FragilityCurveLocationResult{
+		{Name: "Dam", FailureElevation: 670},
+		{Name: "Levee", FailureElevation: 600},
+		{Name: "Spillway", FailureElevation: 650},
+		{Name: "Culvert", FailureElevation: 640},
+	}}
+	report := UpdateBreachTriggers(bf, ras.NewStructureResolver(structures), fcResult)
+	if len(report.Updated) != 1 || report.Updated[0] != (BreachUpdate{Location: "Dam", SNetID: 3, Trigger: fragilitycurve.TRIGGER_ELEVATION}) {
+		t.Errorf("unexpected updates %+v", report.Updated)
+	}
+	if !reflect.DeepEqual(report.NotFound, []string{"Spillway"}) {
+		t.Errorf("unexpected locations not found %v", report.NotFound)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Location != "Culvert" {
+		t.Errorf("expected the culvert without breach data to fail, got %+v", report.Failed)
+	}
+	if !reflect.DeepEqual(report.NotUpdated, []string{"Saddle"}) {
+		t.Errorf("unexpected breach structures not updated %v", report.NotUpdated)
+	}
+	if !reflect.DeepEqual(report.DuplicateNames, []string{"Levee"}) {
+		t.Errorf("unexpected duplicate names %v", report.DuplicateNames)
+	}
+	if report.Err() == nil {
+		t.Error("expected the report to have reconciliation errors")
+	}
+	dam, err := bf.FindBreachDataBySNetID(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := dam.GetFloat(ras.BreachFailureElevation); err != nil || got != 670 {
+		t.Errorf("unexpected Dam failure elevation %f %v", got, err)
+	}
+
+	fcResult.Results = []fragilitycurve.FragilityCurveLocationResult{{Name: "Dam", FailureElevation: 671}, {Name: "Saddle", FailureElevation: 571}}
+	report = UpdateBreachTriggers(bf, ras.NewStructureResolver(structures[:2]), fcResult)
+	if err := report.Err(); err != nil || len(report.Updated) != 2 {
+		t.Errorf("expected every result to reconcile: %v %+v", err, report)
+	}
+}
+
+func TestUpdateBreachTriggersGoverningMode(t *testing.T) {
+	bf, err := ras.InitBFile(MULTI_BREACH_BFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structures := []ras.Structure{
+		{Index: 0, Type: "Connection", Connection: "Dam", SNetID: 3},
+		{Index: 1, Type: "Connection", Connection: "Saddle", SNetID: 6},
+	}
+	failureTime := 12.0
+	fcResult := fragilitycurve.ModelResult{Results: []fragilitycurve.FragilityCurveLocationResult{
+		{Name: "Dam", FailureMode: fragilitycurve.PIPING, FailureElevation: 670},
+		{Name: "Dam", FailureMode: fragilitycurve.SEISMIC, Trigger: fragilitycurve.TRIGGER_TIME, FailureTime: &failureTime},
+		{Name: "Saddle", FailureElevation: 571},
+	}}
+	report := UpdateBreachTriggers(bf, ras.NewStructureResolver(structures), fcResult)
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []BreachUpdate{
+		{Location: "Dam", SNetID: 3, FailureMode: fragilitycurve.SEISMIC, Trigger: fragilitycurve.TRIGGER_TIME},
+		{Location: "Saddle", SNetID: 6, Trigger: fragilitycurve.TRIGGER_ELEVATION},
+	}
+	if !reflect.DeepEqual(report.Updated, want) {
+		t.Errorf("unexpected updates %+v", report.Updated)
+	}
+	if len(report.Superseded) != 1 || report.Superseded[0].FailureMode != fragilitycurve.PIPING {
+		t.Errorf("expected the elevation mode to be superseded, got %+v", report.Superseded)
+	}
+	dam, err := bf.FindBreachDataBySNetID(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trigger, err := dam.Trigger(); err != nil || trigger != ras.BREACH_TRIGGER_TIME {
+		t.Errorf("expected a time trigger, got %s %v", trigger, err)
+	}
+	if got, err := dam.GetFloat(ras.BreachFailureTime); err != nil || got != 12 {
+		t.Errorf("unexpected Dam failure time %f %v", got, err)
+	}
+
+	//two results with the same trigger for a structure are duplicates
+	fcResult.Results = append(fcResult.Results, fragilitycurve.FragilityCurveLocationResult{Name: "Saddle", FailureMode: fragilitycurve.PIPING, FailureElevation: 570})
+	report = UpdateBreachTriggers(bf, ras.NewStructureResolver(structures), fcResult)
+	if !reflect.DeepEqual(report.DuplicateNames, []string{"Saddle"}) {
+		t.Errorf("unexpected duplicate names %v", report.DuplicateNames)
+	}
+}
+
+func TestUpdateBreachTriggersByIdentifier(t *testing.T) {
+	bf, err := ras.InitBFile(MULTI_BREACH_BFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structures := []ras.Structure{
+		{Index: 0, Type: "Connection", Connection: "Dam", SNetID: 3},
+		{Index: 1, Type: "Inline", River: "Bald Eagle", Reach: "Lock Haven", RS: "81500", SNetID: 6},
+	}
+	damID, saddleID := 3, 6
+	fcResult := fragilitycurve.ModelResult{Results: []fragilitycurve.FragilityCurveLocationResult{
+		{SNetID: &damID, FailureElevation: 670},
+		{River: "Bald Eagle", Reach: "Lock Haven", RS: "81500", FailureElevation: 570},
+	}}
+	report := UpdateBreachTriggers(bf, ras.NewStructureResolver(structures), fcResult)
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, block := range bf.BfileBlocks {
+		if bd, ok := block.(*ras.BreachData); ok {
+			names = append(names, bd.Name)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"Dam", "Bald Eagle Lock Haven 81500"}) {
+		t.Errorf("unexpected breach data names %q", names)
+	}
+	saddle, err := bf.FindBreachDataBySNetID(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := saddle.GetFloat(ras.BreachFailureElevation); err != nil || got != 570 {
+		t.Errorf("unexpected failure elevation %f %v", got, err)
+	}
+
+	//without a geometry only SNET IDs resolve
+	fcResult.Results = []fragilitycurve.FragilityCurveLocationResult{
+		{SNetID: &saddleID, FailureElevation: 571},
+		{Name: "Dam", FailureElevation: 671},
+	}
+	report = UpdateBreachTriggers(bf, ras.NewStructureResolver(nil), fcResult)
+	if len(report.Updated) != 1 || report.Updated[0].SNetID != 6 || !reflect.DeepEqual(report.NotFound, []string{"Dam"}) {
+		t.Errorf("unexpected geometry free report %+v", report)
+	}
+}
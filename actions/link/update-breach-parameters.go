@@ -0,0 +1,125 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"ras-runner/actions"
+	"ras-runner/ras"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+)
+
+func init() {
+	cc.ActionRegistry.RegisterAction("update-breach-parameters", &UpdateBreachParametersAction{})
+}
+
+// BreachParameterResult is the set of breach parameters for a structure.  The structure is identified by its
+// connection name, SNET ID or river, reach and RS.
+type BreachParameterResult struct {
+	Name       string               `json:"location,omitempty"`
+	SNetID     *int                 `json:"snet_id,omitempty"`
+	River      string               `json:"river,omitempty"`
+	Reach      string               `json:"reach,omitempty"`
+	RS         string               `json:"rs,omitempty"`
+	Parameters ras.BreachParameters `json:"parameters"`
+}
+
+// StructureIdentifier identifies the structure of the breach parameters, the location is the connection name
+func (r BreachParameterResult) StructureIdentifier() ras.StructureIdentifier {
+	return ras.StructureIdentifier{
+		SNetID:     r.SNetID,
+		River:      r.River,
+		Reach:      r.Reach,
+		RS:         r.RS,
+		Connection: r.Name,
+	}
+}
+
+// BreachParameterResults is the breach parameter file read by the update-breach-parameters action
+type BreachParameterResults struct {
+	Results []BreachParameterResult `json:"results"`
+}
+
+// UpdateBreachParametersAction updates breach parameters in a bfile from a breach parameter file.  Structures are
+// resolved from the geometry hdf or a structure catalog, when there is one, the same way update-breach-bfile resolves
+// fragility curve results.  Only the parameters included for a structure are changed.
+type UpdateBreachParametersAction struct {
+	cc.ActionRunnerBase
+	ModelDir string
+}
+
+func (a *UpdateBreachParametersAction) Run() error {
+	// Assumes bFile, geometry and parameter files were copied local with the CopyLocal a.Action.
+	log.Printf("Ready to update breach parameters.")
+	if a.ModelDir == "" {
+		a.ModelDir = actions.MODEL_DIR
+	}
+
+	bFileName, err := a.Action.Attributes.GetString("bFile")
+	if err != nil {
+		return fmt.Errorf("action attributes do not include a bFile")
+	}
+	bfilePath := fmt.Sprintf("%v/%v", a.ModelDir, bFileName)
+	if !actions.FileExists(bfilePath) {
+		return fmt.Errorf("input source %s, was not found in local directory. Run copy-local first", bfilePath)
+	}
+	bf, err := ras.InitBFile(bfilePath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize and read the b-file: %s", err)
+	}
+
+	structures, err := readStructures(a.Action.Attributes, a.ModelDir)
+	if err != nil {
+		return err
+	}
+	resolver := ras.NewStructureResolver(structures)
+
+	paramFileName, err := a.Action.Attributes.GetString("paramFile")
+	if err != nil {
+		return fmt.Errorf("action attributes do not include a paramFile")
+	}
+	paramBytes, err := os.ReadFile(fmt.Sprintf("%v/%v", a.ModelDir, paramFileName))
+	if err != nil {
+		return fmt.Errorf("unable to read the paramFile: %s", err)
+	}
+	var params BreachParameterResults
+	err = json.Unmarshal(paramBytes, &params)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling breach parameters from %s: %s", paramFileName, err)
+	}
+
+	err = UpdateBreachParameters(bf, resolver, params)
+	if err != nil {
+		return err
+	}
+
+	err = bf.WriteFile(bfilePath)
+	if err != nil {
+		return fmt.Errorf("error writing b file: %s", err)
+	}
+	return nil
+}
+
+// UpdateBreachParameters applies the parameters of each structure to the bfile, resolving the structure of each
+// result by its connection name, SNET ID or river, reach and RS.  The update stops at the first structure that can not
+// be updated.
+func UpdateBreachParameters(bf *ras.Bfile, resolver *ras.StructureResolver, params BreachParameterResults) error {
+	bf.NameBreachData(resolver)
+	for _, result := range params.Results {
+		id := result.StructureIdentifier()
+		structure, err := resolver.Resolve(id)
+		if err != nil {
+			return fmt.Errorf("failed to update breach parameters: %s", err)
+		}
+		bd, err := bf.FindBreachDataBySNetID(structure.SNetID)
+		if err == nil {
+			err = bd.UpdateParameters(result.Parameters)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update breach parameters for %s: %s", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package actions
+
+import (
+	"encoding/json"
+	"ras-runner/ras"
+	"testing"
+)
+
+const MULTI_BREACH_BFILE string = "/workspaces/cc-ras-runner/testData/multiDamBreach.b01"
+
+func TestUpdateBreachParameters(t *testing.T) {
+	paramFile := `{"results":[
+		{"location":"Dam","parameters":{"bottom_width":250,"formation_time":1.5,"progression_curve":[{"x":0,"y":0},{"x":1,"y":1}]}},
+		{"snet_id":6,"parameters":{"weir_coefficient":3}}
+	]}`
+	var params BreachParameterResults
+	err := json.Unmarshal([]byte(paramFile), &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf, err := ras.InitBFile(MULTI_BREACH_BFILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structures := []ras.Structure{
+		{Index: 0, Type: "Connection", Connection: "Dam", SNetID: 3},
+		{Index: 1, Type: "Connection", Connection: "Saddle", SNetID: 6},
+	}
+	resolver := ras.NewStructureResolver(structures)
+	err = UpdateBreachParameters(bf, resolver, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dam, err := bf.FindBreachDataBySNetID(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	damParams, err := dam.Parameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *damParams.BottomWidth != 250 || *damParams.FormationTime != 1.5 || len(damParams.ProgressionCurve) != 2 {
+		t.Errorf("unexpected Dam parameters %+v", damParams)
+	}
+	saddle, err := bf.FindBreachDataBySNetID(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coef, err := saddle.GetFloat(ras.BreachWeirCoefficient)
+	if err != nil || coef != 3 {
+		t.Errorf("unexpected Saddle weir coefficient %f %v", coef, err)
+	}
+
+	if saddle.Name != "Saddle" {
+		t.Errorf("expected the breach data to be named from the structures, got %q", saddle.Name)
+	}
+
+	//without structures only SNET IDs resolve
+	snetOnly := BreachParameterResults{Results: []BreachParameterResult{{SNetID: params.Results[1].SNetID, Parameters: params.Results[1].Parameters}}}
+	if err = UpdateBreachParameters(bf, ras.NewStructureResolver(nil), snetOnly); err != nil {
+		t.Errorf("expected an SNET ID to resolve without structures: %v", err)
+	}
+	if err = UpdateBreachParameters(bf, ras.NewStructureResolver(nil), params); err == nil {
+		t.Error("expected an error for a name without structures")
+	}
+
+	params.Results = append(params.Results, BreachParameterResult{Name: "Spillway"})
+	if err = UpdateBreachParameters(bf, resolver, params); err == nil {
+		t.Error("expected an error for an unknown structure")
+	}
+}
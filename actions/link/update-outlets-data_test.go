@@ -2,10 +2,15 @@ package actions
 
 import (
 	"fmt"
+	"math"
+	"os"
 	"path/filepath"
+	"ras-runner/ras"
+	"strings"
 	"testing"
 
 	"github.com/usace-cloud-compute/cc-go-sdk"
+	"github.com/usace-cloud-compute/go-hdf5"
 )
 
 const ELKATSUTTON_HDF string = "/ElkRiver_at_Sutton.p01.tmp.hdf"
@@ -44,3 +49,166 @@ func TestOutletTSAction(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestUpdateOutletTSFlows(t *testing.T) {
+	outletTS := &ras.OutletTS{
+		RowCount:   3,
+		TimeSeries: []ras.FlowData{{Index: 0, Flow: 0}, {Index: 1, Flow: 0}, {Index: 2, Flow: 0}},
+		ExtraLines: []string{ras.EndOfFlow},
+	}
+	// half hourly source over the two hours of the b-file index
+	times := []float32{0, 0.5, 1, 1.5, 2}
+	flows := []float32{0, 5, 10, 15, 20}
+	err := UpdateOutletTSFlows(outletTS, times, flows, OUTLET_TS_RESAMPLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx, want := range []float32{0, 10, 20} {
+		if math.Abs(float64(outletTS.TimeSeries[idx].Flow-want)) > 0.001 {
+			t.Errorf("resampled flow %d = %v; want %v", idx, outletTS.TimeSeries[idx].Flow, want)
+		}
+	}
+	err = UpdateOutletTSFlows(outletTS, times, flows, OUTLET_TS_REWRITE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outletTS.RowCount != 5 || len(outletTS.ExtraLines) != 1 {
+		t.Errorf("rewrite produced %d ordinates and extra lines %q", outletTS.RowCount, outletTS.ExtraLines)
+	}
+	err = UpdateOutletTSFlows(outletTS, nil, []float32{1, 2, 3, 4, 5}, OUTLET_TS_COPY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outletTS.TimeSeries[4] != (ras.FlowData{Index: 2, Flow: 5}) {
+		t.Errorf("copy changed the index or missed a flow: %+v", outletTS.TimeSeries[4])
+	}
+	if err := UpdateOutletTSFlows(outletTS, nil, flows[:2], OUTLET_TS_COPY); err == nil {
+		t.Error("expected an error copying flows of a different length")
+	}
+	if err := UpdateOutletTSFlows(outletTS, times, flows, "stretch"); err == nil {
+		t.Error("expected an error for an unsupported mode")
+	}
+	if hours, err := hoursPerTimeUnit("days"); err != nil || hours != 24 {
+		t.Errorf("hoursPerTimeUnit(days) = %v, %v; want 24", hours, err)
+	}
+	if _, err := hoursPerTimeUnit("weeks"); err == nil {
+		t.Error("expected an error for unsupported time units")
+	}
+}
+
+// writeOutletSource writes a source dataset of hourly times and flows of 1000 plus the hour to an hdf file
+func writeOutletSource(t *testing.T, path string, rows int) {
+	f, err := hdf5.CreateFile(path, hdf5.F_ACC_TRUNC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	space, err := hdf5.CreateSimpleDataspace([]uint{uint(rows), 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer space.Close()
+	ds, err := f.CreateDataset("flows", hdf5.T_NATIVE_FLOAT, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+	values := make([]float32, rows*2)
+	for row := range rows {
+		values[row*2] = float32(row)
+		values[row*2+1] = float32(1000 + row)
+	}
+	err = ds.Write(&values[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutletTSModes(t *testing.T) {
+	const outletTSName = "SA Conn: SuttonDam (Outlet TS: SuttonDam_OUT)"
+	run := func(t *testing.T, rows int, attrs map[string]any) (*ras.OutletTS, error) {
+		modelDir := t.TempDir()
+		bfile, err := os.ReadFile("../../testData/ElkRiver_at_Sutton.b01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(modelDir, "ElkRiver_at_Sutton.b01"), bfile, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeOutletSource(t, filepath.Join(modelDir, "source.hdf"), rows)
+		parameters := map[string]any{
+			"bFile":       "ElkRiver_at_Sutton.b01",
+			"outletTS":    outletTSName,
+			"hdfFile":     "source.hdf",
+			"hdfDataPath": "/flows",
+		}
+		for key, value := range attrs {
+			parameters[key] = value
+		}
+		if _, ok := attrs["hdfDataSource"]; ok {
+			delete(parameters, "hdfFile")
+		}
+		runner := UpdateOutletTSAction{
+			ActionRunnerBase: cc.ActionRunnerBase{
+				ActionName: "update-outlet-ts-bfile",
+				Action: cc.Action{IOManager: cc.IOManager{
+					Attributes: parameters,
+					Stores:     []cc.DataStore{{Name: "local"}},
+					Inputs: []cc.DataSource{{
+						Name:      "observed",
+						StoreName: "local",
+						Paths:     map[string]string{"flows": filepath.Join(modelDir, "source.hdf")},
+					}},
+				}},
+			},
+			ModelDir: modelDir,
+		}
+		if err := runner.Run(); err != nil {
+			return nil, err
+		}
+		bf, err := ras.InitBFile(filepath.Join(modelDir, "ElkRiver_at_Sutton.b01"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, outletTS, err := bf.FindOutletTS(outletTSName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return outletTS, nil
+	}
+
+	//without a mode, a source with the outlet row count is copied without reading its times
+	outletTS, err := run(t, 577, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outletTS.RowCount != 577 || outletTS.TimeSeries[10] != (ras.FlowData{Index: 10, Flow: 1010}) {
+		t.Errorf("unexpected copied outlet time series %d %+v", outletTS.RowCount, outletTS.TimeSeries[10])
+	}
+	if _, err = run(t, 300, nil); err == nil || !strings.Contains(err.Error(), "set the mode") {
+		t.Errorf("expected an error asking for a mode, got %v", err)
+	}
+	if _, err = run(t, 577, map[string]any{"mode": OUTLET_TS_RESAMPLE}); err == nil || !strings.Contains(err.Error(), "hdfTimeUnits") {
+		t.Errorf("expected an error asking for hdfTimeUnits, got %v", err)
+	}
+	outletTS, err = run(t, 600, map[string]any{"mode": OUTLET_TS_RESAMPLE, "hdfTimeUnits": "hours"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outletTS.RowCount != 577 || outletTS.TimeSeries[10].Flow != 1010 {
+		t.Errorf("unexpected resampled outlet time series %d %+v", outletTS.RowCount, outletTS.TimeSeries[10])
+	}
+
+	//the source can be read from an input data source like the other link actions
+	outletTS, err = run(t, 577, map[string]any{"hdfDataSource": "observed", "hdfPathKey": "flows", "hdf-access": "local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outletTS.TimeSeries[10].Flow != 1010 {
+		t.Errorf("unexpected outlet time series from a data source %+v", outletTS.TimeSeries[10])
+	}
+	if _, err = run(t, 577, map[string]any{"hdfDataSource": "missing"}); err == nil {
+		t.Error("expected an error for a missing input data source")
+	}
+}
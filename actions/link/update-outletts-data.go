@@ -3,7 +3,6 @@ package actions
 import (
 	"fmt"
 	"log"
-	"os"
 	"ras-runner/actions"
 	"ras-runner/ras"
 	"reflect"
@@ -18,13 +17,19 @@ func init() {
 	cc.ActionRegistry.RegisterAction("update-outlet-ts-bfile", &UpdateOutletTSAction{})
 }
 
+const (
+	OUTLET_TS_COPY     string = "copy"
+	OUTLET_TS_RESAMPLE string = "resample"
+	OUTLET_TS_REWRITE  string = "rewrite"
+)
+
 type UpdateOutletTSAction struct {
 	cc.ActionRunnerBase
 	ModelDir string
 }
 
 func (a *UpdateOutletTSAction) Run() error {
-	// Assumes bFile and hdf file  were copied local with the CopyLocal a.Action.
+	// Assumes the bFile was copied local with the CopyLocal a.Action.  The hdf source is read from its data source or a local hdfFile.
 	if a.ModelDir == "" {
 		a.ModelDir = actions.MODEL_DIR
 	}
@@ -47,66 +52,138 @@ func (a *UpdateOutletTSAction) Run() error {
 	if err != nil {
 		return fmt.Errorf("unable to initialize the bfile: %s", err)
 	}
-	outletTSIdx := 0
-	var outletTS *ras.OutletTS
-	for idx, block := range bf.BfileBlocks {
-		outts, ok := block.(*ras.OutletTS)
-		if ok {
-			if strings.Contains(outts.Name, outletTSName) {
-				outletTSIdx = idx //will never be zero, that is the ras version block.
-				outletTS = outts
-				break
-			}
-		}
-	}
-
-	if outletTSIdx == 0 {
-		return fmt.Errorf("could not find the outlet TS named %s", outletTSName)
+	outletTSIdx, outletTS, err := bf.FindOutletTS(outletTSName)
+	if err != nil {
+		return err
 	}
 	hdfDataPath, err := a.Action.Attributes.GetString("hdfDataPath")
 	if err != nil {
 		return fmt.Errorf("action attributes do not include a hdfDataPath")
 	}
 
-	hdfFileName, err := a.Action.Attributes.GetString("hdfFile")
-	if err != nil {
-		return fmt.Errorf("action attributes do not include a hdfFile")
-	}
-
-	hdfFilePath := fmt.Sprintf("%v/%v", a.ModelDir, hdfFileName)
-	destfile, err := hdf5.OpenFile(hdfFilePath, hdf5.F_ACC_RDWR) //@TODO ..not closing..
+	srcfile, err := a.openSource()
 	if err != nil {
-		return fmt.Errorf("unable to open the hdf destination file: %s", err)
+		return err
 	}
-	defer destfile.Close()
+	defer srcfile.Close()
 
 	options := util.HdfReadOptions{
 		Dtype:        reflect.Float32,
-		File:         destfile,
+		File:         srcfile,
 		ReadOnCreate: true,
 	}
-	destVals, err := util.NewHdfDataset(hdfDataPath, options)
+	srcVals, err := util.NewHdfDataset(hdfDataPath, options)
 	if err != nil {
-		return fmt.Errorf("unable to get the desitional dataset values from hdf: %s", err)
+		return fmt.Errorf("unable to get the source dataset values from hdf: %s", err)
 	}
-	defer destVals.Close()
+	defer srcVals.Close()
 
-	flows := make([]float32, outletTS.RowCount)
-	err = destVals.ReadColumn(1, &flows)
+	mode, err := a.Action.Attributes.GetString("mode")
+	if err != nil {
+		//without a mode, flows that line up with the outlet time series are copied as they were before modes
+		if srcVals.Rows() != outletTS.RowCount {
+			return fmt.Errorf("source has %d flows and outlet time series %s has %d, set the mode to %s or %s", srcVals.Rows(), outletTSName, outletTS.RowCount, OUTLET_TS_RESAMPLE, OUTLET_TS_REWRITE)
+		}
+		mode = OUTLET_TS_COPY
+	}
+	flows := make([]float32, srcVals.Rows())
+	err = srcVals.ReadColumn(1, &flows)
 	if err != nil {
 		return fmt.Errorf("failed to read flows column from outletTS hdf: %s", err)
 	}
+	var times []float32
+	if mode != OUTLET_TS_COPY {
+		//source times are only used to resample or rewrite, and their units are never assumed
+		units, err := a.Action.Attributes.GetString("hdfTimeUnits")
+		if err != nil {
+			return fmt.Errorf("the %s mode requires hdfTimeUnits", mode)
+		}
+		hoursPerUnit, err := hoursPerTimeUnit(units)
+		if err != nil {
+			return err
+		}
+		times = make([]float32, srcVals.Rows())
+		err = srcVals.ReadColumn(0, &times)
+		if err != nil {
+			return fmt.Errorf("failed to read time column from outletTS hdf: %s", err)
+		}
+		for idx := range times {
+			times[idx] *= hoursPerUnit
+		}
+	}
 
-	err = outletTS.UpdateFloatArray(flows)
+	err = UpdateOutletTSFlows(outletTS, times, flows, mode)
 	if err != nil {
-		return fmt.Errorf("failed to update the outlet float array: %s", err)
+		return fmt.Errorf("failed to update the outlet time series: %s", err)
 	}
 
 	bf.BfileBlocks[outletTSIdx] = outletTS
-	resultBytes, err := bf.Write()
+	err = bf.WriteFile(bfilePath)
+	if err != nil {
+		return fmt.Errorf("failed to write bfile: %s", err)
+	}
+	return nil
+}
+
+// openSource opens the source hdf file named by the hdfDataSource attribute, which may be local or remote, like the
+// sources of the other link actions.  Without a hdfDataSource the hdfFile copied into the model directory is opened.
+func (a *UpdateOutletTSAction) openSource() (*hdf5.File, error) {
+	hdfDataSource, err := a.Action.Attributes.GetString("hdfDataSource")
+	if err != nil {
+		hdfFileName, err := a.Action.Attributes.GetString("hdfFile")
+		if err != nil {
+			return nil, fmt.Errorf("action attributes do not include a hdfDataSource or hdfFile")
+		}
+		srcfile, err := hdf5.OpenFile(fmt.Sprintf("%v/%v", a.ModelDir, hdfFileName), hdf5.F_ACC_RDONLY)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open the hdf source file: %s", err)
+		}
+		return srcfile, nil
+	}
+	srcfile, err := actions.OpenHdfSource(a.Action, actions.HdfSourceInput{
+		DataSourceName: hdfDataSource,
+		PathKey:        a.Action.Attributes.GetStringOrDefault("hdfPathKey", actions.HDF_PATHKEY),
+		Access:         actions.HdfAccessFromAttributes(a.Action.Attributes),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to write bfile to a byte array: %s", err)
+		return nil, fmt.Errorf("unable to open input source %s: %s", hdfDataSource, err)
+	}
+	return srcfile, nil
+}
+
+// UpdateOutletTSFlows applies a source flow series to an outlet time series.  times are in hours, the units of the
+// b-file index ordinates.  In copy mode the flows replace the flows of the existing index ordinates and times are not
+// used, in resample mode the flows are interpolated onto the existing index ordinates, and in rewrite mode the block
+// is replaced with the source ordinates.
+func UpdateOutletTSFlows(outletTS *ras.OutletTS, times []float32, flows []float32, mode string) error {
+	switch mode {
+	case OUTLET_TS_COPY:
+		return outletTS.UpdateFloatArray(flows)
+	case OUTLET_TS_RESAMPLE:
+		srcTimes := make([]float64, len(times))
+		srcFlows := make([]float64, len(flows))
+		for idx := range times {
+			srcTimes[idx] = float64(times[idx])
+		}
+		for idx := range flows {
+			srcFlows[idx] = float64(flows[idx])
+		}
+		return outletTS.ResampleFlows(srcTimes, srcFlows)
+	case OUTLET_TS_REWRITE:
+		return outletTS.UpdateTimeSeries(times, flows)
+	default:
+		return fmt.Errorf("unsupported outlet ts mode %s", mode)
 	}
+}
 
-	return os.WriteFile(bfilePath, resultBytes, 0600)
+// hoursPerTimeUnit returns the multiplier converting source times to b-file hours
+func hoursPerTimeUnit(units string) (float32, error) {
+	switch strings.ToLower(units) {
+	case "days":
+		return 24, nil
+	case "hours":
+		return 1, nil
+	default:
+		return 0, fmt.Errorf("unsupported hdfTimeUnits %s", units)
+	}
 }
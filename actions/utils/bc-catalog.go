@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"ras-runner/actions"
+	"ras-runner/ras"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+)
+
+const (
+	bcCatalogPathKey string = "catalog"
+)
+
+func init() {
+	cc.ActionRegistry.RegisterAction("boundary-condition-catalog", &BcCatalogAction{})
+}
+
+// BcCatalogAction lists every boundary condition in a plan HDF file as JSON.
+// The catalog reports the type, location, dataset path, length and time range of each
+// boundary condition so link payloads can refer to boundary conditions by name.
+type BcCatalogAction struct {
+	cc.ActionRunnerBase
+	ModelDir string
+}
+
+func (a *BcCatalogAction) Run() error {
+	log.Printf("Ready to catalog boundary conditions for %s\n", a.Action.Description)
+	if a.ModelDir == "" {
+		a.ModelDir = actions.MODEL_DIR
+	}
+
+	planFileName, err := a.Action.Attributes.GetString("planHdfFile")
+	if err != nil {
+		return fmt.Errorf("action attributes do not include a planHdfFile")
+	}
+
+	planFilePath := fmt.Sprintf("%v/%v", a.ModelDir, planFileName)
+	if !actions.FileExists(planFilePath) {
+		return fmt.Errorf("input source %s, was not found in local directory. Run copy-inputs first", planFilePath)
+	}
+
+	catalog, err := ras.ReadBoundaryConditionCatalog(planFilePath)
+	if err != nil {
+		return fmt.Errorf("unable to read the boundary condition catalog: %s", err)
+	}
+
+	catalogBytes, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	outputDataSource, err := a.Action.Attributes.GetString("outputDataSource")
+	if err != nil {
+		//no output data source so the catalog is only logged
+		log.Println(string(catalogBytes))
+		return nil
+	}
+
+	_, err = a.Action.Put(cc.PutOpInput{
+		SrcReader: bytes.NewReader(catalogBytes),
+		DataSourceOpInput: cc.DataSourceOpInput{
+			DataSourceName: outputDataSource,
+			PathKey:        bcCatalogPathKey,
+		},
+	})
+	return err
+}
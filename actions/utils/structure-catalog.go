@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"ras-runner/actions"
+	"ras-runner/ras"
+
+	"github.com/usace-cloud-compute/cc-go-sdk"
+)
+
+const (
+	structureCatalogJsonPathKey string = "json"
+	structureCatalogCsvPathKey  string = "csv"
+)
+
+func init() {
+	cc.ActionRegistry.RegisterAction("structure-catalog", &StructureCatalogAction{})
+}
+
+// StructureCatalogAction lists every structure in a geometry HDF file with its attributes as JSON and CSV.
+// When a b-file is provided each structure reports whether the b-file has breach data for it.
+type StructureCatalogAction struct {
+	cc.ActionRunnerBase
+	ModelDir string
+}
+
+func (a *StructureCatalogAction) Run() error {
+	log.Printf("Ready to catalog structures for %s\n", a.Action.Description)
+	if a.ModelDir == "" {
+		a.ModelDir = actions.MODEL_DIR
+	}
+
+	geoFileName, err := a.Action.Attributes.GetString("geoHdfFile")
+	if err != nil {
+		return fmt.Errorf("action attributes do not include a geoHdfFile")
+	}
+	geoFilePath := fmt.Sprintf("%v/%v", a.ModelDir, geoFileName)
+	if !actions.FileExists(geoFilePath) {
+		return fmt.Errorf("input source %s, was not found in local directory. Run copy-inputs first", geoFilePath)
+	}
+
+	structures, err := ras.ReadStructureCatalog(geoFilePath)
+	if err != nil {
+		return fmt.Errorf("unable to read the structure catalog: %s", err)
+	}
+
+	var bf *ras.Bfile
+	bFileName := a.Action.Attributes.GetStringOrDefault("bFile", "")
+	if bFileName != "" {
+		bfilePath := fmt.Sprintf("%v/%v", a.ModelDir, bFileName)
+		if !actions.FileExists(bfilePath) {
+			return fmt.Errorf("input source %s, was not found in local directory. Run copy-inputs first", bfilePath)
+		}
+		bf, err = ras.InitBFile(bfilePath)
+		if err != nil {
+			return fmt.Errorf("unable to read the b-file: %s", err)
+		}
+	}
+	catalog := ras.NewStructureCatalog(structures, bf)
+
+	catalogBytes, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	outputDataSource, err := a.Action.Attributes.GetString("outputDataSource")
+	if err != nil {
+		//no output data source so the catalog is only logged
+		log.Println(string(catalogBytes))
+		return nil
+	}
+	ds, err := a.Action.GetOutputDataSource(outputDataSource)
+	if err != nil {
+		return fmt.Errorf("error getting output data source %s: %s", outputDataSource, err)
+	}
+
+	var csvBytes bytes.Buffer
+	err = ras.WriteStructureCatalogCsv(&csvBytes, catalog)
+	if err != nil {
+		return fmt.Errorf("unable to write the structure catalog csv: %s", err)
+	}
+
+	outputs := map[string][]byte{
+		structureCatalogJsonPathKey: catalogBytes,
+		structureCatalogCsvPathKey:  csvBytes.Bytes(),
+	}
+	written := 0
+	for _, pathKey := range []string{structureCatalogJsonPathKey, structureCatalogCsvPathKey} {
+		if _, ok := ds.Paths[pathKey]; !ok {
+			continue
+		}
+		_, err = a.Action.Put(cc.PutOpInput{
+			SrcReader: bytes.NewReader(outputs[pathKey]),
+			DataSourceOpInput: cc.DataSourceOpInput{
+				DataSourceName: outputDataSource,
+				PathKey:        pathKey,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		written++
+	}
+	if written == 0 {
+		return errors.New("the structure catalog output data source must include a json or csv path")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"ras-runner/eventdb"
+	"ras-runner/ras"
+	"sort"
+)
+
+// cliCommand is a subcommand that can be run from the command line outside of a CC payload.
+type cliCommand struct {
+	usage string
+	run   func(args []string) error
+}
+
+const (
+	bcCatalogUsage    string = "bc-catalog <plan hdf file>: list the boundary conditions in a plan HDF file as JSON"
+	bfileDiffUsage    string = "bfile-diff <before b-file> <after b-file> [geometry hdf file]: report the block and cell differences between two b-files as JSON"
+	bfileDumpUsage    string = "bfile-dump <b-file> [geometry hdf file]: list the typed blocks of a b-file as JSON"
+	structureUsage    string = "structure-catalog <geometry hdf file> [b-file]: list the structures in a geometry HDF file as JSON"
+	eventDbMergeUsage string = "eventdb-merge <study database> <event database>...: merge event databases into a study database, replacing the rows of merged events"
+)
+
+var cliCommands map[string]cliCommand = map[string]cliCommand{
+	"bc-catalog":        {usage: bcCatalogUsage, run: bcCatalogCommand},
+	"bfile-diff":        {usage: bfileDiffUsage, run: bfileDiffCommand},
+	"bfile-dump":        {usage: bfileDumpUsage, run: bfileDumpCommand},
+	"structure-catalog": {usage: structureUsage, run: structureCatalogCommand},
+	"eventdb-merge":     {usage: eventDbMergeUsage, run: eventDbMergeCommand},
+}
+
+// runCli runs the subcommand named by the first argument.
+func runCli(args []string) error {
+	cmd, ok := cliCommands[args[0]]
+	if !ok {
+		printUsage()
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	return cmd.run(args[1:])
+}
+
+func printUsage() {
+	names := make([]string, 0, len(cliCommands))
+	for name := range cliCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "usage: ras-runner <command> [arguments]")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", cliCommands[name].usage)
+	}
+}
+
+func bcCatalogCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New(bcCatalogUsage)
+	}
+	catalog, err := ras.ReadBoundaryConditionCatalog(args[0])
+	if err != nil {
+		return err
+	}
+	return printJson(catalog)
+}
+
+func bfileDiffCommand(args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return errors.New(bfileDiffUsage)
+	}
+	before, err := readBfile(args[0], args[2:])
+	if err != nil {
+		return err
+	}
+	after, err := readBfile(args[1], args[2:])
+	if err != nil {
+		return err
+	}
+	diffs, err := ras.DiffBfiles(before, after)
+	if err != nil {
+		return err
+	}
+	return printJson(diffs)
+}
+
+func bfileDumpCommand(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New(bfileDumpUsage)
+	}
+	bf, err := readBfile(args[0], args[1:])
+	if err != nil {
+		return err
+	}
+	return printJson(bf.Summarize())
+}
+
+func structureCatalogCommand(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New(structureUsage)
+	}
+	structures, err := ras.ReadStructureCatalog(args[0])
+	if err != nil {
+		return err
+	}
+	var bf *ras.Bfile
+	if len(args) == 2 {
+		bf, err = readBfile(args[1], nil)
+		if err != nil {
+			return err
+		}
+	}
+	return printJson(ras.NewStructureCatalog(structures, bf))
+}
+
+// eventDbMergeCommand merges event databases into a study database and prints the merged event identifiers
+func eventDbMergeCommand(args []string) error {
+	if len(args) < 2 {
+		return errors.New(eventDbMergeUsage)
+	}
+	events, err := eventdb.Merge(args[0], args[1:]...)
+	if err != nil {
+		return err
+	}
+	return printJson(map[string]any{"merged_events": events})
+}
+
+// readBfile reads a b-file, naming the breach structures from the geometry hdf file when one is given
+func readBfile(bfilePath string, geoHdfPath []string) (*ras.Bfile, error) {
+	bf, err := ras.InitBFile(bfilePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %s", bfilePath, err)
+	}
+	if len(geoHdfPath) > 0 {
+		err = bf.SetSNetIDToNameFromGeoHDF(geoHdfPath[0])
+		if err != nil {
+			return nil, fmt.Errorf("unable to set the snetID from the geohdf: %s", err)
+		}
+	}
+	return bf, nil
+}
+
+func printJson(val any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(val)
+}
@@ -0,0 +1,306 @@
+// Package eventdb writes extraction results to an embedded sqlite database.  Each event writes its own database file
+// and the event files are merged into a study database with Merge.
+//
+// Every row is keyed by the event identifier, the block name and the dataset:
+//   - summaries:  a value per summary (e.g. max) and dataset column
+//   - timeseries: a value per time step and dataset column
+//   - records:    a value per named field of a record, such as a breach record
+//
+// Values are stored as sqlite REAL, INTEGER or TEXT values and NaN values are stored as NULL.  The value columns are
+// declared without a type so sqlite does not convert values: a text value "007" stays text and a float value of 680
+// stays REAL.  A block of an event is always written as a whole, replacing the rows the event wrote for the block
+// before, so writing an event again never duplicates rows.
+package eventdb
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+const DRIVER string = "sqlite"
+
+// the tables written for each event, all start with the event_id, block and dataset columns
+var tables []string = []string{"summaries", "timeseries", "records"}
+
+const schema string = `
+CREATE TABLE IF NOT EXISTS events (
+	event_id TEXT PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS summaries (
+	event_id     TEXT NOT NULL,
+	block        TEXT NOT NULL,
+	dataset      TEXT NOT NULL,
+	summary      TEXT NOT NULL,
+	column_index INTEGER NOT NULL,
+	column_name  TEXT NOT NULL,
+	value,
+	PRIMARY KEY (event_id, block, dataset, summary, column_index)
+) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS timeseries (
+	event_id     TEXT NOT NULL,
+	block        TEXT NOT NULL,
+	dataset      TEXT NOT NULL,
+	timestep     INTEGER NOT NULL,
+	column_index INTEGER NOT NULL,
+	column_name  TEXT NOT NULL,
+	value,
+	PRIMARY KEY (event_id, block, dataset, timestep, column_index)
+) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS records (
+	event_id TEXT NOT NULL,
+	block    TEXT NOT NULL,
+	dataset  TEXT NOT NULL,
+	field    TEXT NOT NULL,
+	value,
+	PRIMARY KEY (event_id, block, dataset, field)
+) WITHOUT ROWID;
+`
+
+// Dataset is the extracted values of a dataset.  Summaries and Data rows have a value per column.
+type Dataset struct {
+	Name      string
+	Columns   []string
+	Summaries map[string][]any
+	Data      [][]any
+}
+
+// Record is a set of named values, such as a breach record, stored under a dataset name
+type Record struct {
+	Dataset string
+	Fields  map[string]any
+}
+
+// EventDb is an open event or study database
+type EventDb struct {
+	db *sql.DB
+}
+
+// Open opens or creates a database and its tables
+func Open(path string) (*EventDb, error) {
+	db, err := sql.Open(DRIVER, path)
+	if err != nil {
+		return nil, err
+	}
+	//a single connection keeps attached databases and transactions on the same connection
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(schema)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to create the event database tables in %s: %s", path, err)
+	}
+	return &EventDb{db: db}, nil
+}
+
+func (edb *EventDb) Close() error {
+	return edb.db.Close()
+}
+
+// WriteBlock replaces the rows of a block of an event with the datasets and records
+func (edb *EventDb) WriteBlock(eventIdentifier string, block string, datasets []Dataset, records []Record) error {
+	tx, err := edb.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("INSERT OR IGNORE INTO events (event_id) VALUES (?)", eventIdentifier)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE event_id = ? AND block = ?", table), eventIdentifier, block)
+		if err != nil {
+			return err
+		}
+	}
+	for _, ds := range datasets {
+		err = writeDataset(tx, eventIdentifier, block, ds)
+		if err != nil {
+			return fmt.Errorf("unable to write dataset %s of block %s: %s", ds.Name, block, err)
+		}
+	}
+	recordStmt, err := tx.Prepare("INSERT OR REPLACE INTO records VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer recordStmt.Close()
+	for _, rec := range records {
+		for field, val := range rec.Fields {
+			_, err = recordStmt.Exec(eventIdentifier, block, rec.Dataset, field, dbValue(val))
+			if err != nil {
+				return fmt.Errorf("unable to write record %s of block %s: %s", rec.Dataset, block, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func writeDataset(tx *sql.Tx, eventIdentifier string, block string, ds Dataset) error {
+	summaryStmt, err := tx.Prepare("INSERT OR REPLACE INTO summaries VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer summaryStmt.Close()
+	for summary, vals := range ds.Summaries {
+		for idx, val := range vals {
+			_, err = summaryStmt.Exec(eventIdentifier, block, ds.Name, summary, idx, columnName(ds.Columns, idx), dbValue(val))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	dataStmt, err := tx.Prepare("INSERT OR REPLACE INTO timeseries VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer dataStmt.Close()
+	for step, vals := range ds.Data {
+		for idx, val := range vals {
+			_, err = dataStmt.Exec(eventIdentifier, block, ds.Name, step, idx, columnName(ds.Columns, idx), dbValue(val))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// columnName is the name of a column, or its index when the dataset does not name it
+func columnName(columns []string, idx int) string {
+	if idx < len(columns) {
+		return columns[idx]
+	}
+	return fmt.Sprint(idx)
+}
+
+// dbValue converts NaN to NULL and widens values to the sqlite storage types
+func dbValue(val any) any {
+	switch v := val.(type) {
+	case float32:
+		if math.IsNaN(float64(v)) {
+			return nil
+		}
+		return float64(v)
+	case float64:
+		if math.IsNaN(v) {
+			return nil
+		}
+		return v
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	}
+	return val
+}
+
+// Events lists the event identifiers in the database
+func (edb *EventDb) Events() ([]string, error) {
+	return queryEvents(edb.db, "main")
+}
+
+// queryEvents lists the event identifiers in the events table of a database attached to a connection or transaction
+func queryEvents(q interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, database string) ([]string, error) {
+	rows, err := q.Query(fmt.Sprintf("SELECT event_id FROM %s.events ORDER BY event_id", database))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []string{}
+	for rows.Next() {
+		var event string
+		err = rows.Scan(&event)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// Merge copies the events of each event database into the study database, replacing the rows the study database
+// already has for those events.  The study database is created if it does not exist.  Event databases must exist and
+// are only read.  The merged event identifiers are returned.
+func Merge(studyPath string, eventPaths ...string) ([]string, error) {
+	study, err := Open(studyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer study.Close()
+	merged := []string{}
+	for _, eventPath := range eventPaths {
+		events, err := study.merge(eventPath)
+		if err != nil {
+			return merged, fmt.Errorf("unable to merge %s: %s", eventPath, err)
+		}
+		merged = append(merged, events...)
+	}
+	return merged, nil
+}
+
+func (edb *EventDb) merge(eventPath string) ([]string, error) {
+	//attaching a missing file creates an empty database, so a mistyped path would merge nothing
+	info, err := os.Stat(eventPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", eventPath)
+	}
+	//the event database is attached read only, so its tables are read as they are and never created
+	uri, err := readOnlyURI(eventPath)
+	if err != nil {
+		return nil, err
+	}
+	_, err = edb.db.Exec("ATTACH DATABASE ? AS event", uri)
+	if err != nil {
+		return nil, err
+	}
+	defer edb.db.Exec("DETACH DATABASE event")
+
+	tx, err := edb.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	events, err := queryEvents(tx, "event")
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		_, err = tx.Exec(fmt.Sprintf("DELETE FROM main.%s WHERE event_id IN (SELECT event_id FROM event.events)", table))
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(fmt.Sprintf("INSERT INTO main.%s SELECT * FROM event.%s", table, table))
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = tx.Exec("INSERT OR IGNORE INTO main.events SELECT * FROM event.events")
+	if err != nil {
+		return nil, err
+	}
+	return events, tx.Commit()
+}
+
+// readOnlyURI is the sqlite URI filename that opens a database file read only
+func readOnlyURI(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	uri := url.URL{Scheme: "file", Path: filepath.ToSlash(abs), RawQuery: "mode=ro"}
+	return uri.String(), nil
+}
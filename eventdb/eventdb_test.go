@@ -0,0 +1,186 @@
+package eventdb
+
+import (
+	"database/sql"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func countRows(t *testing.T, edb *EventDb, query string, args ...any) int {
+	var count int
+	err := edb.db.QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func writeEvent(t *testing.T, path string, event string, peak float32) {
+	edb, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer edb.Close()
+	datasets := []Dataset{{
+		Name:      "Flow",
+		Columns:   []string{"upstream", "downstream"},
+		Summaries: map[string][]any{"max": {peak, float32(math.NaN())}},
+		Data:      [][]any{{float32(1), float32(2)}, {peak, float32(math.NaN())}},
+	}}
+	err = edb.WriteBlock(event, "refline-peaks", datasets, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []Record{{Dataset: "Dam", Fields: map[string]any{"Breached": true, "MaxFlow": float32(1200), "BreachIndex": 12}}}
+	err = edb.WriteBlock(event, "breach_records", nil, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.db")
+	writeEvent(t, path, "1", 100)
+	//writing the event again replaces its rows
+	writeEvent(t, path, "1", 150)
+
+	edb, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer edb.Close()
+	if n := countRows(t, edb, "SELECT COUNT(*) FROM timeseries"); n != 4 {
+		t.Errorf("expected 4 time series values, got %d", n)
+	}
+	var peak float64
+	err = edb.db.QueryRow("SELECT value FROM summaries WHERE event_id = '1' AND dataset = 'Flow' AND summary = 'max' AND column_name = 'upstream'").Scan(&peak)
+	if err != nil || peak != 150 {
+		t.Errorf("unexpected peak %f %v", peak, err)
+	}
+	if n := countRows(t, edb, "SELECT COUNT(*) FROM summaries WHERE value IS NULL"); n != 1 {
+		t.Errorf("expected NaN to be written as NULL, got %d NULL summaries", n)
+	}
+	if n := countRows(t, edb, "SELECT COUNT(*) FROM records WHERE block = 'breach_records' AND dataset = 'Dam'"); n != 3 {
+		t.Errorf("expected 3 breach record fields, got %d", n)
+	}
+	events, err := edb.Events()
+	if err != nil || !reflect.DeepEqual(events, []string{"1"}) {
+		t.Errorf("unexpected events %v %v", events, err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	event1 := filepath.Join(dir, "1.db")
+	event2 := filepath.Join(dir, "2.db")
+	writeEvent(t, event1, "1", 100)
+	writeEvent(t, event2, "2", 200)
+	study := filepath.Join(dir, "study.db")
+	merged, err := Merge(study, event1, event2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(merged, []string{"1", "2"}) {
+		t.Errorf("unexpected merged events %v", merged)
+	}
+	//merging a rerun event replaces its rows
+	writeEvent(t, event1, "1", 120)
+	_, err = Merge(study, event1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edb, err := Open(study)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer edb.Close()
+	if n := countRows(t, edb, "SELECT COUNT(*) FROM timeseries"); n != 8 {
+		t.Errorf("expected 8 time series values, got %d", n)
+	}
+	if n := countRows(t, edb, "SELECT COUNT(*) FROM records"); n != 6 {
+		t.Errorf("expected 6 record fields, got %d", n)
+	}
+	var peak float64
+	err = edb.db.QueryRow("SELECT value FROM summaries WHERE event_id = '1' AND summary = 'max' AND column_index = 0").Scan(&peak)
+	if err != nil || peak != 120 {
+		t.Errorf("unexpected merged peak %f %v", peak, err)
+	}
+	events, err := edb.Events()
+	if err != nil || !reflect.DeepEqual(events, []string{"1", "2"}) {
+		t.Errorf("unexpected study events %v %v", events, err)
+	}
+}
+
+func TestMergeInputs(t *testing.T) {
+	dir := t.TempDir()
+	study := filepath.Join(dir, "study.db")
+	missing := filepath.Join(dir, "missing.db")
+	if _, err := Merge(study, missing); err == nil {
+		t.Error("expected an error for a missing event database")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("expected the missing event database not to be created: %v", err)
+	}
+
+	//a database without event tables is an error and is not given the tables
+	empty := filepath.Join(dir, "empty.db")
+	db, err := sql.Open(DRIVER, empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err = db.Exec("CREATE TABLE other (id INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Merge(study, empty); err == nil {
+		t.Error("expected an error for a database without event tables")
+	}
+	var tableCount int
+	if err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&tableCount); err != nil || tableCount != 1 {
+		t.Errorf("expected the input database to be unchanged, found %d tables %v", tableCount, err)
+	}
+}
+
+func TestValueRoundTrip(t *testing.T) {
+	edb, err := Open(filepath.Join(t.TempDir(), "event.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer edb.Close()
+	datasets := []Dataset{{
+		Name:      "Stage",
+		Columns:   []string{"gage"},
+		Summaries: map[string][]any{"max": {float32(680)}},
+	}}
+	records := []Record{{Dataset: "Dam", Fields: map[string]any{"Id": "007", "MaxStage": float32(680)}}}
+	err = edb.WriteBlock("1", "stage", datasets, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//values keep their storage type, text is not converted to a number and integral floats stay REAL
+	tests := []struct {
+		query    string
+		wantType string
+		want     any
+	}{
+		{"SELECT typeof(value), value FROM records WHERE field = 'Id'", "text", "007"},
+		{"SELECT typeof(value), value FROM records WHERE field = 'MaxStage'", "real", float64(680)},
+		{"SELECT typeof(value), value FROM summaries WHERE dataset = 'Stage'", "real", float64(680)},
+	}
+	for _, test := range tests {
+		var valueType string
+		var value any
+		err = edb.db.QueryRow(test.query).Scan(&valueType, &value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if valueType != test.wantType || !reflect.DeepEqual(value, test.want) {
+			t.Errorf("%s: expected %s %v, got %s %v (%T)", test.query, test.wantType, test.want, valueType, value, value)
+		}
+	}
+}
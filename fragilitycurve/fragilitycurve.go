@@ -1,9 +1,368 @@
 package fragilitycurve
 
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// FragilityCurveLocationResult is the sampled failure of a structure.  Elevation triggers fail at FailureElevation.
+// Duration triggers fail once the water surface has been above ThresholdElevation for ThresholdDuration hours, or
+// immediately at FailureElevation.  Time triggers fail at FailureTime, in hours from the simulation start.  An empty
+// Trigger is an elevation trigger.  The structure is identified by its connection name in Name, its SNET ID or its
+// River, Reach and RS.
 type FragilityCurveLocationResult struct {
-	Name             string  `json:"location"`
-	FailureElevation float64 `json:"failure_elevation"`
+	Name               string   `json:"location"`
+	SNetID             *int     `json:"snet_id,omitempty"`
+	River              string   `json:"river,omitempty"`
+	Reach              string   `json:"reach,omitempty"`
+	RS                 string   `json:"rs,omitempty"`
+	FailureMode        string   `json:"failure_mode,omitempty"`
+	Trigger            string   `json:"trigger,omitempty"`
+	FailureElevation   float64  `json:"failure_elevation"`
+	FailureTime        *float64 `json:"failure_time,omitempty"`
+	ThresholdElevation *float64 `json:"threshold_elevation,omitempty"`
+	ThresholdDuration  *float64 `json:"threshold_duration,omitempty"`
 }
 type ModelResult struct {
 	Results []FragilityCurveLocationResult `json:"results"`
 }
+
+// fragility curve types
+const (
+	TABULAR   string = "tabular"
+	NORMAL    string = "normal"
+	LOGNORMAL string = "lognormal"
+)
+
+// breach triggers
+const (
+	TRIGGER_ELEVATION string = "elevation"
+	TRIGGER_DURATION  string = "duration"
+	TRIGGER_TIME      string = "time"
+)
+
+// common failure modes, other names may be used
+const (
+	OVERTOPPING string = "overtopping"
+	PIPING      string = "piping"
+	SEISMIC     string = "seismic"
+)
+
+// Ordinate is the cumulative probability of failure at an elevation
+type Ordinate struct {
+	Elevation   float64 `json:"elevation"`
+	Probability float64 `json:"probability"`
+}
+
+// FragilityCurve is the probability of failure of a structure as a function of elevation, or of time for time
+// triggered failure modes.
+//
+// Tabular curves are elevation-probability ordinates with non decreasing probabilities between 0 and 1.  Normal
+// curves have failure elevations with the Mean and StandardDeviation.  For lognormal curves Mean and
+// StandardDeviation are of the natural log of the failure elevation less Shift, so the failure elevation is
+// Shift + exp(Mean + StandardDeviation*z).  Sampled elevations are limited to LowerBound and UpperBound when set.
+type FragilityCurve struct {
+	Type              string     `json:"type"`
+	Ordinates         []Ordinate `json:"ordinates,omitempty"`
+	Mean              float64    `json:"mean,omitempty"`
+	StandardDeviation float64    `json:"standard_deviation,omitempty"`
+	Shift             float64    `json:"shift,omitempty"`
+	LowerBound        *float64   `json:"lower_bound,omitempty"`
+	UpperBound        *float64   `json:"upper_bound,omitempty"`
+}
+
+// FragilityCurveLocation is the fragility curve of a failure mode of a structure, named as in the geometry hdf.  The
+// curve is sampled for the failure elevation of elevation triggers, the threshold elevation of duration triggers and
+// the failure time of time triggers.  Duration triggers also require the ThresholdDuration and the FailureElevation
+// that fails the structure immediately.  The structure is identified by its connection name in Name, its SNET ID or
+// its River, Reach and RS.
+type FragilityCurveLocation struct {
+	Name              string         `json:"location"`
+	SNetID            *int           `json:"snet_id,omitempty"`
+	River             string         `json:"river,omitempty"`
+	Reach             string         `json:"reach,omitempty"`
+	RS                string         `json:"rs,omitempty"`
+	FailureMode       string         `json:"failure_mode,omitempty"`
+	Trigger           string         `json:"trigger,omitempty"`
+	Curve             FragilityCurve `json:"curve"`
+	ThresholdDuration *float64       `json:"threshold_duration,omitempty"`
+	FailureElevation  *float64       `json:"failure_elevation,omitempty"`
+}
+
+// ModelFragilityCurves are the fragility curves of the structures in a model
+type ModelFragilityCurves struct {
+	Locations []FragilityCurveLocation `json:"locations"`
+}
+
+func (fc FragilityCurve) Validate() error {
+	switch fc.Type {
+	case TABULAR:
+		if len(fc.Ordinates) < 2 {
+			return errors.New("tabular fragility curves require at least two ordinates")
+		}
+		for idx, ord := range fc.Ordinates {
+			if ord.Probability < 0 || ord.Probability > 1 || math.IsNaN(ord.Probability) {
+				return fmt.Errorf("ordinate %d probability %f is not between 0 and 1", idx, ord.Probability)
+			}
+			if idx > 0 {
+				prev := fc.Ordinates[idx-1]
+				if ord.Elevation <= prev.Elevation {
+					return fmt.Errorf("ordinate %d elevation %f does not increase", idx, ord.Elevation)
+				}
+				if ord.Probability < prev.Probability {
+					return fmt.Errorf("ordinate %d probability %f decreases", idx, ord.Probability)
+				}
+			}
+		}
+	case NORMAL, LOGNORMAL:
+		if fc.StandardDeviation <= 0 || math.IsNaN(fc.StandardDeviation) {
+			return fmt.Errorf("%s fragility curves require a positive standard deviation", fc.Type)
+		}
+	default:
+		return fmt.Errorf("unsupported fragility curve type %q", fc.Type)
+	}
+	if fc.LowerBound != nil && fc.UpperBound != nil && *fc.LowerBound > *fc.UpperBound {
+		return fmt.Errorf("lower bound %f is above the upper bound %f", *fc.LowerBound, *fc.UpperBound)
+	}
+	return nil
+}
+
+// Quantile is the inverse of the fragility curve, the elevation or time with probability p of failure.  p must be
+// between 0 and 1, exclusive.  Tabular curves return the end elevations for probabilities outside of the ordinates.
+func (fc FragilityCurve) Quantile(p float64) (float64, error) {
+	if p <= 0 || p >= 1 || math.IsNaN(p) {
+		return 0, fmt.Errorf("probability %f is not between 0 and 1", p)
+	}
+	err := fc.Validate()
+	if err != nil {
+		return 0, err
+	}
+	var elevation float64
+	switch fc.Type {
+	case TABULAR:
+		elevation = fc.tabularElevation(p)
+	case NORMAL:
+		elevation = fc.Mean + fc.StandardDeviation*standardNormalQuantile(p)
+	case LOGNORMAL:
+		elevation = fc.Shift + math.Exp(fc.Mean+fc.StandardDeviation*standardNormalQuantile(p))
+	}
+	if fc.LowerBound != nil {
+		elevation = math.Max(elevation, *fc.LowerBound)
+	}
+	if fc.UpperBound != nil {
+		elevation = math.Min(elevation, *fc.UpperBound)
+	}
+	return elevation, nil
+}
+
+func (fc FragilityCurve) tabularElevation(p float64) float64 {
+	ords := fc.Ordinates
+	i := sort.Search(len(ords), func(i int) bool { return ords[i].Probability >= p })
+	if i == 0 {
+		return ords[0].Elevation
+	}
+	if i == len(ords) {
+		return ords[len(ords)-1].Elevation
+	}
+	lo, hi := ords[i-1], ords[i]
+	weight := (p - lo.Probability) / (hi.Probability - lo.Probability)
+	return lo.Elevation + weight*(hi.Elevation-lo.Elevation)
+}
+
+func standardNormalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// Validate checks the curve and the trigger settings of the failure mode
+func (loc FragilityCurveLocation) Validate() error {
+	if loc.Label() == "" {
+		return errors.New("the location does not identify a structure")
+	}
+	switch loc.Trigger {
+	case "", TRIGGER_ELEVATION, TRIGGER_TIME:
+	case TRIGGER_DURATION:
+		if loc.ThresholdDuration == nil || *loc.ThresholdDuration <= 0 {
+			return errors.New("duration triggers require a positive threshold duration")
+		}
+		if loc.FailureElevation == nil {
+			return errors.New("duration triggers require a failure elevation")
+		}
+	default:
+		return fmt.Errorf("unsupported trigger %q", loc.Trigger)
+	}
+	return loc.Curve.Validate()
+}
+
+func (loc FragilityCurveLocation) trigger() string {
+	if loc.Trigger == "" {
+		return TRIGGER_ELEVATION
+	}
+	return loc.Trigger
+}
+
+// TriggerType is the trigger of the result, elevation when Trigger is empty
+func (r FragilityCurveLocationResult) TriggerType() string {
+	if r.Trigger == "" {
+		return TRIGGER_ELEVATION
+	}
+	return r.Trigger
+}
+
+// Label names the structure by each of its identifiers
+func (loc FragilityCurveLocation) Label() string {
+	return locationLabel(loc.Name, loc.SNetID, loc.River, loc.Reach, loc.RS)
+}
+
+// Label names the structure by each of its identifiers
+func (r FragilityCurveLocationResult) Label() string {
+	return locationLabel(r.Name, r.SNetID, r.River, r.Reach, r.RS)
+}
+
+func locationLabel(name string, snetID *int, river string, reach string, rs string) string {
+	parts := []string{}
+	if name != "" {
+		parts = append(parts, name)
+	}
+	if river != "" || reach != "" || rs != "" {
+		parts = append(parts, fmt.Sprintf("%s/%s/%s", river, reach, rs))
+	}
+	if snetID != nil {
+		parts = append(parts, fmt.Sprintf("SNET ID %d", *snetID))
+	}
+	return strings.Join(parts, " ")
+}
+
+// result is the failure of the mode for a sampled value of the curve
+func (loc FragilityCurveLocation) result(value float64) FragilityCurveLocationResult {
+	result := FragilityCurveLocationResult{
+		Name:        loc.Name,
+		SNetID:      loc.SNetID,
+		River:       loc.River,
+		Reach:       loc.Reach,
+		RS:          loc.RS,
+		FailureMode: loc.FailureMode,
+		Trigger:     loc.Trigger,
+	}
+	switch loc.Trigger {
+	case TRIGGER_DURATION:
+		result.FailureElevation = *loc.FailureElevation
+		result.ThresholdElevation = &value
+		result.ThresholdDuration = loc.ThresholdDuration
+	case TRIGGER_TIME:
+		result.FailureTime = &value
+	default:
+		result.FailureElevation = value
+	}
+	return result
+}
+
+// Sample draws a failure for each location.  Each failure mode of a location has its own random stream seeded from
+// the event identifier, the seed, the location identifiers and the failure mode, so an event always samples the same failures
+// and adding or removing a location or mode does not change the samples of the others.  The modes of a location that
+// share a trigger fail by the mode with the lowest sampled elevation or earliest time, so a location has one result for
+// each of its triggers.  GoverningResult combines the results of the triggers of a structure into the one breach
+// trigger of the b-file.  Results are in the order the locations and triggers are first listed.
+func (mfc ModelFragilityCurves) Sample(eventIdentifier string, seed uint64) (ModelResult, error) {
+	result := ModelResult{Results: []FragilityCurveLocationResult{}}
+	governing := map[[2]string]int{}
+	sampled := map[[2]string]float64{}
+	modes := map[[2]string]bool{}
+	for _, loc := range mfc.Locations {
+		label := loc.Label()
+		mode := [2]string{label, loc.FailureMode}
+		if modes[mode] {
+			return ModelResult{}, fmt.Errorf("location %s has more than one fragility curve for failure mode %q", label, loc.FailureMode)
+		}
+		modes[mode] = true
+		err := loc.Validate()
+		if err != nil {
+			return ModelResult{}, fmt.Errorf("invalid fragility curve for %s: %s", label, err)
+		}
+		stream := hashString(label)
+		if loc.FailureMode != "" {
+			stream = hashString(label + "\x00" + loc.FailureMode)
+		}
+		rng := rand.New(rand.NewPCG(hashString(eventIdentifier)^seed, stream))
+		p := rng.Float64()
+		for p == 0 {
+			p = rng.Float64()
+		}
+		value, err := loc.Curve.Quantile(p)
+		if err != nil {
+			return ModelResult{}, fmt.Errorf("invalid fragility curve for %s: %s", label, err)
+		}
+		trigger := [2]string{label, loc.trigger()}
+		idx, ok := governing[trigger]
+		if !ok {
+			governing[trigger] = len(result.Results)
+			sampled[trigger] = value
+			result.Results = append(result.Results, loc.result(value))
+			continue
+		}
+		if value < sampled[trigger] {
+			sampled[trigger] = value
+			result.Results[idx] = loc.result(value)
+		}
+	}
+	return result, nil
+}
+
+// GoverningResult combines the results of one structure, one for each trigger, into the single breach trigger a b-file
+// holds.  The precedence is:
+//   - time: a time trigger governs, since the failure time is sampled independently of the water surface and no other
+//     trigger can express it.
+//   - duration: otherwise a duration trigger governs.  An elevation result lowers its failure elevation, the elevation
+//     that fails the structure immediately, when the elevation result is lower, so both modes can fail the structure.
+//   - elevation: an elevation trigger governs when it is the only result.
+//
+// The failure modes of combined results are joined.  Returns an error if there are no results or two results share a
+// trigger.
+func GoverningResult(results []FragilityCurveLocationResult) (FragilityCurveLocationResult, error) {
+	byTrigger := map[string]FragilityCurveLocationResult{}
+	for _, r := range results {
+		if _, ok := byTrigger[r.TriggerType()]; ok {
+			return FragilityCurveLocationResult{}, fmt.Errorf("%s has more than one %s result", r.Label(), r.TriggerType())
+		}
+		byTrigger[r.TriggerType()] = r
+	}
+	if timeResult, ok := byTrigger[TRIGGER_TIME]; ok {
+		return timeResult, nil
+	}
+	duration, hasDuration := byTrigger[TRIGGER_DURATION]
+	elevation, hasElevation := byTrigger[TRIGGER_ELEVATION]
+	switch {
+	case hasDuration && hasElevation:
+		if elevation.FailureElevation < duration.FailureElevation {
+			duration.FailureElevation = elevation.FailureElevation
+			duration.FailureMode = joinModes(duration.FailureMode, elevation.FailureMode)
+		}
+		return duration, nil
+	case hasDuration:
+		return duration, nil
+	case hasElevation:
+		return elevation, nil
+	default:
+		return FragilityCurveLocationResult{}, errors.New("there are no results to combine")
+	}
+}
+
+func joinModes(modes ...string) string {
+	named := []string{}
+	for _, m := range modes {
+		if m != "" {
+			named = append(named, m)
+		}
+	}
+	return strings.Join(named, ",")
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
@@ -0,0 +1,231 @@
+package fragilitycurve
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestQuantile(t *testing.T) {
+	tabular := FragilityCurve{Type: TABULAR, Ordinates: []Ordinate{{600, 0}, {650, 0.5}, {700, 1}}}
+	lower := 640.0
+	cases := []struct {
+		name  string
+		curve FragilityCurve
+		p     float64
+		want  float64
+	}{
+		{"tabular median", tabular, 0.5, 650},
+		{"tabular interpolated", tabular, 0.25, 625},
+		{"tabular upper", tabular, 0.75, 675},
+		{"normal median", FragilityCurve{Type: NORMAL, Mean: 650, StandardDeviation: 10}, 0.5, 650},
+		{"normal one sigma", FragilityCurve{Type: NORMAL, Mean: 650, StandardDeviation: 10}, 0.8413447460685429, 660},
+		{"lognormal median", FragilityCurve{Type: LOGNORMAL, Mean: math.Log(20), StandardDeviation: 0.2, Shift: 600}, 0.5, 620},
+		{"bounded", FragilityCurve{Type: NORMAL, Mean: 650, StandardDeviation: 10, LowerBound: &lower}, 0.01, 640},
+	}
+	for _, c := range cases {
+		got, err := c.curve.Quantile(c.p)
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		if math.Abs(got-c.want) > 1e-6 {
+			t.Errorf("%s: Quantile(%f) = %f; want %f", c.name, c.p, got, c.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []FragilityCurve{
+		{Type: "weibull"},
+		{Type: TABULAR, Ordinates: []Ordinate{{600, 0}}},
+		{Type: TABULAR, Ordinates: []Ordinate{{600, 0}, {590, 1}}},
+		{Type: TABULAR, Ordinates: []Ordinate{{600, 0.5}, {650, 0.2}}},
+		{Type: TABULAR, Ordinates: []Ordinate{{600, 0}, {650, 1.5}}},
+		{Type: NORMAL, Mean: 650},
+		{Type: LOGNORMAL, Mean: 3, StandardDeviation: -1},
+	}
+	for _, curve := range invalid {
+		if err := curve.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", curve)
+		}
+	}
+	if _, err := (FragilityCurve{Type: NORMAL, Mean: 650, StandardDeviation: 10}).Quantile(1); err == nil {
+		t.Error("expected an error for a probability of 1")
+	}
+}
+
+func TestSample(t *testing.T) {
+	curvesJson := `{"locations":[
+		{"location":"Dam","curve":{"type":"normal","mean":676,"standard_deviation":3}},
+		{"location":"Saddle","curve":{"type":"tabular","ordinates":[{"elevation":670,"probability":0},{"elevation":680,"probability":1}]}}
+	]}`
+	var curves ModelFragilityCurves
+	err := json.Unmarshal([]byte(curvesJson), &curves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := curves.Sample("event-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := curves.Sample("event-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Results[0] != again.Results[0] || first.Results[1] != again.Results[1] {
+		t.Errorf("the same event sampled different elevations %v %v", first, again)
+	}
+	saddle := first.Results[1]
+	if saddle.Name != "Saddle" || saddle.FailureElevation < 670 || saddle.FailureElevation > 680 {
+		t.Errorf("unexpected saddle result %+v", saddle)
+	}
+
+	//samples for a location do not depend on the other locations
+	alone, err := ModelFragilityCurves{Locations: curves.Locations[1:]}.Sample("event-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alone.Results[0] != saddle {
+		t.Errorf("sampling depends on the other locations: %v %v", alone.Results[0], saddle)
+	}
+
+	other, err := curves.Sample("event-2", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeded, err := curves.Sample("event-1", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Results[0] == first.Results[0] || seeded.Results[0] == first.Results[0] {
+		t.Error("expected different events and seeds to sample different elevations")
+	}
+
+	curves.Locations = append(curves.Locations, curves.Locations[0])
+	if _, err := curves.Sample("event-1", 0); err == nil {
+		t.Error("expected an error for a repeated location")
+	}
+}
+
+// the sampled probabilities of many events should follow the curve
+func TestSampleDistribution(t *testing.T) {
+	curves := ModelFragilityCurves{Locations: []FragilityCurveLocation{
+		{Name: "Dam", Curve: FragilityCurve{Type: NORMAL, Mean: 676, StandardDeviation: 3}},
+	}}
+	below := 0
+	events := 4000
+	for event := 0; event < events; event++ {
+		result, err := curves.Sample(string(rune('a'+event%26))+string(rune(event)), 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Results[0].FailureElevation < 676 {
+			below++
+		}
+	}
+	if fraction := float64(below) / float64(events); math.Abs(fraction-0.5) > 0.05 {
+		t.Errorf("%f of the samples were below the mean", fraction)
+	}
+}
+
+func TestSampleFailureModes(t *testing.T) {
+	curvesJson := `{"locations":[
+		{"location":"Dam","failure_mode":"overtopping","curve":{"type":"normal","mean":680,"standard_deviation":1}},
+		{"location":"Dam","failure_mode":"piping","curve":{"type":"normal","mean":660,"standard_deviation":1}},
+		{"location":"Levee","failure_mode":"piping","trigger":"duration","threshold_duration":6,"failure_elevation":590,
+			"curve":{"type":"tabular","ordinates":[{"elevation":580,"probability":0},{"elevation":585,"probability":1}]}},
+		{"location":"Spillway","failure_mode":"seismic","trigger":"time",
+			"curve":{"type":"tabular","ordinates":[{"elevation":0,"probability":0},{"elevation":24,"probability":1}]}}
+	]}`
+	var curves ModelFragilityCurves
+	err := json.Unmarshal([]byte(curvesJson), &curves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := curves.Sample("event-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Results) != 3 {
+		t.Fatalf("expected a result for each location, got %+v", result.Results)
+	}
+	dam := result.Results[0]
+	if dam.Name != "Dam" || dam.FailureMode != PIPING || dam.FailureElevation > 670 {
+		t.Errorf("expected piping to govern the dam, got %+v", dam)
+	}
+	levee := result.Results[1]
+	if levee.TriggerType() != TRIGGER_DURATION || levee.FailureElevation != 590 || *levee.ThresholdDuration != 6 ||
+		*levee.ThresholdElevation < 580 || *levee.ThresholdElevation > 585 {
+		t.Errorf("unexpected levee result %+v", levee)
+	}
+	spillway := result.Results[2]
+	if spillway.TriggerType() != TRIGGER_TIME || spillway.FailureTime == nil || *spillway.FailureTime < 0 || *spillway.FailureTime > 24 {
+		t.Errorf("unexpected spillway result %+v", spillway)
+	}
+
+	invalid := ModelFragilityCurves{Locations: []FragilityCurveLocation{curves.Locations[2]}}
+	invalid.Locations[0].ThresholdDuration = nil
+	if _, err := invalid.Sample("event-1", 0); err == nil {
+		t.Error("expected an error for a duration trigger without a threshold duration")
+	}
+}
+
+func TestSampleMixedTriggers(t *testing.T) {
+	curvesJson := `{"locations":[
+		{"location":"Dam","failure_mode":"overtopping","curve":{"type":"normal","mean":680,"standard_deviation":1}},
+		{"location":"Dam","failure_mode":"piping","curve":{"type":"normal","mean":660,"standard_deviation":1}},
+		{"location":"Dam","failure_mode":"seismic","trigger":"time",
+			"curve":{"type":"tabular","ordinates":[{"elevation":0,"probability":0},{"elevation":24,"probability":1}]}}
+	]}`
+	var curves ModelFragilityCurves
+	err := json.Unmarshal([]byte(curvesJson), &curves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := curves.Sample("event-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Results) != 2 {
+		t.Fatalf("expected a result for each trigger, got %+v", result.Results)
+	}
+	elevation, seismic := result.Results[0], result.Results[1]
+	if elevation.TriggerType() != TRIGGER_ELEVATION || elevation.FailureMode != PIPING {
+		t.Errorf("expected piping to govern the elevation trigger, got %+v", elevation)
+	}
+	if seismic.TriggerType() != TRIGGER_TIME || seismic.FailureTime == nil {
+		t.Errorf("unexpected time result %+v", seismic)
+	}
+	governing, err := GoverningResult(result.Results)
+	if err != nil || governing.TriggerType() != TRIGGER_TIME || governing.FailureMode != SEISMIC {
+		t.Errorf("expected the time trigger to govern, got %+v %v", governing, err)
+	}
+}
+
+func TestGoverningResult(t *testing.T) {
+	threshold, hours := 585.0, 6.0
+	duration := FragilityCurveLocationResult{Name: "Levee", FailureMode: PIPING, Trigger: TRIGGER_DURATION,
+		FailureElevation: 590, ThresholdElevation: &threshold, ThresholdDuration: &hours}
+	elevation := FragilityCurveLocationResult{Name: "Levee", FailureMode: OVERTOPPING, FailureElevation: 588}
+
+	governing, err := GoverningResult([]FragilityCurveLocationResult{elevation, duration})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if governing.TriggerType() != TRIGGER_DURATION || governing.FailureElevation != 588 || *governing.ThresholdElevation != 585 ||
+		governing.FailureMode != "piping,overtopping" {
+		t.Errorf("expected the lower elevation to fail the duration trigger immediately, got %+v", governing)
+	}
+	elevation.FailureElevation = 595
+	governing, err = GoverningResult([]FragilityCurveLocationResult{elevation, duration})
+	if err != nil || governing.FailureElevation != 590 || governing.FailureMode != PIPING {
+		t.Errorf("expected the duration trigger unchanged, got %+v %v", governing, err)
+	}
+	if _, err = GoverningResult([]FragilityCurveLocationResult{elevation, elevation}); err == nil {
+		t.Error("expected an error for two results with the same trigger")
+	}
+	if _, err = GoverningResult(nil); err == nil {
+		t.Error("expected an error without results")
+	}
+}
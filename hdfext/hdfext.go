@@ -0,0 +1,168 @@
+// Package hdfext binds the HDF5 and HDF5 high level C functions used by the ras runner that go-hdf5 does not wrap.
+// Functions take the ids of open go-hdf5 objects, e.g. dataset.ID(), and do not close them.
+package hdfext
+
+// #cgo LDFLAGS: -lhdf5 -lhdf5_hl
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #cgo darwin LDFLAGS: -L/usr/local/lib
+// #cgo linux,!arm64 CFLAGS: -I/usr/local/include -I/usr/lib/x86_64-linux-gnu/hdf5/serial/include
+// #cgo linux,!arm64 LDFLAGS: -L/usr/local/lib -L/usr/lib/x86_64-linux-gnu/hdf5/serial/
+// #cgo linux,arm64 CFLAGS: -I/usr/local/include -I/usr/lib/aarch64-linux-gnu/hdf5/serial/include
+// #cgo linux,arm64 LDFLAGS: -L/usr/local/lib -L/usr/lib/aarch64-linux-gnu/hdf5/serial/
+// #include <stdlib.h>
+// #include "hdf5.h"
+// #include "hdf5_hl.h"
+//
+// static herr_t count_attribute(hid_t loc, const char *name, const H5A_info_t *info, void *count) {
+//     (*(int *)count)++;
+//     return 0;
+// }
+//
+// static int attribute_count(hid_t obj) {
+//     int count = 0;
+//     hsize_t idx = 0;
+//     if (H5Aiterate2(obj, H5_INDEX_NAME, H5_ITER_NATIVE, &idx, count_attribute, &count) < 0) {
+//         return -1;
+//     }
+//     return count;
+// }
+//
+// static ssize_t attribute_name(hid_t obj, hsize_t idx, char *name, size_t size) {
+//     return H5Aget_name_by_idx(obj, ".", H5_INDEX_NAME, H5_ITER_INC, idx, name, size, H5P_DEFAULT);
+// }
+//
+// // copy_attribute copies an attribute in its file type, replacing an attribute of the same name in dest.
+// // Committed types are copied to transient types so attributes can be copied between files.
+// static herr_t copy_attribute(hid_t src, hid_t dest, const char *name) {
+//     herr_t status = -1;
+//     hid_t sattr = -1, dattr = -1, ftype = -1, mtype = -1, space = -1;
+//     void *buf = NULL;
+//     hssize_t npoints;
+//     if ((sattr = H5Aopen(src, name, H5P_DEFAULT)) < 0) goto done;
+//     if ((ftype = H5Aget_type(sattr)) < 0) goto done;
+//     if ((mtype = H5Tcopy(ftype)) < 0) goto done;
+//     if ((space = H5Aget_space(sattr)) < 0) goto done;
+//     if ((npoints = H5Sget_simple_extent_npoints(space)) < 0) goto done;
+//     if ((buf = calloc(npoints > 0 ? npoints : 1, H5Tget_size(mtype))) == NULL) goto done;
+//     if (H5Aread(sattr, mtype, buf) < 0) goto done;
+//     if (H5Aexists(dest, name) > 0 && H5Adelete(dest, name) < 0) goto done;
+//     if ((dattr = H5Acreate2(dest, name, mtype, space, H5P_DEFAULT, H5P_DEFAULT)) < 0) goto done;
+//     if (H5Awrite(dattr, mtype, buf) < 0) goto done;
+//     status = 0;
+// done:
+//     if (buf != NULL) {
+//         H5Treclaim(mtype, space, H5P_DEFAULT, buf);
+//         free(buf);
+//     }
+//     if (dattr >= 0) H5Aclose(dattr);
+//     if (space >= 0) H5Sclose(space);
+//     if (mtype >= 0) H5Tclose(mtype);
+//     if (ftype >= 0) H5Tclose(ftype);
+//     if (sattr >= 0) H5Aclose(sattr);
+//     return status;
+// }
+import "C"
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// Unlimited is the maximum size of an unlimited dimension, H5S_UNLIMITED
+const Unlimited uint = math.MaxUint64
+
+// SetExtent changes the dimensions of a chunked dataset.  The new dimensions must not exceed the maximum
+// dimensions of the dataset; contiguous datasets cannot be resized.
+func SetExtent(datasetId int64, dims []uint) error {
+	if len(dims) == 0 {
+		return fmt.Errorf("no dimensions to set")
+	}
+	cdims := make([]C.hsize_t, len(dims))
+	for i, d := range dims {
+		cdims[i] = C.hsize_t(d)
+	}
+	if C.H5Dset_extent(C.hid_t(datasetId), &cdims[0]) < 0 {
+		return fmt.Errorf("unable to set the dataset extent to %v", dims)
+	}
+	return nil
+}
+
+// AttributeNames returns the names of the attributes of an object in name order
+func AttributeNames(objId int64) ([]string, error) {
+	count := int(C.attribute_count(C.hid_t(objId)))
+	if count < 0 {
+		return nil, fmt.Errorf("unable to count attributes")
+	}
+	names := make([]string, count)
+	for i := range names {
+		size := C.attribute_name(C.hid_t(objId), C.hsize_t(i), nil, 0)
+		if size < 0 {
+			return nil, fmt.Errorf("unable to read the name of attribute %d", i)
+		}
+		buf := (*C.char)(C.malloc(C.size_t(size) + 1))
+		size = C.attribute_name(C.hid_t(objId), C.hsize_t(i), buf, C.size_t(size)+1)
+		names[i] = C.GoString(buf)
+		C.free(unsafe.Pointer(buf))
+		if size < 0 {
+			return nil, fmt.Errorf("unable to read the name of attribute %d", i)
+		}
+	}
+	return names, nil
+}
+
+// CopyAttributes copies every attribute of the source object to the destination object, replacing attributes of
+// the same name.  The objects may be in different files.
+func CopyAttributes(srcId int64, destId int64) error {
+	names, err := AttributeNames(srcId)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		cname := C.CString(name)
+		status := C.copy_attribute(C.hid_t(srcId), C.hid_t(destId), cname)
+		C.free(unsafe.Pointer(cname))
+		if status < 0 {
+			return fmt.Errorf("unable to copy attribute %s", name)
+		}
+	}
+	return nil
+}
+
+// SetScale makes a dataset a dimension scale with a name.  Scales attached to the dimensions of other datasets are
+// how netCDF-4 files record the dimensions of their variables.
+func SetScale(datasetId int64, name string) error {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	if C.H5DSset_scale(C.hid_t(datasetId), cname) < 0 {
+		return fmt.Errorf("unable to make a dimension scale %s", name)
+	}
+	return nil
+}
+
+// AttachScale attaches a dimension scale to dimension idx of a dataset, which adds the DIMENSION_LIST attribute of
+// the dataset and the REFERENCE_LIST attribute of the scale
+func AttachScale(datasetId int64, scaleId int64, idx uint) error {
+	if C.H5DSattach_scale(C.hid_t(datasetId), C.hid_t(scaleId), C.uint(idx)) < 0 {
+		return fmt.Errorf("unable to attach the dimension scale to dimension %d", idx)
+	}
+	return nil
+}
+
+// IsAttached reports whether a dimension scale is attached to dimension idx of a dataset
+func IsAttached(datasetId int64, scaleId int64, idx uint) (bool, error) {
+	attached := C.H5DSis_attached(C.hid_t(datasetId), C.hid_t(scaleId), C.uint(idx))
+	if attached < 0 {
+		return false, fmt.Errorf("unable to check the dimension scale of dimension %d", idx)
+	}
+	return attached > 0, nil
+}
+
+// LibVersion is the version of the HDF5 library, such as 1.14.6
+func LibVersion() (string, error) {
+	var major, minor, release C.uint
+	if C.H5get_libversion(&major, &minor, &release) < 0 {
+		return "", fmt.Errorf("unable to read the hdf5 library version")
+	}
+	return fmt.Sprintf("%d.%d.%d", major, minor, release), nil
+}
@@ -0,0 +1,127 @@
+package hdfext
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/usace-cloud-compute/go-hdf5"
+)
+
+func TestHdfextSetExtentAndCopyAttributes(t *testing.T) {
+	f, err := hdf5.CreateFile(filepath.Join(t.TempDir(), "ext.hdf"), hdf5.F_ACC_TRUNC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	dcpl, err := hdf5.NewPropList(hdf5.P_DATASET_CREATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dcpl.Close()
+	err = dcpl.SetChunk([]uint{4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	space, err := hdf5.CreateSimpleDataspace([]uint{4}, []uint{Unlimited})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer space.Close()
+	src, err := f.CreateDatasetWith("src", hdf5.T_NATIVE_FLOAT, space, dcpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dest, err := f.CreateDatasetWith("dest", hdf5.T_NATIVE_FLOAT, space, dcpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dest.Close()
+
+	err = SetExtent(dest.ID(), []uint{10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	destspace := dest.Space()
+	defer destspace.Close()
+	dims, _, err := destspace.SimpleExtentDims()
+	if err != nil || dims[0] != 10 {
+		t.Errorf("expected 10 rows, got %v: %v", dims, err)
+	}
+
+	attrspace, err := hdf5.CreateSimpleDataspace([]uint{1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer attrspace.Close()
+	for _, name := range []string{"units", "scale"} {
+		attr, err := src.CreateAttribute(name, hdf5.T_NATIVE_INT32, attrspace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value := int32(7)
+		err = attr.Write(&value, hdf5.T_NATIVE_INT32)
+		attr.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = CopyAttributes(src.ID(), dest.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := AttributeNames(dest.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"scale", "units"}) {
+		t.Errorf("unexpected attributes %v", names)
+	}
+}
+
+func TestHdfextDimensionScales(t *testing.T) {
+	f, err := hdf5.CreateFile(filepath.Join(t.TempDir(), "scales.hdf"), hdf5.F_ACC_TRUNC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	space, err := hdf5.CreateSimpleDataspace([]uint{3}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer space.Close()
+	scale, err := f.CreateDataset("time", hdf5.T_NATIVE_DOUBLE, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer scale.Close()
+	flow, err := f.CreateDataset("flow", hdf5.T_NATIVE_FLOAT, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer flow.Close()
+
+	if err = SetScale(scale.ID(), "time"); err != nil {
+		t.Fatal(err)
+	}
+	if attached, err := IsAttached(flow.ID(), scale.ID(), 0); err != nil || attached {
+		t.Errorf("expected an unattached scale, got %v %v", attached, err)
+	}
+	if err = AttachScale(flow.ID(), scale.ID(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if attached, err := IsAttached(flow.ID(), scale.ID(), 0); err != nil || !attached {
+		t.Errorf("expected an attached scale, got %v %v", attached, err)
+	}
+	if names, err := AttributeNames(flow.ID()); err != nil || !slices.Equal(names, []string{"DIMENSION_LIST"}) {
+		t.Errorf("unexpected dataset attributes %v %v", names, err)
+	}
+	if names, err := AttributeNames(scale.ID()); err != nil || !slices.Equal(names, []string{"CLASS", "NAME", "REFERENCE_LIST"}) {
+		t.Errorf("unexpected scale attributes %v %v", names, err)
+	}
+	if version, err := LibVersion(); err != nil || version == "" {
+		t.Errorf("unexpected library version %q %v", version, err)
+	}
+}
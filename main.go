@@ -1,21 +1,31 @@
 package main
 
 import (
+	"errors"
 	"log"
-	_ "ras-runner/actions/extract/hdf"
+	"os"
+	"ras-runner/actions/extract/hdf"
 	_ "ras-runner/actions/link"
 	_ "ras-runner/actions/run"
 	_ "ras-runner/actions/utils"
+	"strings"
 
 	"github.com/usace-cloud-compute/cc-go-sdk"
 )
 
 func main() {
+	//command line arguments run a cli subcommand rather than the CC payload
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		if err := runCli(os.Args[1:]); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
 	pm, err := cc.InitPluginManager()
 	if err != nil {
 		log.Fatalf("unable to initialize the CC plugin manager: %s\n", err)
 	}
-	err = pm.RunActions()
+	err = errors.Join(pm.RunActions(), hdf.ReleasePluginRunResults(pm))
 	if err != nil {
 		log.Fatalf("Error running actions: %s\n", err)
 	}
@@ -0,0 +1,408 @@
+package netcdf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"ras-runner/hdfext"
+
+	"github.com/usace-cloud-compute/go-hdf5"
+)
+
+/*
+netcdf writes NetCDF-4 files, the HDF5 based format of the NetCDF-4 libraries.  Each variable and each dimension is an
+HDF5 dataset in the root group:
+  - a dimension with a coordinate variable, a one dimensional variable named after its dimension, is the dataset of
+    the variable
+  - a dimension without a coordinate variable is a dataset without values named after the dimension
+
+The dimension datasets are HDF5 dimension scales with a _Netcdf4Dimid attribute, the id of the dimension, and the
+scales are attached to the dimensions of each variable that uses them, which writes the DIMENSION_LIST attribute of
+the variable and the REFERENCE_LIST attribute of the scale.  The _NCProperties attribute of the root group records the
+format version.
+
+Files do not have an unlimited dimension, so every dimension has a fixed length.
+
+Variable values and attribute values are typed by their go type:
+  - []int8:    NC_BYTE
+  - []byte:    NC_CHAR, strings are written as NC_CHAR
+  - []int16:   NC_SHORT
+  - []int32:   NC_INT
+  - []float32: NC_FLOAT
+  - []float64: NC_DOUBLE
+
+Attributes may also be a single value of one of these types.
+*/
+
+// the name of the scale of a dimension without a coordinate variable, which the NetCDF-4 libraries pad with the
+// dimension length
+const dimWithoutVariable string = "This is a netCDF dimension but not a netCDF variable.%10d"
+
+type Dimension struct {
+	Name   string
+	Length int
+}
+
+type Attribute struct {
+	Name  string
+	Value any
+}
+
+// Variable is a variable and its values in row major order of its dimensions
+type Variable struct {
+	Name       string
+	Dimensions []string
+	Attributes []Attribute
+	Values     any
+}
+
+// File is a NetCDF file built in memory and written with Create
+type File struct {
+	Dimensions []Dimension
+	Attributes []Attribute
+	Variables  []Variable
+}
+
+// AddDimension adds a fixed length dimension.  Lengths must be positive since the file does not have an unlimited
+// dimension.
+func (f *File) AddDimension(name string, length int) error {
+	if length <= 0 {
+		return fmt.Errorf("dimension %s length %d is not positive", name, length)
+	}
+	if f.dimension(name) >= 0 {
+		return fmt.Errorf("dimension %s already exists", name)
+	}
+	f.Dimensions = append(f.Dimensions, Dimension{Name: name, Length: length})
+	return nil
+}
+
+// AddAttribute adds a global attribute
+func (f *File) AddAttribute(name string, value any) {
+	f.Attributes = append(f.Attributes, Attribute{Name: name, Value: value})
+}
+
+// AddVariable adds a variable.  Its dimensions must already be added and it must have a value for each element of
+// its dimensions.  A variable named after a dimension must be the coordinate variable of the dimension, since the
+// dimension and the variable share a dataset.
+func (f *File) AddVariable(v Variable) error {
+	if slices.ContainsFunc(f.Variables, func(fv Variable) bool { return fv.Name == v.Name }) {
+		return fmt.Errorf("variable %s already exists", v.Name)
+	}
+	if f.dimension(v.Name) >= 0 && !slices.Equal(v.Dimensions, []string{v.Name}) {
+		return fmt.Errorf("variable %s has the name of a dimension and is not its coordinate variable", v.Name)
+	}
+	count := 1
+	for _, dim := range v.Dimensions {
+		idx := f.dimension(dim)
+		if idx < 0 {
+			return fmt.Errorf("variable %s dimension %s does not exist", v.Name, dim)
+		}
+		count *= f.Dimensions[idx].Length
+	}
+	n, err := valueCount(v.Values)
+	if err != nil {
+		return fmt.Errorf("variable %s: %s", v.Name, err)
+	}
+	if n != count {
+		return fmt.Errorf("variable %s has %d values for %d elements", v.Name, n, count)
+	}
+	f.Variables = append(f.Variables, v)
+	return nil
+}
+
+func (f *File) dimension(name string) int {
+	return slices.IndexFunc(f.Dimensions, func(d Dimension) bool { return d.Name == name })
+}
+
+func (f *File) variable(name string) int {
+	return slices.IndexFunc(f.Variables, func(v Variable) bool { return v.Name == name })
+}
+
+// Create writes the file to path, replacing an existing file
+func (f *File) Create(path string) error {
+	h5, err := hdf5.CreateFile(path, hdf5.F_ACC_TRUNC)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %s", path, err)
+	}
+	defer h5.Close()
+	version, err := hdfext.LibVersion()
+	if err != nil {
+		return err
+	}
+	root, err := h5.OpenGroup("/")
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	err = writeAttributes(root, append([]Attribute{{Name: "_NCProperties", Value: "version=2,hdf5=" + version}}, f.Attributes...))
+	if err != nil {
+		return err
+	}
+
+	scales := make([]*hdf5.Dataset, 0, len(f.Dimensions))
+	defer func() {
+		for _, scale := range scales {
+			scale.Close()
+		}
+	}()
+	for id, dim := range f.Dimensions {
+		var scale *hdf5.Dataset
+		var scaleName string
+		if v := f.variable(dim.Name); v >= 0 {
+			scale, err = createVariable(h5, f.Variables[v], []uint{uint(dim.Length)})
+			scaleName = dim.Name
+		} else {
+			scale, err = createDataset(h5, dim.Name, hdf5.T_IEEE_F32BE, []uint{uint(dim.Length)})
+			scaleName = fmt.Sprintf(dimWithoutVariable, dim.Length)
+		}
+		if err != nil {
+			return fmt.Errorf("dimension %s: %s", dim.Name, err)
+		}
+		scales = append(scales, scale)
+		err = hdfext.SetScale(scale.ID(), scaleName)
+		if err != nil {
+			return fmt.Errorf("dimension %s: %s", dim.Name, err)
+		}
+		err = writeAttributes(scale, []Attribute{{Name: "_Netcdf4Dimid", Value: int32(id)}})
+		if err != nil {
+			return fmt.Errorf("dimension %s: %s", dim.Name, err)
+		}
+	}
+
+	for _, v := range f.Variables {
+		if f.dimension(v.Name) >= 0 {
+			//coordinate variables were written as the scales of their dimensions
+			continue
+		}
+		err = f.writeVariable(h5, v, scales)
+		if err != nil {
+			return fmt.Errorf("variable %s: %s", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// writeVariable writes a variable and attaches the scales of its dimensions
+func (f *File) writeVariable(h5 *hdf5.File, v Variable, scales []*hdf5.Dataset) error {
+	dims := make([]uint, len(v.Dimensions))
+	for i, dim := range v.Dimensions {
+		dims[i] = uint(f.Dimensions[f.dimension(dim)].Length)
+	}
+	ds, err := createVariable(h5, v, dims)
+	if err != nil {
+		return err
+	}
+	defer ds.Close()
+	for i, dim := range v.Dimensions {
+		err = hdfext.AttachScale(ds.ID(), scales[f.dimension(dim)].ID(), uint(i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createVariable writes the dataset of a variable with its values and attributes
+func createVariable(h5 *hdf5.File, v Variable, dims []uint) (*hdf5.Dataset, error) {
+	dtype, err := datatype(v.Values)
+	if err != nil {
+		return nil, err
+	}
+	defer dtype.Close()
+	ds, err := createDataset(h5, v.Name, dtype, dims)
+	if err != nil {
+		return nil, err
+	}
+	err = ds.Write(firstValue(v.Values))
+	if err == nil {
+		err = writeAttributes(ds, v.Attributes)
+	}
+	if err != nil {
+		ds.Close()
+		return nil, err
+	}
+	return ds, nil
+}
+
+func createDataset(h5 *hdf5.File, name string, dtype *hdf5.Datatype, dims []uint) (*hdf5.Dataset, error) {
+	var space *hdf5.Dataspace
+	var err error
+	if len(dims) == 0 {
+		space, err = hdf5.CreateDataspace(hdf5.S_SCALAR)
+	} else {
+		space, err = hdf5.CreateSimpleDataspace(dims, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer space.Close()
+	return h5.CreateDataset(name, dtype, space)
+}
+
+// attributeWriter is a group or dataset
+type attributeWriter interface {
+	CreateAttribute(name string, dtype *hdf5.Datatype, dspace *hdf5.Dataspace) (*hdf5.Attribute, error)
+}
+
+func writeAttributes(obj attributeWriter, attrs []Attribute) error {
+	for _, attr := range attrs {
+		err := writeAttribute(obj, attr)
+		if err != nil {
+			return fmt.Errorf("attribute %s: %s", attr.Name, err)
+		}
+	}
+	return nil
+}
+
+// writeAttribute writes text as a fixed length string, as NetCDF-4 writes NC_CHAR attributes, and other values as an
+// array
+func writeAttribute(obj attributeWriter, attr Attribute) error {
+	values := attributeValues(attr.Value)
+	dtype, err := datatype(values)
+	if err != nil {
+		return err
+	}
+	defer dtype.Close()
+	n, _ := valueCount(values)
+	var space *hdf5.Dataspace
+	switch {
+	case n == 0:
+		space, err = hdf5.CreateDataspace(hdf5.S_NULL)
+	case dtype.Class() == hdf5.T_STRING:
+		err = dtype.SetSize(n)
+		if err == nil {
+			space, err = hdf5.CreateDataspace(hdf5.S_SCALAR)
+		}
+	default:
+		space, err = hdf5.CreateSimpleDataspace([]uint{uint(n)}, nil)
+	}
+	if err != nil {
+		return err
+	}
+	defer space.Close()
+	a, err := obj.CreateAttribute(attr.Name, dtype, space)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	if n == 0 {
+		return nil
+	}
+	return a.Write(firstValue(values), dtype)
+}
+
+// firstValue is a pointer to the first of a slice of values, the address go-hdf5 writes the values from
+func firstValue(values any) any {
+	return reflect.ValueOf(values).Index(0).Addr().Interface()
+}
+
+// attributeValues converts single attribute values and strings to slices
+func attributeValues(value any) any {
+	switch v := value.(type) {
+	case string:
+		return []byte(v)
+	case int8:
+		return []int8{v}
+	case int16:
+		return []int16{v}
+	case int32:
+		return []int32{v}
+	case float32:
+		return []float32{v}
+	case float64:
+		return []float64{v}
+	}
+	return value
+}
+
+// valueCount is the number of values
+func valueCount(values any) (int, error) {
+	switch v := values.(type) {
+	case []int8:
+		return len(v), nil
+	case []byte:
+		return len(v), nil
+	case []int16:
+		return len(v), nil
+	case []int32:
+		return len(v), nil
+	case []float32:
+		return len(v), nil
+	case []float64:
+		return len(v), nil
+	}
+	return 0, fmt.Errorf("unsupported value type %T", values)
+}
+
+// datatype is a copy of the HDF5 type of the values, closed by the caller.  NC_CHAR is a one character null
+// terminated string.
+func datatype(values any) (*hdf5.Datatype, error) {
+	var dtype *hdf5.Datatype
+	switch values.(type) {
+	case []int8:
+		dtype = hdf5.T_NATIVE_INT8
+	case []byte:
+		dtype = hdf5.T_C_S1
+	case []int16:
+		dtype = hdf5.T_NATIVE_INT16
+	case []int32:
+		dtype = hdf5.T_NATIVE_INT32
+	case []float32:
+		dtype = hdf5.T_NATIVE_FLOAT
+	case []float64:
+		dtype = hdf5.T_NATIVE_DOUBLE
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", values)
+	}
+	return dtype.Copy()
+}
+
+// CharValues lays out strings as a char variable with a last dimension of width characters.  Strings are padded with
+// zeros and must not be longer than width.
+func CharValues(strs []string, width int) ([]byte, error) {
+	values := make([]byte, len(strs)*width)
+	for i, s := range strs {
+		if len(s) > width {
+			return nil, fmt.Errorf("%q is longer than %d characters", s, width)
+		}
+		copy(values[i*width:], s)
+	}
+	return values, nil
+}
+
+// MaxLength is the length of the longest string, or 1 for a list of empty strings, for the width of a char variable
+func MaxLength(strs []string) int {
+	width := 1
+	for _, s := range strs {
+		width = max(width, len(s))
+	}
+	return width
+}
+
+var ErrInvalidName = errors.New("invalid NetCDF name")
+
+// Name converts a RAS name to a NetCDF name with letters, digits and underscores that starts with a letter, as CF
+// recommends.  Other characters are replaced by underscores.
+func Name(name string) (string, error) {
+	var out []byte
+	for _, c := range []byte(name) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			out = append(out, c)
+		case len(out) > 0 && out[len(out)-1] != '_':
+			out = append(out, '_')
+		}
+	}
+	for len(out) > 0 && out[len(out)-1] == '_' {
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("%q: %w", name, ErrInvalidName)
+	}
+	if out[0] >= '0' && out[0] <= '9' {
+		out = append([]byte("v_"), out...)
+	}
+	return string(out), nil
+}
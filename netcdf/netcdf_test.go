@@ -0,0 +1,181 @@
+package netcdf
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"ras-runner/hdfext"
+
+	"github.com/usace-cloud-compute/go-hdf5"
+)
+
+func TestCreate(t *testing.T) {
+	f := File{}
+	for _, dim := range []Dimension{{"time", 3}, {"station", 2}, {"name_strlen", 4}} {
+		if err := f.AddDimension(dim.Name, dim.Length); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.AddAttribute("title", "refline-series")
+	variables := []Variable{
+		{Name: "time", Dimensions: []string{"time"}, Attributes: []Attribute{{"units", "days since 2000-01-01 00:00:00"}}, Values: []float64{0, 0.5, 1}},
+		{Name: "station_name", Dimensions: []string{"station", "name_strlen"}, Values: []byte("Dam\x00Weir")},
+		{Name: "flow", Dimensions: []string{"station", "time"}, Attributes: []Attribute{{"_FillValue", float32(-9999)}}, Values: []float32{1, 2, 3, 4, 5, 6}},
+	}
+	for _, v := range variables {
+		if err := f.AddVariable(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "series.nc")
+	if err := f.Create(path); err != nil {
+		t.Fatal(err)
+	}
+
+	h5, err := hdf5.OpenFile(path, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h5.Close()
+	root, err := h5.OpenGroup("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	if names, err := hdfext.AttributeNames(root.ID()); err != nil || !reflect.DeepEqual(names, []string{"_NCProperties", "title"}) {
+		t.Errorf("unexpected global attributes %v %v", names, err)
+	}
+	title, err := root.OpenAttribute("title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer title.Close()
+	var titleValue string
+	if err = title.Read(&titleValue, nil); err != nil || titleValue != "refline-series" {
+		t.Errorf("unexpected title %q %v", titleValue, err)
+	}
+
+	datasets := map[string]*hdf5.Dataset{}
+	for _, name := range []string{"time", "station", "name_strlen", "station_name", "flow"} {
+		ds, err := h5.OpenDataset(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ds.Close()
+		datasets[name] = ds
+	}
+	//the dimensions are scales, and the coordinate variable time is the scale of its dimension
+	for name, want := range map[string][]string{
+		"time":        {"CLASS", "NAME", "REFERENCE_LIST", "_Netcdf4Dimid", "units"},
+		"station":     {"CLASS", "NAME", "REFERENCE_LIST", "_Netcdf4Dimid"},
+		"name_strlen": {"CLASS", "NAME", "REFERENCE_LIST", "_Netcdf4Dimid"},
+		"flow":        {"DIMENSION_LIST", "_FillValue"},
+	} {
+		if names, err := hdfext.AttributeNames(datasets[name].ID()); err != nil || !reflect.DeepEqual(names, want) {
+			t.Errorf("unexpected %s attributes %v %v", name, names, err)
+		}
+	}
+	for _, attached := range []struct {
+		variable string
+		idx      uint
+		scale    string
+	}{{"flow", 0, "station"}, {"flow", 1, "time"}, {"station_name", 0, "station"}, {"station_name", 1, "name_strlen"}} {
+		ok, err := hdfext.IsAttached(datasets[attached.variable].ID(), datasets[attached.scale].ID(), attached.idx)
+		if err != nil || !ok {
+			t.Errorf("expected %s to be the scale of dimension %d of %s: %v", attached.scale, attached.idx, attached.variable, err)
+		}
+	}
+	dimName, err := datasets["station"].OpenAttribute("NAME")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dimName.Close()
+	var dimNameValue string
+	if err = dimName.Read(&dimNameValue, nil); err != nil || dimNameValue != "This is a netCDF dimension but not a netCDF variable.         2" {
+		t.Errorf("unexpected dimension scale name %q %v", dimNameValue, err)
+	}
+	dimid, err := datasets["name_strlen"].OpenAttribute("_Netcdf4Dimid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dimid.Close()
+	var dimidValue int32
+	if err = dimid.Read(&dimidValue, hdf5.T_NATIVE_INT32); err != nil || dimidValue != 2 {
+		t.Errorf("unexpected dimension id %d %v", dimidValue, err)
+	}
+
+	flow := make([]float32, 6)
+	if err = datasets["flow"].Read(&flow); err != nil || !reflect.DeepEqual(flow, []float32{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("unexpected flow %v %v", flow, err)
+	}
+	days := make([]float64, 3)
+	if err = datasets["time"].Read(&days); err != nil || !reflect.DeepEqual(days, []float64{0, 0.5, 1}) {
+		t.Errorf("unexpected time %v %v", days, err)
+	}
+	stations := make([]byte, 8)
+	if err = datasets["station_name"].Read(&stations); err != nil || string(stations) != "Dam\x00Weir" {
+		t.Errorf("unexpected station names %q %v", stations, err)
+	}
+}
+
+func TestAddVariable(t *testing.T) {
+	f := File{}
+	if err := f.AddDimension("time", 0); err == nil {
+		t.Error("expected an error for an empty dimension")
+	}
+	f.AddDimension("time", 3)
+	f.AddDimension("station", 2)
+	if err := f.AddVariable(Variable{Name: "flow", Dimensions: []string{"time", "station"}, Values: []float32{1, 2, 3}}); err == nil {
+		t.Error("expected an error for too few values")
+	}
+	if err := f.AddVariable(Variable{Name: "flow", Dimensions: []string{"time", "reach"}, Values: []float32{1, 2, 3}}); err == nil {
+		t.Error("expected an error for a missing dimension")
+	}
+	if err := f.AddVariable(Variable{Name: "flow", Dimensions: []string{"time"}, Values: []int64{1, 2, 3}}); err == nil {
+		t.Error("expected an error for an unsupported type")
+	}
+	if err := f.AddVariable(Variable{Name: "flow", Dimensions: []string{"time", "station"}, Values: make([]float32, 6)}); err != nil {
+		t.Error(err)
+	}
+	if err := f.AddVariable(Variable{Name: "flow", Dimensions: []string{"time"}, Values: make([]float32, 3)}); err == nil {
+		t.Error("expected an error for a duplicate variable")
+	}
+	if err := f.AddVariable(Variable{Name: "station", Dimensions: []string{"time"}, Values: make([]float32, 3)}); err == nil {
+		t.Error("expected an error for a variable named after a dimension it does not coordinate")
+	}
+}
+
+func TestCharValues(t *testing.T) {
+	names := []string{"Dam", "Levee 1"}
+	width := MaxLength(names)
+	values, err := CharValues(names, width)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 7 || string(values) != "Dam\x00\x00\x00\x00Levee 1" {
+		t.Errorf("unexpected char values %q width %d", values, width)
+	}
+	if _, err = CharValues(names, 3); err == nil {
+		t.Error("expected an error for a name longer than the width")
+	}
+}
+
+func TestName(t *testing.T) {
+	for in, want := range map[string]string{
+		"Water Surface":   "Water_Surface",
+		"Flow (cfs)":      "Flow_cfs",
+		"2D Flow Area":    "v_2D_Flow_Area",
+		"Stage HW":        "Stage_HW",
+		"Velocity--Face ": "Velocity_Face",
+	} {
+		got, err := Name(in)
+		if err != nil || got != want {
+			t.Errorf("Name(%q) = %q %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := Name(" - "); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("expected an invalid name error, got %v", err)
+	}
+}
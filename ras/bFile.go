@@ -1,9 +1,11 @@
 package ras
 
 import (
-	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"math"
 	"os"
 	"strconv"
@@ -14,6 +16,11 @@ import (
 const CELL_SIZE_ERROR string = "the row was not able to be divided evenly by the cell size without remainder. Ensure the b-file has not been modified outside of RAS"
 const BREACH_DATA_HEADER string = "Breach Data"
 const TS_OUTFLOW_HEADER string = "Outlet TS - "
+const GATE_OPENING_DATA_HEADER string = "Time Series of Gate Opening Data for:"
+const GATE_HEADER string = "Gate:"
+const INITIAL_CONDITIONS_HEADER string = "Initial Conditions (use restart file?)"
+const OBSERVED_DATA_HEADER string = "Internal Observed Stage/Flow Boundaries"
+const EXTRA_COMMANDS_HEADER string = "Extra Commands"
 
 var TS_OUTFLOW_SUFFIX []byte = []byte("\n 3.4E+38\n")
 
@@ -24,15 +31,22 @@ type Bfile struct {
 	Filename           string
 	BfileBlocks        []BfileBlock
 	SNETidToStructName map[string]int // This should be initialized with a geometry hdf using InitSNETidToStructName("*.g**.hdf")
+	crlf               bool           // the file was read with windows line endings
+	noFinalNewline     bool           // the file did not end with a newline
 }
 type BfileBlock interface {
 	UpdateFloat(value float64) error
 	UpdateFloatArray(values []float32) error
 	ToBytes() ([]byte, error)
+	// WriteTo streams the rows of the block with "\n" line endings
+	WriteTo(w io.Writer) (int64, error)
 	Header() string
 }
 type DefaultBlock struct {
 	Rows []string
+	// ParseErr is set when the rows are a typed block that could not be parsed.  The rows are kept so the file
+	// still round trips, and lookups of the typed block return the error rather than not finding the block.
+	ParseErr error
 }
 
 // assuming row 0 is always the header for a default block
@@ -50,11 +64,11 @@ func (db *DefaultBlock) UpdateFloatArray(values []float32) error {
 	return errors.New("cannot update float array on default blocks")
 }
 func (db *DefaultBlock) ToBytes() ([]byte, error) {
-	bytedata := make([]byte, 0)
-	for _, row := range db.Rows {
-		bytedata = append(bytedata, fmt.Sprintf("%s\n", row)...)
-	}
-	return bytedata, nil
+	return blockBytes(db)
+}
+
+func (db *DefaultBlock) WriteTo(w io.Writer) (int64, error) {
+	return writeRows(w, db.Rows)
 }
 func InitBFile(bfilePath string) (*Bfile, error) {
 	bf := Bfile{
@@ -69,31 +83,48 @@ func InitBFile(bfilePath string) (*Bfile, error) {
 
 // read the file into memory as a slice of string, where each line/row is a string.
 func (bf *Bfile) readBFile() error {
-	file, err := os.Open(bf.Filename)
+	data, err := os.ReadFile(bf.Filename)
 	if err != nil {
 		return err
 	}
-	//close the file when we're done
-	defer file.Close()
+	return bf.readBytes(data)
+}
+
+// readBytes splits the b-file into blocks at each header row and parses the block types we know about.
+// Line endings are restored by Write so unchanged files round trip byte for byte.
+func (bf *Bfile) readBytes(data []byte) error {
+	text := string(data)
+	bf.crlf = strings.Contains(text, "\r\n")
+	if bf.crlf {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	bf.noFinalNewline = len(text) > 0 && !strings.HasSuffix(text, "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		bf.BfileBlocks = []BfileBlock{}
+		return nil
+	}
+	lines := strings.Split(text, "\n")
 
-	//read the file line by line
+	//gather all blocks
 	blocks := make([][]string, 0)
-	scanner := bufio.NewScanner(file)
 	blockRows := make([]string, 0)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if len(blockRows) == 0 {
-			blockRows = append(blockRows, line) //must be the first header
-		} else {
-			if rowIsNotAHeader(line) {
-				blockRows = append(blockRows, line) //must not be a header
-			} else {
-				blocks = append(blocks, blockRows)
-				//new block
-				blockRows = make([]string, 0)
-				blockRows = append(blockRows, line) //must add the line not to loose it
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if len(blockRows) != 0 && !rowIsNotAHeader(line) {
+			blocks = append(blocks, blockRows)
+			//new block
+			blockRows = make([]string, 0)
+		}
+		blockRows = append(blockRows, line)
+		//extra commands can start with a letter so the declared number of command rows are always part of the block
+		if len(blockRows) == 2 && strings.HasPrefix(blockRows[0], EXTRA_COMMANDS_HEADER) {
+			count, err := getIntFromCellValue(line)
+			if err == nil && count > 0 {
+				end := min(i+1+count, len(lines))
+				blockRows = append(blockRows, lines[i+1:end]...)
+				i = end - 1
 			}
-
 		}
 	}
 	//add the final block to the blocks slice
@@ -108,27 +139,129 @@ func (bf *Bfile) readBFile() error {
 				return err
 			}
 			bFileBlocks = append(bFileBlocks, breachData...)
-		} else if strings.Contains(block[0], TS_OUTFLOW_HEADER) {
-			tsOutflowData, err := InitOutletTS(block)
-			if err != nil {
-				return err
+			continue
+		}
+		var parsed BfileBlock
+		var parseErr error
+		for _, bt := range blockTypes {
+			if bt.matches(block[0]) {
+				typedBlock, err := bt.init(block)
+				if err != nil {
+					//keep blocks we can not parse as they are so the file still round trips
+					parseErr = fmt.Errorf("unable to parse the %s block: %s", strings.TrimSpace(block[0]), err)
+					log.Printf("%s, keeping it unchanged\n", parseErr)
+					break
+				}
+				parsed = typedBlock
+				break
 			}
-			bFileBlocks = append(bFileBlocks, tsOutflowData)
-		} else {
-			db := DefaultBlock{Rows: block}
-			bFileBlocks = append(bFileBlocks, &db)
 		}
+		if parsed == nil {
+			parsed = &DefaultBlock{Rows: block, ParseErr: parseErr}
+		}
+		bFileBlocks = append(bFileBlocks, parsed)
 	}
 	bf.BfileBlocks = bFileBlocks
 	return nil
 }
 
+// unparsedBlockError returns the parse error of the first block with a matching header that could not be parsed,
+// or nil when every matching block was parsed
+func (bf *Bfile) unparsedBlockError(matches func(header string) bool) error {
+	for _, block := range bf.BfileBlocks {
+		if db, ok := block.(*DefaultBlock); ok && db.ParseErr != nil && matches(db.Header()) {
+			return db.ParseErr
+		}
+	}
+	return nil
+}
+
+// FindOutletTS returns the index and block of the first outlet time series whose name contains name.  An outlet time
+// series with a matching name that could not be parsed is reported with its parse error.
+func (bf *Bfile) FindOutletTS(name string) (int, *OutletTS, error) {
+	for idx, block := range bf.BfileBlocks {
+		if outts, ok := block.(*OutletTS); ok && strings.Contains(outts.Name, name) {
+			return idx, outts, nil
+		}
+	}
+	err := bf.unparsedBlockError(func(header string) bool {
+		return strings.HasPrefix(header, TS_OUTFLOW_HEADER) && strings.Contains(header[len(TS_OUTFLOW_HEADER):], name)
+	})
+	if err != nil {
+		return -1, nil, err
+	}
+	return -1, nil, fmt.Errorf("could not find the outlet TS named %s", name)
+}
+
+// blockType matches a block header to the parser for the block
+type blockType struct {
+	matches func(header string) bool
+	init    func(rows []string) (BfileBlock, error)
+}
+
+var blockTypes []blockType = []blockType{
+	{
+		matches: func(header string) bool { return strings.HasPrefix(header, TS_OUTFLOW_HEADER) },
+		init:    func(rows []string) (BfileBlock, error) { return InitOutletTS(rows) },
+	},
+	{
+		matches: isHydrographHeader,
+		init:    func(rows []string) (BfileBlock, error) { return InitHydrograph(rows) },
+	},
+	{
+		matches: func(header string) bool { return strings.HasPrefix(header, GATE_OPENING_DATA_HEADER) },
+		init:    func(rows []string) (BfileBlock, error) { return InitGateOpeningData(rows) },
+	},
+	{
+		matches: func(header string) bool { return strings.HasPrefix(header, GATE_HEADER) },
+		init:    func(rows []string) (BfileBlock, error) { return InitGateOpening(rows) },
+	},
+	{
+		matches: func(header string) bool { return header == INITIAL_CONDITIONS_HEADER },
+		init:    func(rows []string) (BfileBlock, error) { return InitInitialConditions(rows) },
+	},
+	{
+		matches: func(header string) bool { return header == OBSERVED_DATA_HEADER },
+		init:    func(rows []string) (BfileBlock, error) { return InitObservedData(rows) },
+	},
+	{
+		matches: func(header string) bool { return header == EXTRA_COMMANDS_HEADER },
+		init:    func(rows []string) (BfileBlock, error) { return InitExtraCommands(rows) },
+	},
+}
+
+// WriteBreachRows replaces the breach data of the matching structures, by SNET ID, and writes the b-file to bfilePath.
 func (bf *Bfile) WriteBreachRows(bds []BreachData, bfilePath string) ([]byte, error) {
-	return nil, nil
+	for _, bd := range bds {
+		found := false
+		for idx, block := range bf.BfileBlocks {
+			breach, ok := block.(*BreachData)
+			if ok && breach.SNetID == bd.SNetID {
+				updated := bd
+				bf.BfileBlocks[idx] = &updated
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("breach data for SNET ID %d does not exist in the bFile", bd.SNetID)
+		}
+	}
+	b, err := bf.Write()
+	if err != nil {
+		return b, err
+	}
+	return b, WriteFileAtomic(bfilePath, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
 }
 
 // /Headers always start with a letter, Checks if the row starts with a letter, if it doesn't, returns false.
 func rowIsNotAHeader(row string) bool {
+	if len(row) == 0 {
+		return true
+	}
 	var firstLetter rune = rune(row[0]) //first letter as a rune / kinda like a char.
 	isAHeader := unicode.IsLetter(firstLetter)
 	return !isAHeader
@@ -145,6 +278,15 @@ func rowIsNotWhiteSpace(row string) bool {
 	return false
 }
 
+// cellsToRow right aligns each value in a b-file cell
+func cellsToRow(values []string) string {
+	var sb strings.Builder
+	for _, v := range values {
+		sb.WriteString(fmt.Sprintf("%8s", v))
+	}
+	return sb.String()
+}
+
 func convertFloatToBfileCellValue(fl float64) string {
 	// Round the float to 8 digits
 	rounded := math.Round(fl*1e8) / 1e8
@@ -167,47 +309,46 @@ func getIntFromCellValue(cell string) (int, error) {
 
 // AmmendBreachElevations finds the structure breach data which matches the structureName and updates it's elevation in the breach data rows.
 func (bf *Bfile) AmmendBreachElevations(structureName string, newFailureElevation float64) error {
+	Breach, err := bf.FindBreachData(structureName)
+	if err != nil {
+		return err
+	}
+	return Breach.UpdateFloat(newFailureElevation)
+}
+
+// AmmendBreachParameters finds the structure breach data which matches the structureName and updates the parameters that are set.
+func (bf *Bfile) AmmendBreachParameters(structureName string, params BreachParameters) error {
+	Breach, err := bf.FindBreachData(structureName)
+	if err != nil {
+		return err
+	}
+	return Breach.UpdateParameters(params)
+}
+
+// FindBreachData returns the breach data of a structure by name.  SNETidToStructName must be set first.
+func (bf *Bfile) FindBreachData(structureName string) (*BreachData, error) {
 	//searching for the right breach data with a loop seems inefficient. I could bring in the geom in the Init and build a dictionary
 	if bf.SNETidToStructName == nil {
-		return errors.New("use SetSNetIDToNameFromGeoHDF() to set SNETidToStructName property in BFile before ammending elevations")
-	}
-	targetSNetID := bf.SNETidToStructName[structureName]
-	for _, v := range bf.BfileBlocks {
-		Breach, ok := v.(*BreachData)
-		if ok {
-			strucID := Breach.SNetID
-			if strucID == targetSNetID {
-				err := Breach.UpdateFloat(newFailureElevation)
-				if err != nil {
-					return err
-				}
-				return nil
+		return nil, errors.New("use SetSNetIDToNameFromGeoHDF() to set SNETidToStructName property in BFile before ammending elevations")
+	}
+	targetSNetID, ok := bf.SNETidToStructName[structureName]
+	if ok {
+		for _, v := range bf.BfileBlocks {
+			Breach, ok := v.(*BreachData)
+			if ok && Breach.SNetID == targetSNetID {
+				return Breach, nil
 			}
 		}
-
 	}
 	//if we made it through the loop without finding a structure, it's not there.
-	return fmt.Errorf("structure name, %v, did not exist in bFile", structureName)
+	return nil, fmt.Errorf("structure name, %v, did not exist in bFile", structureName)
 }
 
-// Write writes bFile to byte array
+// Write writes bFile to byte array.  Use WriteTo or WriteFile to stream large b-files.
 func (bf Bfile) Write() ([]byte, error) {
-	//for each row in th file
-	//@TODO: using a byte slice to compose a large string is not efficient.  Use a strings.Builder or a bytes.Buffer
-	b := make([]byte, 0)
-	for _, block := range bf.BfileBlocks {
-		blockBytes, err := block.ToBytes()
-		if err != nil {
-			return b, err
-		}
-		//switch with a single case is bad form, but I'm gussing we will have more corner cases so I'm using the switch over an if statement
-		//switch {
-		//case strings.HasPrefix(block.Header(), TS_OUTFLOW_HEADER):
-		//	blockBytes = append(blockBytes, TS_OUTFLOW_SUFFIX...)
-		//}
-		b = append(b, blockBytes...)
-	}
-	return b, nil
+	var buf bytes.Buffer
+	_, err := bf.WriteTo(&buf)
+	return buf.Bytes(), err
 }
 
 // Create a dictionary of SNET-ID to structure name from the Geometry HDF.
@@ -0,0 +1,197 @@
+package ras
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// block changes reported by DiffBfiles
+const (
+	BLOCK_ADDED    string = "added"
+	BLOCK_REMOVED  string = "removed"
+	BLOCK_MODIFIED string = "modified"
+)
+
+// BfileBlockSummary is a typed b-file block with the key used to match it between b-files
+type BfileBlockSummary struct {
+	Key        string            `json:"key"`
+	Type       string            `json:"type"`
+	Block      BfileBlock        `json:"block"`
+	Parameters *BreachParameters `json:"breach_parameters,omitempty"`
+}
+
+// BfileDifference is a block that was added, removed or modified between two b-files.  Cells are only reported for
+// modified blocks.
+type BfileDifference struct {
+	Key    string                `json:"key"`
+	Type   string                `json:"type"`
+	Change string                `json:"change"`
+	Cells  []BfileCellDifference `json:"cells,omitempty"`
+}
+
+// BfileCellDifference is a changed 8 character cell.  Row and Column are counted from the first row of the block and
+// Delta is reported when both values are numeric.  Parameter names the breach parameter stored in the cell.
+type BfileCellDifference struct {
+	Row       int             `json:"row"`
+	Column    int             `json:"column"`
+	Before    string          `json:"before"`
+	After     string          `json:"after"`
+	Delta     *float64        `json:"delta,omitempty"`
+	Parameter BreachParameter `json:"parameter,omitempty"`
+}
+
+// Summarize lists the blocks of the b-file with the key used to match them by DiffBfiles.  Breach structures are
+// keyed by name when the SNET ID names have been read from a geometry hdf, otherwise by SNET ID.  Outlet time series
+// are keyed by name and gates by their structure and gate names.  Repeated keys are numbered in file order.
+func (bf *Bfile) Summarize() []BfileBlockSummary {
+	names := make(map[int]string, len(bf.SNETidToStructName))
+	for name, id := range bf.SNETidToStructName {
+		names[id] = name
+	}
+	summaries := make([]BfileBlockSummary, 0, len(bf.BfileBlocks))
+	counts := map[string]int{}
+	structure := ""
+	for _, block := range bf.BfileBlocks {
+		var key string
+		summary := BfileBlockSummary{Type: strings.TrimPrefix(fmt.Sprintf("%T", block), "*ras."), Block: block}
+		switch b := block.(type) {
+		case *BreachData:
+			name := b.Name
+			if name == "" {
+				name = names[b.SNetID]
+			}
+			if name != "" {
+				key = fmt.Sprintf("%s: %s", BREACH_DATA_HEADER, name)
+			} else {
+				key = fmt.Sprintf("%s: SNET ID %d", BREACH_DATA_HEADER, b.SNetID)
+			}
+			params, err := b.Parameters()
+			if err == nil {
+				summary.Parameters = &params
+			}
+		case *OutletTS:
+			key = TS_OUTFLOW_HEADER + b.Name
+		case *GateOpeningData:
+			structure = strings.TrimSpace(b.StructureName)
+			key = GATE_OPENING_DATA_HEADER + structure
+		case *GateOpening:
+			key = fmt.Sprintf("%s%s %s%s", GATE_OPENING_DATA_HEADER, structure, GATE_HEADER, b.Gate)
+		default:
+			key = strings.TrimSpace(block.Header())
+		}
+		counts[key]++
+		if counts[key] > 1 {
+			key = fmt.Sprintf("%s #%d", key, counts[key])
+		}
+		summary.Key = key
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// DiffBfiles compares two b-files block by block.  Blocks are matched by the keys from Summarize and the differences
+// are reported in the order of the blocks in before, followed by the blocks only found in after.
+func DiffBfiles(before *Bfile, after *Bfile) ([]BfileDifference, error) {
+	afterSummaries := after.Summarize()
+	afterBlocks := map[string]BfileBlockSummary{}
+	for _, summary := range afterSummaries {
+		afterBlocks[summary.Key] = summary
+	}
+	differences := []BfileDifference{}
+	matched := map[string]bool{}
+	for _, b := range before.Summarize() {
+		a, ok := afterBlocks[b.Key]
+		if !ok {
+			differences = append(differences, BfileDifference{Key: b.Key, Type: b.Type, Change: BLOCK_REMOVED})
+			continue
+		}
+		matched[b.Key] = true
+		cells, err := diffBlockCells(b.Block, a.Block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare %s: %s", b.Key, err)
+		}
+		if len(cells) > 0 {
+			differences = append(differences, BfileDifference{Key: b.Key, Type: a.Type, Change: BLOCK_MODIFIED, Cells: cells})
+		}
+	}
+	for _, a := range afterSummaries {
+		if !matched[a.Key] {
+			differences = append(differences, BfileDifference{Key: a.Key, Type: a.Type, Change: BLOCK_ADDED})
+		}
+	}
+	return differences, nil
+}
+
+// diffBlockCells compares the written rows of two blocks cell by cell
+func diffBlockCells(before BfileBlock, after BfileBlock) ([]BfileCellDifference, error) {
+	beforeRows, err := blockCells(before)
+	if err != nil {
+		return nil, err
+	}
+	afterRows, err := blockCells(after)
+	if err != nil {
+		return nil, err
+	}
+	parameters := map[[2]int]BreachParameter{}
+	if bd, ok := after.(*BreachData); ok {
+		for param := range breachCells {
+			row, cell, err := bd.cellIndex(param)
+			if err == nil {
+				parameters[[2]int{row, cell}] = param
+			}
+		}
+	}
+	differences := []BfileCellDifference{}
+	for row := 0; row < max(len(beforeRows), len(afterRows)); row++ {
+		beforeCells := cellsAt(beforeRows, row)
+		afterCells := cellsAt(afterRows, row)
+		for col := 0; col < max(len(beforeCells), len(afterCells)); col++ {
+			b := cellsAt(beforeCells, col)
+			a := cellsAt(afterCells, col)
+			if b == a {
+				continue
+			}
+			diff := BfileCellDifference{Row: row, Column: col, Before: b, After: a, Parameter: parameters[[2]int{row, col}]}
+			bval, berr := strconv.ParseFloat(strings.TrimSpace(b), 64)
+			aval, aerr := strconv.ParseFloat(strings.TrimSpace(a), 64)
+			if berr == nil && aerr == nil {
+				delta := aval - bval
+				diff.Delta = &delta
+			}
+			differences = append(differences, diff)
+		}
+	}
+	return differences, nil
+}
+
+// blockCells splits the written rows of a block into 8 character cells.  Rows that are not a whole number of cells,
+// such as headers, are kept as a single cell.
+func blockCells(block BfileBlock) ([][]string, error) {
+	if bd, ok := block.(*BreachData); ok {
+		return bd.BreachDataRows, nil
+	}
+	b, err := block.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	rows := strings.Split(string(bytes.TrimSuffix(b, []byte("\n"))), "\n")
+	cells := make([][]string, len(rows))
+	for idx, row := range rows {
+		rowCells, err := splitRowsIntoCells(row)
+		if err != nil || len(rowCells) == 0 {
+			rowCells = []string{row}
+		}
+		cells[idx] = rowCells
+	}
+	return cells, nil
+}
+
+func cellsAt[T any](values []T, idx int) T {
+	var none T
+	if idx < len(values) {
+		return values[idx]
+	}
+	return none
+}
@@ -0,0 +1,105 @@
+package ras
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDiffBfilesUnchanged(t *testing.T) {
+	diffs, err := DiffBfiles(multiBreach(t), multiBreach(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("expected no differences, found %v", diffs)
+	}
+}
+
+func TestDiffBfilesBreach(t *testing.T) {
+	before := multiBreach(t)
+	after := multiBreach(t)
+	err := after.AmmendBreachElevations("Saddle", 700.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := DiffBfiles(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 {
+		t.Fatalf("expected one difference, found %v", diffs)
+	}
+	diff := diffs[0]
+	if diff.Key != "Breach Data: Saddle" || diff.Change != BLOCK_MODIFIED || len(diff.Cells) != 1 {
+		t.Fatalf("unexpected difference %+v", diff)
+	}
+	cell := diff.Cells[0]
+	if cell.Parameter != BreachFailureElevation || cell.Delta == nil {
+		t.Fatalf("unexpected cell difference %+v", cell)
+	}
+	bd, _ := before.FindBreachData("Saddle")
+	elevation, _ := bd.GetFloat(BreachFailureElevation)
+	if math.Abs(*cell.Delta-(700.5-elevation)) > 0.001 {
+		t.Errorf("delta = %f; want %f", *cell.Delta, 700.5-elevation)
+	}
+
+	//without the geometry names structures are keyed by SNET ID
+	before.SNETidToStructName = nil
+	after.SNETidToStructName = nil
+	diffs, err = DiffBfiles(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].Key != "Breach Data: SNET ID 6" {
+		t.Errorf("unexpected differences %v", diffs)
+	}
+}
+
+func TestDiffBfilesAddedRemoved(t *testing.T) {
+	before := multiBreach(t)
+	after := multiBreach(t)
+	extra := findBlock[*ExtraCommands](t, after)
+	extra.AddCommands("SKIP_HDF_DSS")
+	removed := after.BfileBlocks[1]
+	after.BfileBlocks = append(after.BfileBlocks[:1], after.BfileBlocks[2:]...)
+	diffs, err := DiffBfiles(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := map[string]string{}
+	for _, diff := range diffs {
+		changes[diff.Key] = diff.Change
+	}
+	removedKey := strings.TrimSpace(removed.Header())
+	if changes[removedKey] != BLOCK_REMOVED {
+		t.Errorf("expected %s to be removed: %v", removedKey, changes)
+	}
+	if changes[EXTRA_COMMANDS_HEADER] != BLOCK_MODIFIED {
+		t.Errorf("expected extra commands to be modified: %v", changes)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	bf := multiBreach(t)
+	summaries := bf.Summarize()
+	if len(summaries) != len(bf.BfileBlocks) {
+		t.Fatalf("expected %d summaries, found %d", len(bf.BfileBlocks), len(summaries))
+	}
+	keys := map[string]BfileBlockSummary{}
+	for _, summary := range summaries {
+		if _, ok := keys[summary.Key]; ok {
+			t.Errorf("duplicate key %s", summary.Key)
+		}
+		keys[summary.Key] = summary
+	}
+	dam, ok := keys["Breach Data: Dam"]
+	if !ok || dam.Type != "BreachData" || dam.Parameters == nil || *dam.Parameters.FailureElevation != 676 {
+		t.Errorf("unexpected breach summary %+v", dam)
+	}
+	_, err := json.Marshal(summaries)
+	if err != nil {
+		t.Error(err)
+	}
+}
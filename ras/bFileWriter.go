@@ -0,0 +1,155 @@
+package ras
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rowWriter writes b-file rows, counting the bytes written and keeping the first error so blocks can write rows
+// without checking each one.
+type rowWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (rw *rowWriter) write(s string) {
+	if rw.err != nil {
+		return
+	}
+	n, err := io.WriteString(rw.w, s)
+	rw.n += int64(n)
+	rw.err = err
+}
+
+// row writes a newline terminated row
+func (rw *rowWriter) row(s string) {
+	rw.write(s)
+	rw.write("\n")
+}
+
+func (rw *rowWriter) rows(rows []string) {
+	for _, row := range rows {
+		rw.row(row)
+	}
+}
+
+// writeRows writes rows as newline terminated lines
+func writeRows(w io.Writer, rows []string) (int64, error) {
+	rw := rowWriter{w: w}
+	rw.rows(rows)
+	return rw.n, rw.err
+}
+
+// blockBytes writes a block to a byte slice
+func blockBytes(block io.WriterTo) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := block.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// lineEndingWriter restores the line endings of the file that was read.  Blocks always write "\n", which is written as
+// "\r\n" for windows files.  The newline ending the last row is held back until more is written, so it can be dropped
+// for files without a final newline.
+type lineEndingWriter struct {
+	w       *bufio.Writer
+	eol     string
+	pending bool
+	n       int64
+}
+
+func (lw *lineEndingWriter) Write(p []byte) (int, error) {
+	return lw.WriteString(string(p))
+}
+
+func (lw *lineEndingWriter) WriteString(s string) (int, error) {
+	written := len(s)
+	for len(s) > 0 {
+		if lw.pending {
+			if err := lw.writeString(lw.eol); err != nil {
+				return 0, err
+			}
+			lw.pending = false
+		}
+		idx := strings.IndexByte(s, '\n')
+		if idx < 0 {
+			return written, lw.writeString(s)
+		}
+		if err := lw.writeString(s[:idx]); err != nil {
+			return 0, err
+		}
+		lw.pending = true
+		s = s[idx+1:]
+	}
+	return written, nil
+}
+
+func (lw *lineEndingWriter) writeString(s string) error {
+	n, err := lw.w.WriteString(s)
+	lw.n += int64(n)
+	return err
+}
+
+// close writes the held back newline when the file ends with one and flushes the buffer
+func (lw *lineEndingWriter) close(finalNewline bool) error {
+	if lw.pending && finalNewline {
+		if err := lw.writeString(lw.eol); err != nil {
+			return err
+		}
+	}
+	lw.pending = false
+	return lw.w.Flush()
+}
+
+// WriteTo streams the b-file to w with the line endings of the file that was read
+func (bf Bfile) WriteTo(w io.Writer) (int64, error) {
+	lw := lineEndingWriter{w: bufio.NewWriter(w), eol: "\n"}
+	if bf.crlf {
+		lw.eol = "\r\n"
+	}
+	for _, block := range bf.BfileBlocks {
+		_, err := block.WriteTo(&lw)
+		if err != nil {
+			return lw.n, err
+		}
+	}
+	err := lw.close(!bf.noFinalNewline)
+	return lw.n, err
+}
+
+// WriteFile streams the b-file to a temporary file next to bfilePath and renames it over bfilePath, so a failed write
+// never leaves a partial b-file.
+func (bf Bfile) WriteFile(bfilePath string) error {
+	return WriteFileAtomic(bfilePath, func(w io.Writer) error {
+		_, err := bf.WriteTo(w)
+		return err
+	})
+}
+
+// WriteFileAtomic writes to a temporary file in the destination directory and renames it to dest, so a failed write
+// never leaves a partial file.  The file is synced before the rename and is only readable by its owner.
+func WriteFileAtomic(dest string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = write(tmp)
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
@@ -0,0 +1,205 @@
+package ras
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// failingBlock fails part way through writing
+type failingBlock struct {
+	DefaultBlock
+}
+
+func (fb *failingBlock) WriteTo(w io.Writer) (int64, error) {
+	n, _ := io.WriteString(w, "partial\n")
+	return int64(n), errors.New("write failed")
+}
+
+func TestWriteFile(t *testing.T) {
+	for _, fixture := range []string{Elk_At_Sutton_BFile, MULTI_BREACH_FILE} {
+		original, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, crlf := range []bool{false, true} {
+			data := original
+			if crlf {
+				data = bytes.ReplaceAll(original, []byte("\n"), []byte("\r\n"))
+			}
+			bfilePath := filepath.Join(t.TempDir(), filepath.Base(fixture))
+			err = os.WriteFile(bfilePath, data, 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bf, err := InitBFile(bfilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = bf.WriteFile(bfilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			written, err := os.ReadFile(bfilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(written, data) {
+				t.Errorf("%s (crlf %v) did not round trip through WriteFile", fixture, crlf)
+			}
+			entries, err := os.ReadDir(filepath.Dir(bfilePath))
+			if err != nil || len(entries) != 1 {
+				t.Errorf("expected only the b-file in the directory, found %v", entries)
+			}
+		}
+	}
+}
+
+func TestWriteFileFailureKeepsOriginal(t *testing.T) {
+	bfilePath := filepath.Join(t.TempDir(), "model.b01")
+	original := []byte("HEC-RAS 6.3.1 September 2022\n       1\n")
+	err := os.WriteFile(bfilePath, original, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf, err := InitBFile(bfilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf.BfileBlocks = append(bf.BfileBlocks, &failingBlock{})
+	if err = bf.WriteFile(bfilePath); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	written, err := os.ReadFile(bfilePath)
+	if err != nil || !bytes.Equal(written, original) {
+		t.Errorf("the original b-file was changed by a failed write: %q", written)
+	}
+	entries, err := os.ReadDir(filepath.Dir(bfilePath))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("the temporary file was not removed: %v", entries)
+	}
+}
+
+func TestLineEndingWriterNoFinalNewline(t *testing.T) {
+	bf := Bfile{}
+	err := bf.readBytes([]byte("Extra Commands\r\n       0\r\nRules\r\n       0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	n, err := bf.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "Extra Commands\r\n       0\r\nRules\r\n       0" || n != int64(buf.Len()) {
+		t.Errorf("unexpected output %q (%d bytes reported)", buf.String(), n)
+	}
+}
+
+// syntheticBfile builds a b-file with many long outlet time series, updating the flows so every series is rewritten
+func syntheticBfile(b *testing.B, series int, ordinates int) *Bfile {
+	var sb strings.Builder
+	sb.WriteString("HEC-RAS 6.3.1 September 2022\n       1       1       0       0\n")
+	for s := 0; s < series; s++ {
+		fmt.Fprintf(&sb, "%sSA Conn: Dam%d (Outlet TS: Outlet %d)\n%8d\n", TS_OUTFLOW_HEADER, s, s, ordinates)
+		for o := 0; o < ordinates; o++ {
+			if o != 0 && o%ORDINATES_PER_ROW == 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(convertFloatToBfileCellValue(float64(o)))
+			sb.WriteString(convertFloatToBfileCellValue(100))
+		}
+		sb.WriteString("\n" + EndOfFlow + "\n")
+	}
+	bf := Bfile{}
+	err := bf.readBytes([]byte(sb.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	flows := make([]float32, ordinates)
+	for idx := range flows {
+		flows[idx] = float32(idx % 1000)
+	}
+	for _, block := range bf.BfileBlocks {
+		if outts, ok := block.(*OutletTS); ok {
+			err = outts.UpdateFloatArray(flows)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return &bf
+}
+
+// legacyBlockBytes composes block bytes the way blocks were written before they could be streamed: outlet time series
+// are re-parsed to find changes and appended to a byte slice a cell at a time.
+func legacyBlockBytes(b *testing.B, block BfileBlock) []byte {
+	outts, ok := block.(*OutletTS)
+	if !ok {
+		blockBytes, err := block.ToBytes()
+		if err != nil {
+			b.Fatal(err)
+		}
+		return blockBytes
+	}
+	legacyRows := func(rows []string) []byte {
+		result := make([]byte, 0)
+		for _, row := range rows {
+			result = append(result, row...)
+			result = append(result, '\n')
+		}
+		return result
+	}
+	if original, err := InitOutletTS(outts.rows); err == nil && reflect.DeepEqual(original.TimeSeries, outts.TimeSeries) {
+		return legacyRows(outts.rows)
+	}
+	result := make([]byte, 0)
+	result = append(result, fmt.Sprintf("%v\n", TS_OUTFLOW_HEADER+outts.Name)...)
+	result = append(result, fmt.Sprintf("%*d\n", 8, outts.RowCount)...)
+	for idx, fd := range outts.TimeSeries {
+		if idx != 0 && idx%ORDINATES_PER_ROW == 0 {
+			result = append(result, "\n"...)
+		}
+		result = append(result, fmt.Sprintf("%s%s", convertFloatToBfileCellValue(float64(fd.Index)), convertFloatToBfileCellValue(float64(fd.Flow)))...)
+	}
+	if len(outts.TimeSeries) > 0 {
+		result = append(result, "\n"...)
+	}
+	return append(result, legacyRows(outts.ExtraLines)...)
+}
+
+// BenchmarkBfileWriteAppend composes the b-file by appending the bytes of each block, the way Write worked before
+// blocks could be streamed.
+func BenchmarkBfileWriteAppend(b *testing.B) {
+	bf := syntheticBfile(b, 50, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out := make([]byte, 0)
+		for _, block := range bf.BfileBlocks {
+			out = append(out, legacyBlockBytes(b, block)...)
+		}
+		err := os.WriteFile(filepath.Join(b.TempDir(), "append.b01"), out, 0600)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBfileWriteFile(b *testing.B) {
+	bf := syntheticBfile(b, 50, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := bf.WriteFile(filepath.Join(b.TempDir(), "stream.b01"))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package ras
 
 import (
+	"bytes"
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -154,3 +160,325 @@ func TestHDFread(t *testing.T) {
 	}
 	fmt.Print(bfile.SNETidToStructName)
 }
+
+const BFILE_FIXTURES string = "/workspaces/cc-ras-runner/testData/*.b01"
+
+func TestRoundTripFixtures(t *testing.T) {
+	files, err := filepath.Glob(BFILE_FIXTURES)
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no b-file fixtures found: %v", err)
+	}
+	for _, f := range files {
+		original, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bf, err := InitBFile(f)
+		if err != nil {
+			t.Errorf("%s: %s", f, err)
+			continue
+		}
+		b, err := bf.Write()
+		if err != nil {
+			t.Errorf("%s: %s", f, err)
+			continue
+		}
+		if !bytes.Equal(b, original) {
+			t.Errorf("%s did not round trip", filepath.Base(f))
+		}
+	}
+}
+
+func TestRoundTripLineEndings(t *testing.T) {
+	original, err := os.ReadFile(MULTI_BREACH_FILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"crlf":             bytes.ReplaceAll(original, []byte("\n"), []byte("\r\n")),
+		"no final newline": bytes.TrimSuffix(original, []byte("\n")),
+	} {
+		bf := Bfile{}
+		err = bf.readBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := bf.Write()
+		if err != nil || !bytes.Equal(b, data) {
+			t.Errorf("%s did not round trip", name)
+		}
+	}
+}
+
+// findBlock returns the first block of type T in the b-file
+func findBlock[T BfileBlock](t *testing.T, bf *Bfile) T {
+	for _, block := range bf.BfileBlocks {
+		if typed, ok := block.(T); ok {
+			return typed
+		}
+	}
+	var none T
+	t.Fatalf("no %T block found", none)
+	return none
+}
+
+// rewrite writes a b-file and parses the result
+func rewrite(t *testing.T, bf *Bfile) *Bfile {
+	b, err := bf.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := Bfile{}
+	err = updated.readBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &updated
+}
+
+func TestTypedBlocks(t *testing.T) {
+	bf, err := InitBFile(MULTI_BREACH_FILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateData := findBlock[*GateOpeningData](t, bf)
+	if gateData.StructureName != "Dam             " || strings.Join(gateData.Settings, ",") != "3,1,T,F,F" {
+		t.Errorf("unexpected gate opening data %+v", gateData)
+	}
+	gate := findBlock[*GateOpening](t, bf)
+	if gate.RowCount != 73 || gate.Openings[72] != (FlowData{72, 4}) {
+		t.Errorf("unexpected gate openings %+v", gate)
+	}
+	hydrograph := findBlock[*Hydrograph](t, bf)
+	if hydrograph.Kind != "Upstream Flow Hydrograph" || hydrograph.RowCount != 2 || len(hydrograph.ExtraLines) != 2 || hydrograph.ExtraLines[0] != EndOfFlow {
+		t.Errorf("unexpected hydrograph %+v", hydrograph)
+	}
+	initial := findBlock[*InitialConditions](t, bf)
+	if initial.UseRestartFile {
+		t.Error("expected the restart file to be off")
+	}
+	observed := findBlock[*ObservedData](t, bf)
+	if observed.Count != 0 {
+		t.Errorf("expected no observed boundaries, found %d", observed.Count)
+	}
+	extra := findBlock[*ExtraCommands](t, bf)
+	if len(extra.Commands) != 0 {
+		t.Errorf("expected no extra commands, found %v", extra.Commands)
+	}
+
+	//modify every typed block and read the changes back
+	err = hydrograph.UpdateFloatArray([]float32{200, 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gate.UpdateFloatArray(make([]float32, gate.RowCount))
+	if err != nil {
+		t.Fatal(err)
+	}
+	initial.SetUseRestartFile(true)
+	extra.AddCommands("SKIP_HDF_DSS")
+	updated := rewrite(t, bf)
+	if h := findBlock[*Hydrograph](t, updated); h.Ordinates[1] != (FlowData{8760, 300}) || h.ExtraLines[1] != hydrograph.ExtraLines[1] {
+		t.Errorf("hydrograph update was not written: %+v", h)
+	}
+	if g := findBlock[*GateOpening](t, updated); g.Openings[10].Flow != 0 {
+		t.Errorf("gate opening update was not written: %+v", g.Openings[10])
+	}
+	if !findBlock[*InitialConditions](t, updated).UseRestartFile {
+		t.Error("restart file flag was not written")
+	}
+	if ec := findBlock[*ExtraCommands](t, updated); len(ec.Commands) != 1 || ec.Commands[0] != "SKIP_HDF_DSS" {
+		t.Errorf("extra commands were not written: %v", ec.Commands)
+	}
+	if len(updated.BfileBlocks) != len(bf.BfileBlocks) {
+		t.Errorf("expected %d blocks after writing, found %d", len(bf.BfileBlocks), len(updated.BfileBlocks))
+	}
+}
+
+func TestExtraCommandRows(t *testing.T) {
+	data := []byte("Rules (number of rule sets, number of lookbacks, number of tables)\n       0       0       0\nExtra Commands\n1\nSKIP_HDF_DSS\n")
+	bf := Bfile{}
+	err := bf.readBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bf.BfileBlocks) != 2 {
+		t.Fatalf("expected 2 blocks, found %d", len(bf.BfileBlocks))
+	}
+	extra := findBlock[*ExtraCommands](t, &bf)
+	if len(extra.Commands) != 1 || extra.Commands[0] != "SKIP_HDF_DSS" {
+		t.Errorf("unexpected extra commands %v", extra.Commands)
+	}
+	//commands that are already there, or not there to remove, leave the parsed rows
+	extra.AddCommands("SKIP_HDF_DSS")
+	extra.RemoveCommands("NOT_A_COMMAND")
+	b, err := bf.Write()
+	if err != nil || !bytes.Equal(b, data) {
+		t.Errorf("extra commands did not round trip: %q", b)
+	}
+}
+
+func TestOutletTSResample(t *testing.T) {
+	bf, err := InitBFile(Elk_At_Sutton_BFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outts := findBlock[*OutletTS](t, bf)
+	last := float64(outts.TimeSeries[len(outts.TimeSeries)-1].Index)
+	// a linear source series sampled at a different interval from the b-file index
+	times := []float64{}
+	flows := []float64{}
+	for hour := 0.0; hour <= last+2; hour += 2 {
+		times = append(times, hour)
+		flows = append(flows, 10*hour)
+	}
+	err = outts.ResampleFlows(times, flows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := findBlock[*OutletTS](t, rewrite(t, bf))
+	if updated.RowCount != outts.RowCount || len(updated.TimeSeries) != len(outts.TimeSeries) {
+		t.Fatalf("resampling changed the row count from %d to %d", outts.RowCount, updated.RowCount)
+	}
+	for _, fd := range updated.TimeSeries {
+		want := 10 * fd.Index
+		if math.Abs(float64(fd.Flow-want)) > 0.5 {
+			t.Errorf("flow at hour %v = %v; want %v", fd.Index, fd.Flow, want)
+		}
+	}
+	if !reflect.DeepEqual(updated.ExtraLines, outts.ExtraLines) {
+		t.Errorf("extra lines changed: %q", updated.ExtraLines)
+	}
+	if err := outts.ResampleFlows([]float64{last + 1, last + 2}, []float64{0, 1}); err == nil {
+		t.Error("expected an error resampling a source that does not cover the index ordinates")
+	}
+}
+
+func TestOutletTSRewrite(t *testing.T) {
+	bf, err := InitBFile(Elk_At_Sutton_BFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outts := findBlock[*OutletTS](t, bf)
+	times := make([]float32, 12)
+	flows := make([]float32, 12)
+	for idx := range times {
+		times[idx] = float32(idx) * 6
+		flows[idx] = float32(idx) * 100
+	}
+	err = outts.UpdateTimeSeries(times, flows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := findBlock[*OutletTS](t, rewrite(t, bf))
+	if updated.RowCount != 12 || len(updated.TimeSeries) != 12 {
+		t.Fatalf("expected 12 ordinates, found %d", updated.RowCount)
+	}
+	if updated.TimeSeries[11] != (FlowData{66, 1100}) {
+		t.Errorf("unexpected last ordinate %v", updated.TimeSeries[11])
+	}
+	if !reflect.DeepEqual(updated.ExtraLines, outts.ExtraLines) {
+		t.Errorf("extra lines changed: %q", updated.ExtraLines)
+	}
+	if err := outts.UpdateTimeSeries([]float32{0, 0}, []float32{1, 2}); err == nil {
+		t.Error("expected an error for times that do not increase")
+	}
+}
+
+func TestUpdateExtraCommands(t *testing.T) {
+	//the existing empty block is updated
+	bf, err := InitBFile(MULTI_BREACH_FILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bf.UpdateExtraCommands([]string{SKIP_HDF_DSS, "OTHER_COMMAND", SKIP_HDF_DSS}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := rewrite(t, bf)
+	extra := findBlock[*ExtraCommands](t, updated)
+	if !reflect.DeepEqual(extra.Commands, []string{SKIP_HDF_DSS, "OTHER_COMMAND"}) {
+		t.Errorf("unexpected extra commands %v", extra.Commands)
+	}
+	if len(updated.BfileBlocks) != len(bf.BfileBlocks) {
+		t.Errorf("expected %d blocks, found %d", len(bf.BfileBlocks), len(updated.BfileBlocks))
+	}
+	err = updated.UpdateExtraCommands(nil, []string{SKIP_HDF_DSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra = findBlock[*ExtraCommands](t, rewrite(t, updated))
+	if !reflect.DeepEqual(extra.Commands, []string{"OTHER_COMMAND"}) {
+		t.Errorf("unexpected extra commands after removal %v", extra.Commands)
+	}
+
+	//a block is added when there is none
+	bf, err = InitBFile(Elk_At_Sutton_BFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := bf.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bf.UpdateExtraCommands([]string{SKIP_HDF_DSS}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := bf.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := string(before) + EXTRA_COMMANDS_HEADER + "\n       1\n" + SKIP_HDF_DSS + "\n"
+	if string(after) != want {
+		t.Errorf("unexpected b-file ending %q", after[len(before)-20:])
+	}
+}
+
+func TestUpdateExtraCommandsMergesBlocks(t *testing.T) {
+	data := []byte("Extra Commands\n       0\nExtra Commands\n1\nSKIP_HDF_DSS\n")
+	bf := Bfile{}
+	err := bf.readBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bf.UpdateExtraCommands([]string{SKIP_HDF_DSS}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := bf.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "Extra Commands\n       1\nSKIP_HDF_DSS\n" {
+		t.Errorf("repeated extra command blocks were not merged: %q", b)
+	}
+}
+
+func TestUnparsedBlocks(t *testing.T) {
+	data := []byte("Outlet TS - Dam\n       x\nExtra Commands\n       3\nSKIP_HDF_DSS\n")
+	bf := Bfile{}
+	err := bf.readBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := bf.Write()
+	if err != nil || !bytes.Equal(b, data) {
+		t.Errorf("unparsed blocks did not round trip: %q", b)
+	}
+	_, _, err = bf.FindOutletTS("Dam")
+	if err == nil || !strings.Contains(err.Error(), "unable to parse") {
+		t.Errorf("expected the outlet TS parse error, got %v", err)
+	}
+	_, _, err = bf.FindOutletTS("Spillway")
+	if err == nil || !strings.Contains(err.Error(), "could not find") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	err = bf.UpdateExtraCommands([]string{"OTHER_COMMAND"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unable to parse") {
+		t.Errorf("expected the extra commands parse error, got %v", err)
+	}
+	if len(bf.BfileBlocks) != 2 {
+		t.Errorf("expected the b-file to be unchanged, found %d blocks", len(bf.BfileBlocks))
+	}
+}
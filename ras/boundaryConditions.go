@@ -0,0 +1,241 @@
+package ras
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/usace-cloud-compute/go-hdf5"
+	"github.com/usace-cloud-compute/go-hdf5/util"
+)
+
+// BOUNDARY_CONDITIONS_PATH is the plan HDF group holding one sub group per boundary condition type.
+const BOUNDARY_CONDITIONS_PATH string = "/Event Conditions/Unsteady/Boundary Conditions"
+
+type BoundaryConditionType string
+
+const (
+	FlowHydrographBC       BoundaryConditionType = "flow hydrograph"
+	StageHydrographBC      BoundaryConditionType = "stage hydrograph"
+	LateralInflowBC        BoundaryConditionType = "lateral inflow"
+	UniformLateralInflowBC BoundaryConditionType = "uniform lateral inflow"
+	NormalDepthBC          BoundaryConditionType = "normal depth"
+	RatingCurveBC          BoundaryConditionType = "rating curve"
+	PrecipitationBC        BoundaryConditionType = "precipitation"
+)
+
+// bcGroupTypes maps the RAS boundary condition group names to a boundary condition type.
+// groups not in this list are reported using the lower case group name.
+var bcGroupTypes map[string]BoundaryConditionType = map[string]BoundaryConditionType{
+	"Flow Hydrographs":                   FlowHydrographBC,
+	"Stage Hydrographs":                  StageHydrographBC,
+	"Lateral Inflow Hydrographs":         LateralInflowBC,
+	"Uniform Lateral Inflow Hydrographs": UniformLateralInflowBC,
+	"Normal Depths":                      NormalDepthBC,
+	"Rating Curves":                      RatingCurveBC,
+	"Precipitation Hydrographs":          PrecipitationBC,
+}
+
+// location keys used by RAS when naming boundary condition datasets
+// e.g. "River: Fake River  Reach: Fake Reach  RS: 100" or "SA Conn: Dam (Outlet TS: Outlet)"
+var bcLocationKeys *regexp.Regexp = regexp.MustCompile(`(SA Conn|Outlet TS|River|Reach|RS|2D|SA|BCLine):\s*`)
+
+// BoundaryCondition describes a single boundary condition dataset in a plan HDF file.
+type BoundaryCondition struct {
+	Name      string                `json:"name"`
+	Type      BoundaryConditionType `json:"type"`
+	Location  map[string]string     `json:"location,omitempty"`
+	DataPath  string                `json:"datapath"`
+	Length    int                   `json:"length"`
+	StartTime *float32              `json:"start_time,omitempty"` //first time ordinate of the dataset
+	EndTime   *float32              `json:"end_time,omitempty"`   //last time ordinate of the dataset
+}
+
+type BoundaryConditionCatalog []BoundaryCondition
+
+// ReadBoundaryConditionCatalog opens a plan HDF file and lists every boundary condition in the file.
+func ReadBoundaryConditionCatalog(filePath string) (BoundaryConditionCatalog, error) {
+	f, err := util.OpenFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return BoundaryConditionsFromFile(f)
+}
+
+// BoundaryConditionsFromFile lists every boundary condition in an open plan HDF file.
+func BoundaryConditionsFromFile(f *hdf5.File) (BoundaryConditionCatalog, error) {
+	root, err := f.OpenGroup(BOUNDARY_CONDITIONS_PATH)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the boundary condition group '%s': %s", BOUNDARY_CONDITIONS_PATH, err)
+	}
+	defer root.Close()
+
+	catalog := BoundaryConditionCatalog{}
+	typeGroups, err := groupMembers(root, hdf5.H5G_GROUP)
+	if err != nil {
+		return nil, err
+	}
+	for _, typeGroup := range typeGroups {
+		bcType, ok := bcGroupTypes[typeGroup]
+		if !ok {
+			bcType = BoundaryConditionType(strings.ToLower(typeGroup))
+		}
+		groupPath := path.Join(BOUNDARY_CONDITIONS_PATH, typeGroup)
+		err = func() error {
+			group, err := f.OpenGroup(groupPath)
+			if err != nil {
+				return err
+			}
+			defer group.Close()
+			names, err := groupMembers(group, hdf5.H5G_DATASET)
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				bc, err := readBoundaryCondition(f, bcType, groupPath, name)
+				if err != nil {
+					return err
+				}
+				catalog = append(catalog, bc)
+			}
+			return nil
+		}()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read boundary conditions in '%s': %s", groupPath, err)
+		}
+	}
+	return catalog, nil
+}
+
+// Find returns the boundary condition matching name.  Exact names and data paths are checked first, followed by
+// case insensitive matches of the name or of a location value, such as the BCLine name.  A partial match is only
+// used when nothing matches exactly, so "Upstream Q" finds "Upstream Q" rather than also matching "Upstream Q2".
+// Case insensitive and partial matches must resolve to a single boundary condition.
+func (bcc BoundaryConditionCatalog) Find(name string) (BoundaryCondition, error) {
+	for _, bc := range bcc {
+		if bc.Name == name || bc.DataPath == name || strings.TrimPrefix(bc.DataPath, "/") == name {
+			return bc, nil
+		}
+	}
+	matches := []BoundaryCondition{}
+	for _, bc := range bcc {
+		if strings.EqualFold(bc.Name, name) || locationMatches(bc.Location, name) {
+			matches = append(matches, bc)
+		}
+	}
+	if len(matches) == 0 {
+		lname := strings.ToLower(name)
+		for _, bc := range bcc {
+			if strings.Contains(strings.ToLower(bc.Name), lname) {
+				matches = append(matches, bc)
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return BoundaryCondition{}, fmt.Errorf("boundary condition %s was not found", name)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, m := range matches {
+			names[i] = m.Name
+		}
+		return BoundaryCondition{}, fmt.Errorf("boundary condition %s is ambiguous, it matches: %s", name, strings.Join(names, "; "))
+	}
+}
+
+// locationMatches reports whether any location value is name, ignoring case
+func locationMatches(location map[string]string, name string) bool {
+	for _, v := range location {
+		if strings.EqualFold(v, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveBoundaryConditionPath returns the dataset path for a boundary condition name in a plan HDF file.
+func ResolveBoundaryConditionPath(filePath string, name string) (string, error) {
+	catalog, err := ReadBoundaryConditionCatalog(filePath)
+	if err != nil {
+		return "", err
+	}
+	bc, err := catalog.Find(name)
+	if err != nil {
+		return "", err
+	}
+	return bc.DataPath, nil
+}
+
+// ParseBoundaryConditionLocation splits a RAS boundary condition name into its location parts.
+func ParseBoundaryConditionLocation(name string) map[string]string {
+	location := make(map[string]string)
+	keys := bcLocationKeys.FindAllStringSubmatchIndex(name, -1)
+	for i, k := range keys {
+		end := len(name)
+		if i+1 < len(keys) {
+			end = keys[i+1][0]
+		}
+		value := strings.Trim(name[k[1]:end], " ()")
+		location[name[k[2]:k[3]]] = value
+	}
+	return location
+}
+
+func readBoundaryCondition(f *hdf5.File, bcType BoundaryConditionType, groupPath string, name string) (BoundaryCondition, error) {
+	datapath := path.Join(groupPath, name)
+	bc := BoundaryCondition{
+		Name:     name,
+		Type:     bcType,
+		Location: ParseBoundaryConditionLocation(name),
+		DataPath: datapath,
+	}
+	ds, err := util.NewHdfDataset(datapath, util.HdfReadOptions{
+		Dtype:        reflect.Float32,
+		File:         f,
+		ReadOnCreate: true,
+	})
+	if err != nil {
+		return bc, err
+	}
+	defer ds.Close()
+	bc.Length = ds.Rows()
+	//only time series datasets have time ordinates in the first column
+	if bc.Length > 0 && len(ds.Dims()) == 2 && ds.Cols() == 2 {
+		times := make([]float32, bc.Length)
+		err = ds.ReadColumn(0, &times)
+		if err != nil {
+			return bc, err
+		}
+		bc.StartTime = &times[0]
+		bc.EndTime = &times[len(times)-1]
+	}
+	return bc, nil
+}
+
+// groupMembers returns the names of the objects of the requested type in a group.
+func groupMembers(group *hdf5.Group, objType hdf5.GType) ([]string, error) {
+	numobj, err := group.NumObjects()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for i := uint(0); i < numobj; i++ {
+		t, err := group.ObjectTypeByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		if t == objType {
+			name, err := group.ObjectNameByIndex(i)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,55 @@
+package ras
+
+import (
+	"testing"
+)
+
+func TestParseBoundaryConditionLocation(t *testing.T) {
+	location := ParseBoundaryConditionLocation("River: Fake River  Reach: Fake Reach  RS: 100")
+	if location["River"] != "Fake River" || location["Reach"] != "Fake Reach" || location["RS"] != "100" {
+		t.Errorf("unexpected location %v", location)
+	}
+	location = ParseBoundaryConditionLocation("2D: Perimeter 1 BCLine: Upstream Q")
+	if location["2D"] != "Perimeter 1" || location["BCLine"] != "Upstream Q" {
+		t.Errorf("unexpected location %v", location)
+	}
+}
+
+func TestBoundaryConditionCatalogFind(t *testing.T) {
+	catalog := BoundaryConditionCatalog{
+		{Name: "2D: Perimeter 1 BCLine: Upstream Q", DataPath: BOUNDARY_CONDITIONS_PATH + "/Flow Hydrographs/2D: Perimeter 1 BCLine: Upstream Q",
+			Location: ParseBoundaryConditionLocation("2D: Perimeter 1 BCLine: Upstream Q")},
+		{Name: "2D: Perimeter 1 BCLine: Upstream Q2", DataPath: BOUNDARY_CONDITIONS_PATH + "/Flow Hydrographs/2D: Perimeter 1 BCLine: Upstream Q2",
+			Location: ParseBoundaryConditionLocation("2D: Perimeter 1 BCLine: Upstream Q2")},
+		{Name: "2D: Perimeter 1 BCLine: Downstream", DataPath: BOUNDARY_CONDITIONS_PATH + "/Normal Depths/2D: Perimeter 1 BCLine: Downstream",
+			Location: ParseBoundaryConditionLocation("2D: Perimeter 1 BCLine: Downstream")},
+	}
+	bc, err := catalog.Find("2D: Perimeter 1 BCLine: Upstream Q")
+	if err != nil || bc.Name != "2D: Perimeter 1 BCLine: Upstream Q" {
+		t.Errorf("exact match failed: %v", err)
+	}
+	bc, err = catalog.Find("2d: perimeter 1 bcline: upstream q")
+	if err != nil || bc.Name != "2D: Perimeter 1 BCLine: Upstream Q" {
+		t.Errorf("case insensitive match failed: %v", err)
+	}
+	bc, err = catalog.Find("Upstream Q")
+	if err != nil || bc.Name != "2D: Perimeter 1 BCLine: Upstream Q" {
+		t.Errorf("location match failed, found %s: %v", bc.Name, err)
+	}
+	bc, err = catalog.Find("Upstream Q2")
+	if err != nil || bc.Name != "2D: Perimeter 1 BCLine: Upstream Q2" {
+		t.Errorf("location match failed, found %s: %v", bc.Name, err)
+	}
+	bc, err = catalog.Find("downstream")
+	if err != nil || bc.Name != "2D: Perimeter 1 BCLine: Downstream" {
+		t.Errorf("partial match failed: %v", err)
+	}
+	_, err = catalog.Find("upstream")
+	if err == nil {
+		t.Error("expected an ambiguous match error")
+	}
+	_, err = catalog.Find("Lateral")
+	if err == nil {
+		t.Error("expected a not found error")
+	}
+}
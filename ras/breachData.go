@@ -2,7 +2,7 @@ package ras
 
 import (
 	"errors"
-	"fmt"
+	"io"
 	"strings"
 )
 
@@ -18,21 +18,39 @@ func (bd *BreachData) Header() string {
 	return "Breach Data" //@TODO: not sure how to handle this
 }
 
+// UpdateFloat sets the failure elevation
 func (bd *BreachData) UpdateFloat(value float64) error {
 	bd.BreachDataRows[bd.FailureElevationRowNum][0] = convertFloatToBfileCellValue(value)
 	return nil
 }
+
+// UpdateFloatArray replaces the breach fractions of the progression curve, keeping the existing time fractions
 func (bd *BreachData) UpdateFloatArray(values []float32) error {
-	return errors.New("updating float arrays not currently supported for breach data, could be used to update breach progression")
+	curve, err := bd.ProgressionCurve()
+	if err != nil {
+		return err
+	}
+	if len(curve) != len(values) {
+		return errors.New("breach progression values were not the same length as the target in the b file")
+	}
+	for idx := range curve {
+		curve[idx].Y = float64(values[idx])
+	}
+	return bd.UpdateParameters(BreachParameters{ProgressionCurve: curve})
 }
 func (bd *BreachData) ToBytes() ([]byte, error) {
-	bytes := make([]byte, 0)
+	return blockBytes(bd)
+}
+
+func (bd *BreachData) WriteTo(w io.Writer) (int64, error) {
+	rw := rowWriter{w: w}
 	for _, rowarray := range bd.BreachDataRows {
-		row := fmt.Sprintf("%v\n", strings.Join(rowarray, ""))
-		bytes = append(bytes, row...)
+		for _, cell := range rowarray {
+			rw.write(cell)
+		}
+		rw.write("\n")
 	}
-
-	return bytes, nil
+	return rw.n, rw.err
 }
 func InitBreachData(rows []string) ([]BfileBlock, error) {
 	headerDefaultBlock := DefaultBlock{
@@ -133,7 +151,8 @@ func SplitIntoBreachDataArray(rows [][]string) ([]BfileBlock, error) {
 		if err != nil {
 			return breachdatas, err
 		}
-		specificRows := rows[structureFirstRowIndex:(structureFirstRowIndex + numRowsInStructureBreachData)]
+		//the slice end is limited to the rows read so truncated breach data is not padded with empty rows
+		specificRows := rows[structureFirstRowIndex:min(structureFirstRowIndex+numRowsInStructureBreachData, len(rows))]
 		cellValue := specificRows[0][0] //Always the first cell for a set of structure breach data.
 		sNetID, err := getIntFromCellValue(cellValue)
 		if err != nil {
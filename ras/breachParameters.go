@@ -0,0 +1,407 @@
+package ras
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Breach parameters are located by row and cell within the breach data of a structure.  Rows are counted from the
+// first row of the structure (the row starting with the SNET ID).
+//
+//	row 0:                   SNET ID, center station, formation time (hrs), ..., weir coefficient, breach method, ..., mass wasting
+//	row 1:                   mass wasting data, only when mass wasting is on
+//	failure row - 1:         bottom width, bottom elevation, , left side slope, right side slope, ...
+//	failure row:             failure elevation, failure time (hrs), threshold duration (hrs), threshold elevation,
+//	                         elevation, duration and time trigger flags, ...
+//	failure row + 1:         number of progression ordinates followed by the (time fraction, breach fraction) pairs
+//	after the progression:   number of downcutting ordinates followed by the pairs, simplified physical method only
+type BreachParameter string
+
+const (
+	BreachCenterStation      BreachParameter = "center_station"
+	BreachFormationTime      BreachParameter = "formation_time"
+	BreachWeirCoefficient    BreachParameter = "weir_coefficient"
+	BreachMethod             BreachParameter = "breach_method"
+	BreachBottomWidth        BreachParameter = "bottom_width"
+	BreachBottomElevation    BreachParameter = "bottom_elevation"
+	BreachLeftSideSlope      BreachParameter = "left_side_slope"
+	BreachRightSideSlope     BreachParameter = "right_side_slope"
+	BreachFailureElevation   BreachParameter = "failure_elevation"
+	BreachFailureTime        BreachParameter = "failure_time"
+	BreachThresholdDuration  BreachParameter = "threshold_duration"
+	BreachThresholdElevation BreachParameter = "threshold_elevation"
+)
+
+// BreachTrigger is the condition that starts a breach.  Elevation triggers fail at the failure elevation, duration
+// triggers fail once the water surface has been above the threshold elevation for the threshold duration or reaches the
+// failure elevation, and time triggers fail at the failure time in hours from the simulation start.
+type BreachTrigger string
+
+const (
+	BREACH_TRIGGER_ELEVATION BreachTrigger = "elevation"
+	BREACH_TRIGGER_DURATION  BreachTrigger = "duration"
+	BREACH_TRIGGER_TIME      BreachTrigger = "time"
+)
+
+// the trigger flags are in consecutive cells of the failure elevation row, in this order
+var breachTriggers []BreachTrigger = []BreachTrigger{BREACH_TRIGGER_ELEVATION, BREACH_TRIGGER_DURATION, BREACH_TRIGGER_TIME}
+
+const breachTriggerCell int = 4
+
+// breach method values in the breach method cell
+const (
+	BREACH_METHOD_USER_ENTERED        int = 0
+	BREACH_METHOD_SIMPLIFIED_PHYSICAL int = 1
+)
+
+const breachMethodCell int = 9
+
+// rows relative to the first structure row, or to the failure elevation row, and the cell of each parameter
+type breachCell struct {
+	fromFailureRow bool
+	row            int
+	cell           int
+}
+
+var breachCells map[BreachParameter]breachCell = map[BreachParameter]breachCell{
+	BreachCenterStation:      {false, 0, 1},
+	BreachFormationTime:      {false, 0, 2},
+	BreachWeirCoefficient:    {false, 0, 8},
+	BreachMethod:             {false, 0, breachMethodCell},
+	BreachBottomWidth:        {true, -1, 0},
+	BreachBottomElevation:    {true, -1, 1},
+	BreachLeftSideSlope:      {true, -1, 3},
+	BreachRightSideSlope:     {true, -1, 4},
+	BreachFailureElevation:   {true, 0, 0},
+	BreachFailureTime:        {true, 0, 1},
+	BreachThresholdDuration:  {true, 0, 2},
+	BreachThresholdElevation: {true, 0, 3},
+}
+
+// CurveOrdinate is a point on a breach progression or downcutting curve
+type CurveOrdinate struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// BreachParameters are the named breach parameters of a structure.  Nil values and empty curves are left unchanged
+// by UpdateParameters.
+type BreachParameters struct {
+	CenterStation      *float64        `json:"center_station,omitempty"`
+	FormationTime      *float64        `json:"formation_time,omitempty"`
+	WeirCoefficient    *float64        `json:"weir_coefficient,omitempty"`
+	BreachMethod       *int            `json:"breach_method,omitempty"`
+	BottomWidth        *float64        `json:"bottom_width,omitempty"`
+	BottomElevation    *float64        `json:"bottom_elevation,omitempty"`
+	LeftSideSlope      *float64        `json:"left_side_slope,omitempty"`
+	RightSideSlope     *float64        `json:"right_side_slope,omitempty"`
+	FailureElevation   *float64        `json:"failure_elevation,omitempty"`
+	Trigger            *BreachTrigger  `json:"trigger,omitempty"`
+	FailureTime        *float64        `json:"failure_time,omitempty"`
+	ThresholdDuration  *float64        `json:"threshold_duration,omitempty"`
+	ThresholdElevation *float64        `json:"threshold_elevation,omitempty"`
+	ProgressionCurve   []CurveOrdinate `json:"progression_curve,omitempty"`
+	DowncuttingCurve   []CurveOrdinate `json:"downcutting_curve,omitempty"`
+}
+
+func (bd *BreachData) cellIndex(param BreachParameter) (int, int, error) {
+	loc, ok := breachCells[param]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown breach parameter %s", param)
+	}
+	row := loc.row
+	if loc.fromFailureRow {
+		row += bd.FailureElevationRowNum
+	}
+	if row >= len(bd.BreachDataRows) || loc.cell >= len(bd.BreachDataRows[row]) {
+		return 0, 0, fmt.Errorf("breach data for SNET ID %d does not include %s", bd.SNetID, param)
+	}
+	return row, loc.cell, nil
+}
+
+// GetFloat reads a breach parameter
+func (bd *BreachData) GetFloat(param BreachParameter) (float64, error) {
+	row, cell, err := bd.cellIndex(param)
+	if err != nil {
+		return 0, err
+	}
+	val, err := strconv.ParseFloat(strings.TrimSpace(bd.BreachDataRows[row][cell]), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %s", param, bd.BreachDataRows[row][cell], err)
+	}
+	return val, nil
+}
+
+// SetFloat writes a breach parameter.  The breach method can not be changed with SetFloat when the change adds or
+// removes the downcutting curve, use UpdateParameters instead.
+func (bd *BreachData) SetFloat(param BreachParameter, value float64) error {
+	row, cell, err := bd.cellIndex(param)
+	if err != nil {
+		return err
+	}
+	if param == BreachMethod {
+		current, err := bd.GetFloat(BreachMethod)
+		if err != nil {
+			return err
+		}
+		if (int(current) == BREACH_METHOD_SIMPLIFIED_PHYSICAL) != (int(value) == BREACH_METHOD_SIMPLIFIED_PHYSICAL) {
+			return errors.New("changing to or from the simplified physical breach method changes the downcutting curve rows, use UpdateParameters")
+		}
+		bd.BreachDataRows[row][cell] = fmt.Sprintf("%8d", int(value))
+		return nil
+	}
+	bd.BreachDataRows[row][cell] = convertFloatToBfileCellValue(value)
+	return nil
+}
+
+// Parameters reads the named breach parameters
+func (bd *BreachData) Parameters() (BreachParameters, error) {
+	params := BreachParameters{}
+	floats := map[BreachParameter]**float64{
+		BreachCenterStation:      &params.CenterStation,
+		BreachFormationTime:      &params.FormationTime,
+		BreachWeirCoefficient:    &params.WeirCoefficient,
+		BreachBottomWidth:        &params.BottomWidth,
+		BreachBottomElevation:    &params.BottomElevation,
+		BreachLeftSideSlope:      &params.LeftSideSlope,
+		BreachRightSideSlope:     &params.RightSideSlope,
+		BreachFailureElevation:   &params.FailureElevation,
+		BreachFailureTime:        &params.FailureTime,
+		BreachThresholdDuration:  &params.ThresholdDuration,
+		BreachThresholdElevation: &params.ThresholdElevation,
+	}
+	for param, field := range floats {
+		val, err := bd.GetFloat(param)
+		if err != nil {
+			return params, err
+		}
+		*field = &val
+	}
+	method, err := bd.GetFloat(BreachMethod)
+	if err != nil {
+		return params, err
+	}
+	methodInt := int(method)
+	params.BreachMethod = &methodInt
+	trigger, err := bd.Trigger()
+	if err != nil {
+		return params, err
+	}
+	params.Trigger = &trigger
+	params.ProgressionCurve, err = bd.ProgressionCurve()
+	if err != nil {
+		return params, err
+	}
+	if methodInt == BREACH_METHOD_SIMPLIFIED_PHYSICAL {
+		params.DowncuttingCurve, err = bd.DowncuttingCurve()
+	}
+	return params, err
+}
+
+// UpdateParameters writes the parameters that are set.  Changing to the simplified physical method requires a
+// downcutting curve and changing from it removes the downcutting curve.  Parameters are checked before any are written.
+func (bd *BreachData) UpdateParameters(params BreachParameters) error {
+	current, err := bd.GetFloat(BreachMethod)
+	if err != nil {
+		return err
+	}
+	method := int(current)
+	if params.BreachMethod != nil {
+		method = *params.BreachMethod
+	}
+	updateCurves := params.ProgressionCurve != nil || params.DowncuttingCurve != nil || method != int(current)
+	progression := params.ProgressionCurve
+	var downcutting []CurveOrdinate
+	if updateCurves {
+		if progression == nil {
+			progression, err = bd.ProgressionCurve()
+			if err != nil {
+				return err
+			}
+		}
+		if method == BREACH_METHOD_SIMPLIFIED_PHYSICAL {
+			downcutting = params.DowncuttingCurve
+			if downcutting == nil && int(current) == BREACH_METHOD_SIMPLIFIED_PHYSICAL {
+				downcutting, err = bd.DowncuttingCurve()
+				if err != nil {
+					return err
+				}
+			}
+			if len(downcutting) == 0 {
+				return fmt.Errorf("the simplified physical breach method for SNET ID %d requires a downcutting curve", bd.SNetID)
+			}
+		} else if params.DowncuttingCurve != nil {
+			return errors.New("a downcutting curve is only used by the simplified physical breach method")
+		}
+		if len(progression) == 0 {
+			return fmt.Errorf("breach data for SNET ID %d requires a progression curve", bd.SNetID)
+		}
+	}
+
+	floats := map[BreachParameter]*float64{
+		BreachCenterStation:      params.CenterStation,
+		BreachFormationTime:      params.FormationTime,
+		BreachWeirCoefficient:    params.WeirCoefficient,
+		BreachBottomWidth:        params.BottomWidth,
+		BreachBottomElevation:    params.BottomElevation,
+		BreachLeftSideSlope:      params.LeftSideSlope,
+		BreachRightSideSlope:     params.RightSideSlope,
+		BreachFailureElevation:   params.FailureElevation,
+		BreachFailureTime:        params.FailureTime,
+		BreachThresholdDuration:  params.ThresholdDuration,
+		BreachThresholdElevation: params.ThresholdElevation,
+	}
+	for param, val := range floats {
+		if val == nil {
+			continue
+		}
+		if _, _, err := bd.cellIndex(param); err != nil {
+			return err
+		}
+	}
+	if params.Trigger != nil {
+		if _, err := bd.triggerCells(*params.Trigger); err != nil {
+			return err
+		}
+	}
+	for param, val := range floats {
+		if val != nil {
+			bd.SetFloat(param, *val) //cells were checked above
+		}
+	}
+	if params.Trigger != nil {
+		bd.SetTrigger(*params.Trigger)
+	}
+	if !updateCurves {
+		return nil
+	}
+	row, cell, err := bd.cellIndex(BreachMethod)
+	if err != nil {
+		return err
+	}
+	bd.BreachDataRows[row][cell] = fmt.Sprintf("%8d", method)
+	bd.setCurves(progression, downcutting)
+	return nil
+}
+
+// triggerCells returns the failure elevation row and checks that the trigger is known and the row has the trigger flags
+func (bd *BreachData) triggerCells(trigger BreachTrigger) ([]string, error) {
+	if !slices.Contains(breachTriggers, trigger) {
+		return nil, fmt.Errorf("unknown breach trigger %q", trigger)
+	}
+	row := bd.FailureElevationRowNum
+	if row >= len(bd.BreachDataRows) || len(bd.BreachDataRows[row]) < breachTriggerCell+len(breachTriggers) {
+		return nil, fmt.Errorf("breach data for SNET ID %d does not include the breach trigger", bd.SNetID)
+	}
+	return bd.BreachDataRows[row], nil
+}
+
+// Trigger reads the breach trigger from the trigger flags
+func (bd *BreachData) Trigger() (BreachTrigger, error) {
+	cells, err := bd.triggerCells(BREACH_TRIGGER_ELEVATION)
+	if err != nil {
+		return "", err
+	}
+	for idx, trigger := range breachTriggers {
+		if strings.TrimSpace(cells[breachTriggerCell+idx]) == "T" {
+			return trigger, nil
+		}
+	}
+	return "", fmt.Errorf("breach data for SNET ID %d does not set a breach trigger", bd.SNetID)
+}
+
+// SetTrigger sets the flag of the trigger and clears the others
+func (bd *BreachData) SetTrigger(trigger BreachTrigger) error {
+	cells, err := bd.triggerCells(trigger)
+	if err != nil {
+		return err
+	}
+	for idx, t := range breachTriggers {
+		flag := "F"
+		if t == trigger {
+			flag = "T"
+		}
+		cells[breachTriggerCell+idx] = fmt.Sprintf("%8s", flag)
+	}
+	return nil
+}
+
+// ProgressionCurve reads the breach progression curve, (time fraction, breach fraction) ordinates
+func (bd *BreachData) ProgressionCurve() ([]CurveOrdinate, error) {
+	curve, _, err := bd.readCurve(bd.FailureElevationRowNum + 1)
+	return curve, err
+}
+
+// DowncuttingCurve reads the downcutting curve of the simplified physical breach method
+func (bd *BreachData) DowncuttingCurve() ([]CurveOrdinate, error) {
+	_, next, err := bd.readCurve(bd.FailureElevationRowNum + 1)
+	if err != nil {
+		return nil, err
+	}
+	curve, _, err := bd.readCurve(next)
+	return curve, err
+}
+
+// readCurve reads the ordinate count at countRow and the ordinate rows after it.  The index of the row after the
+// curve is returned.
+func (bd *BreachData) readCurve(countRow int) ([]CurveOrdinate, int, error) {
+	if countRow >= len(bd.BreachDataRows) || len(bd.BreachDataRows[countRow]) == 0 {
+		return nil, 0, fmt.Errorf("breach data for SNET ID %d is missing a curve", bd.SNetID)
+	}
+	count, err := getIntFromCellValue(bd.BreachDataRows[countRow][0])
+	if err != nil {
+		return nil, 0, err
+	}
+	cells := []string{}
+	row := countRow + 1
+	for len(cells) < count*2 && row < len(bd.BreachDataRows) {
+		cells = append(cells, bd.BreachDataRows[row]...)
+		row++
+	}
+	if len(cells) < count*2 {
+		return nil, 0, fmt.Errorf("breach data for SNET ID %d has %d of %d curve ordinates", bd.SNetID, len(cells)/2, count)
+	}
+	curve := make([]CurveOrdinate, count)
+	for i := range curve {
+		x, err := strconv.ParseFloat(strings.TrimSpace(cells[i*2]), 64)
+		if err != nil {
+			return nil, 0, err
+		}
+		y, err := strconv.ParseFloat(strings.TrimSpace(cells[i*2+1]), 64)
+		if err != nil {
+			return nil, 0, err
+		}
+		curve[i] = CurveOrdinate{x, y}
+	}
+	return curve, row, nil
+}
+
+// setCurves replaces the rows after the failure elevation row with the progression curve and, for the simplified
+// physical method, the downcutting curve.
+func (bd *BreachData) setCurves(progression []CurveOrdinate, downcutting []CurveOrdinate) {
+	rows := make([][]string, 0, len(bd.BreachDataRows))
+	rows = append(rows, bd.BreachDataRows[:bd.FailureElevationRowNum+1]...)
+	rows = append(rows, curveRows(progression)...)
+	if len(downcutting) > 0 {
+		rows = append(rows, curveRows(downcutting)...)
+	}
+	bd.BreachDataRows = rows
+	bd.NumRows = len(rows)
+}
+
+// curveRows writes the ordinate count row followed by five ordinate pairs to a row
+func curveRows(curve []CurveOrdinate) [][]string {
+	rows := [][]string{{fmt.Sprintf("%8d", len(curve))}}
+	row := []string{}
+	for i, ord := range curve {
+		row = append(row, convertFloatToBfileCellValue(ord.X), convertFloatToBfileCellValue(ord.Y))
+		if (i+1)%ORDINATES_PER_ROW == 0 || i == len(curve)-1 {
+			rows = append(rows, row)
+			row = []string{}
+		}
+	}
+	return rows
+}
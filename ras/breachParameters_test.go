@@ -0,0 +1,216 @@
+package ras
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func multiBreach(t *testing.T) *Bfile {
+	bf, err := InitBFile(MULTI_BREACH_FILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf.SNETidToStructName = map[string]int{"Dam": 3, "Saddle": 6}
+	return bf
+}
+
+func TestBreachParameters(t *testing.T) {
+	bf := multiBreach(t)
+	bd, err := bf.FindBreachData("Dam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := bd.Parameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string]float64{
+		"center station":    *params.CenterStation,
+		"formation time":    *params.FormationTime,
+		"weir coefficient":  *params.WeirCoefficient,
+		"bottom width":      *params.BottomWidth,
+		"bottom elevation":  *params.BottomElevation,
+		"left side slope":   *params.LeftSideSlope,
+		"right side slope":  *params.RightSideSlope,
+		"failure elevation": *params.FailureElevation,
+	} {
+		want := map[string]float64{
+			"center station": 5700, "formation time": .5, "weir coefficient": 2.6, "bottom width": 200,
+			"bottom elevation": 595, "left side slope": .5, "right side slope": .5, "failure elevation": 676,
+		}[name]
+		if got != want {
+			t.Errorf("%s = %f, want %f", name, got, want)
+		}
+	}
+	if *params.BreachMethod != BREACH_METHOD_USER_ENTERED || params.DowncuttingCurve != nil {
+		t.Errorf("unexpected breach method %d", *params.BreachMethod)
+	}
+	if len(params.ProgressionCurve) != 21 || params.ProgressionCurve[20] != (CurveOrdinate{1, 1}) || params.ProgressionCurve[1] != (CurveOrdinate{.05, .006}) {
+		t.Errorf("unexpected progression curve %v", params.ProgressionCurve)
+	}
+}
+
+func TestUpdateBreachParameters(t *testing.T) {
+	bf := multiBreach(t)
+	original, err := bf.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	width := 350.0
+	slope := 1.5
+	err = bf.AmmendBreachParameters("Dam", BreachParameters{
+		BottomWidth:      &width,
+		LeftSideSlope:    &slope,
+		ProgressionCurve: []CurveOrdinate{{0, 0}, {.5, .25}, {1, 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := rewrite(t, bf)
+	updated.SNETidToStructName = bf.SNETidToStructName
+	bd, err := updated.FindBreachData("Dam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := bd.Parameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *params.BottomWidth != 350 || *params.LeftSideSlope != 1.5 || *params.RightSideSlope != .5 || len(params.ProgressionCurve) != 3 || params.ProgressionCurve[1] != (CurveOrdinate{.5, .25}) {
+		t.Errorf("unexpected parameters after the update %+v", params)
+	}
+	saddle, err := updated.FindBreachData("Saddle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	curve, err := saddle.ProgressionCurve()
+	if err != nil || len(curve) != 2 {
+		t.Errorf("the second structure changed: %v %v", curve, err)
+	}
+	b, err := updated.Write()
+	if err != nil || bytes.Equal(b, original) {
+		t.Error("expected the updated b-file to differ from the original")
+	}
+}
+
+func TestUpdateBreachMethod(t *testing.T) {
+	bf := multiBreach(t)
+	original, err := bf.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	method := BREACH_METHOD_SIMPLIFIED_PHYSICAL
+	width := 999.0
+	err = bf.AmmendBreachParameters("Saddle", BreachParameters{BreachMethod: &method, BottomWidth: &width})
+	if err == nil {
+		t.Fatal("expected an error for the simplified physical method without a downcutting curve")
+	}
+	b, err := bf.Write()
+	if err != nil || !bytes.Equal(b, original) {
+		t.Fatal("a rejected update should not change the b-file")
+	}
+
+	downcutting := []CurveOrdinate{{0, 0}, {2, 1}, {4, 3}, {6, 5}, {8, 7}, {10, 9}}
+	err = bf.AmmendBreachParameters("Saddle", BreachParameters{BreachMethod: &method, DowncuttingCurve: downcutting})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := rewrite(t, bf)
+	updated.SNETidToStructName = bf.SNETidToStructName
+	saddle, err := updated.FindBreachData("Saddle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := saddle.Parameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *params.BreachMethod != BREACH_METHOD_SIMPLIFIED_PHYSICAL || len(params.DowncuttingCurve) != 6 || params.DowncuttingCurve[5] != (CurveOrdinate{10, 9}) {
+		t.Errorf("unexpected parameters after changing the breach method %+v", params)
+	}
+
+	//switching back removes the downcutting curve
+	method = BREACH_METHOD_USER_ENTERED
+	err = saddle.UpdateParameters(BreachParameters{BreachMethod: &method})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err = saddle.Parameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	originalSaddle, err := multiBreach(t).FindBreachData("Saddle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	originalParams, err := originalSaddle.Parameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saddle.NumRows != originalSaddle.NumRows || !reflect.DeepEqual(params, originalParams) {
+		t.Errorf("unexpected breach data after switching back to the user entered method %+v", params)
+	}
+}
+
+func TestUpdateBreachProgression(t *testing.T) {
+	bf := multiBreach(t)
+	bd, err := bf.FindBreachData("Saddle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bd.UpdateFloatArray([]float32{0, .75})
+	if err != nil {
+		t.Fatal(err)
+	}
+	curve, err := bd.ProgressionCurve()
+	if err != nil || curve[1] != (CurveOrdinate{1, .75}) {
+		t.Errorf("unexpected progression curve %v %v", curve, err)
+	}
+	if err = bd.UpdateFloatArray([]float32{0}); err == nil {
+		t.Error("expected an error for the wrong number of values")
+	}
+}
+
+func TestBreachTrigger(t *testing.T) {
+	bf, err := InitBFile(ONE_BREACH_FILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var breach *BreachData
+	for _, block := range bf.BfileBlocks {
+		if b, ok := block.(*BreachData); ok {
+			breach = b
+		}
+	}
+	trigger, err := breach.Trigger()
+	if err != nil || trigger != BREACH_TRIGGER_TIME {
+		t.Errorf("unexpected trigger %q %v", trigger, err)
+	}
+	for param, want := range map[BreachParameter]float64{BreachFailureTime: 9.999999, BreachThresholdDuration: 5, BreachThresholdElevation: 545} {
+		if got, err := breach.GetFloat(param); err != nil || got != want {
+			t.Errorf("%s = %f, want %f %v", param, got, want, err)
+		}
+	}
+
+	trigger = BREACH_TRIGGER_DURATION
+	duration := 12.0
+	threshold := 540.0
+	err = breach.UpdateParameters(BreachParameters{Trigger: &trigger, ThresholdDuration: &duration, ThresholdElevation: &threshold})
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := breach.BreachDataRows[breach.FailureElevationRowNum]
+	want := []string{"     551", "9.999999", "12.00000", "540.0000", "       F", "       T", "       F"}
+	if !reflect.DeepEqual(row[:7], want) {
+		t.Errorf("failure row = %q, want %q", row[:7], want)
+	}
+
+	unknown := BreachTrigger("seismic")
+	if err = breach.UpdateParameters(BreachParameters{Trigger: &unknown, ThresholdDuration: &duration}); err == nil {
+		t.Error("expected an error for an unknown trigger")
+	}
+	if got, _ := breach.GetFloat(BreachThresholdDuration); got != 12 {
+		t.Errorf("a failed update changed the threshold duration to %f", got)
+	}
+}
@@ -0,0 +1,141 @@
+package ras
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// SKIP_HDF_DSS instructs the linux engine not to export results to DSS
+const SKIP_HDF_DSS string = "SKIP_HDF_DSS"
+
+// ExtraCommands is the block of additional engine commands at the end of the b-file
+//
+//	Extra Commands
+//	       1
+//	SKIP_HDF_DSS
+//
+// Parsed blocks are written as the rows they were parsed from until the commands are changed by AddCommands or
+// RemoveCommands.
+type ExtraCommands struct {
+	Commands   []string
+	ExtraLines []string
+	rows       []string //rows the block was parsed from
+	changed    bool     //the commands were updated since they were parsed
+}
+
+func InitExtraCommands(rows []string) (*ExtraCommands, error) {
+	if len(rows) < 2 {
+		return nil, errors.New("missing the extra command count")
+	}
+	count, err := getIntFromCellValue(rows[1])
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2+count {
+		return nil, fmt.Errorf("expected %d extra commands, found %d", count, len(rows)-2)
+	}
+	commands := make([]string, count)
+	copy(commands, rows[2:2+count])
+	extraLines := make([]string, len(rows)-2-count)
+	copy(extraLines, rows[2+count:])
+	return &ExtraCommands{
+		Commands:   commands,
+		ExtraLines: extraLines,
+		rows:       rows,
+	}, nil
+}
+
+func (ec *ExtraCommands) Header() string {
+	return EXTRA_COMMANDS_HEADER
+}
+
+func (ec *ExtraCommands) UpdateFloat(value float64) error {
+	return errors.New("cannot update float on extra commands")
+}
+
+func (ec *ExtraCommands) UpdateFloatArray(values []float32) error {
+	return errors.New("cannot update float array on extra commands")
+}
+
+func (ec *ExtraCommands) ToBytes() ([]byte, error) {
+	return blockBytes(ec)
+}
+
+func (ec *ExtraCommands) WriteTo(w io.Writer) (int64, error) {
+	if !ec.changed && ec.rows != nil {
+		return writeRows(w, ec.rows)
+	}
+	rw := rowWriter{w: w}
+	rw.row(EXTRA_COMMANDS_HEADER)
+	rw.row(fmt.Sprintf("%8d", len(ec.Commands)))
+	rw.rows(ec.Commands)
+	rw.rows(ec.ExtraLines)
+	return rw.n, rw.err
+}
+
+// AddCommands appends the commands that are not already in the block
+func (ec *ExtraCommands) AddCommands(commands ...string) {
+	for _, command := range commands {
+		command = strings.TrimSpace(command)
+		if command != "" && !ec.HasCommand(command) {
+			ec.Commands = append(ec.Commands, command)
+			ec.changed = true
+		}
+	}
+}
+
+// RemoveCommands removes every occurrence of the commands from the block
+func (ec *ExtraCommands) RemoveCommands(commands ...string) {
+	count := len(ec.Commands)
+	ec.Commands = slices.DeleteFunc(ec.Commands, func(existing string) bool {
+		return slices.ContainsFunc(commands, func(command string) bool {
+			return strings.TrimSpace(command) == strings.TrimSpace(existing)
+		})
+	})
+	ec.changed = ec.changed || len(ec.Commands) != count
+}
+
+func (ec *ExtraCommands) HasCommand(command string) bool {
+	return slices.ContainsFunc(ec.Commands, func(existing string) bool {
+		return strings.TrimSpace(existing) == strings.TrimSpace(command)
+	})
+}
+
+// UpdateExtraCommands adds and then removes engine extra commands, keeping the command count correct.  An Extra
+// Commands block is added to the end of the b-file when there is none.  Repeated Extra Commands blocks, such as those
+// appended to a b-file that already had an empty block, are merged into the first block.  Returns the parse error of
+// an Extra Commands block that could not be parsed, and the b-file is not changed.
+func (bf *Bfile) UpdateExtraCommands(add []string, remove []string) error {
+	err := bf.unparsedBlockError(func(header string) bool { return header == EXTRA_COMMANDS_HEADER })
+	if err != nil {
+		return err
+	}
+	var extra *ExtraCommands
+	blocks := make([]BfileBlock, 0, len(bf.BfileBlocks))
+	for _, block := range bf.BfileBlocks {
+		ec, ok := block.(*ExtraCommands)
+		if !ok {
+			blocks = append(blocks, block)
+			continue
+		}
+		if extra == nil {
+			extra = ec
+			blocks = append(blocks, block)
+			continue
+		}
+		extra.AddCommands(ec.Commands...)
+		extra.ExtraLines = append(extra.ExtraLines, ec.ExtraLines...)
+		extra.changed = true
+	}
+	if extra == nil {
+		extra = &ExtraCommands{Commands: []string{}, ExtraLines: []string{}}
+		blocks = append(blocks, extra)
+	}
+	extra.AddCommands(add...)
+	extra.RemoveCommands(remove...)
+	bf.BfileBlocks = blocks
+	return nil
+}
@@ -0,0 +1,130 @@
+package ras
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// GateOpeningData is the header block of the gate opening time series for a structure, e.g.
+//
+//	Time Series of Gate Opening Data for:Dam
+//	       3       1       T       F       F
+//
+// StructureName keeps the padding of the header.  Settings are the trimmed cells of the second row.  Each gate of
+// the structure follows as a GateOpening block.  Parsed blocks are written as the rows they were parsed from.
+type GateOpeningData struct {
+	StructureName string
+	Settings      []string
+	ExtraLines    []string
+	rows          []string //rows the block was parsed from
+}
+
+func InitGateOpeningData(rows []string) (*GateOpeningData, error) {
+	output := GateOpeningData{
+		StructureName: strings.TrimPrefix(rows[0], GATE_OPENING_DATA_HEADER),
+		Settings:      []string{},
+		ExtraLines:    []string{},
+		rows:          rows,
+	}
+	if len(rows) > 1 {
+		cells, err := splitRowsIntoCells(rows[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, cell := range cells {
+			output.Settings = append(output.Settings, strings.TrimSpace(cell))
+		}
+		output.ExtraLines = append(output.ExtraLines, rows[2:]...)
+	}
+	return &output, nil
+}
+
+func (gd *GateOpeningData) Header() string {
+	return GATE_OPENING_DATA_HEADER + gd.StructureName
+}
+
+func (gd *GateOpeningData) UpdateFloat(value float64) error {
+	return errors.New("cannot update float on gate opening data")
+}
+
+func (gd *GateOpeningData) UpdateFloatArray(values []float32) error {
+	return errors.New("cannot update float array on gate opening data")
+}
+
+func (gd *GateOpeningData) ToBytes() ([]byte, error) {
+	return blockBytes(gd)
+}
+
+func (gd *GateOpeningData) WriteTo(w io.Writer) (int64, error) {
+	if gd.rows != nil {
+		return writeRows(w, gd.rows)
+	}
+	rw := rowWriter{w: w}
+	rw.row(gd.Header())
+	rw.row(cellsToRow(gd.Settings))
+	rw.rows(gd.ExtraLines)
+	return rw.n, rw.err
+}
+
+// GateOpening is the opening time series of a single gate, e.g.
+//
+//	Gate:Gate #1
+//	      73
+//	       0       2       1       3 ...
+//
+// The Index of each ordinate is the time in hours and Flow is the gate opening height.  Parsed blocks are written as
+// the rows they were parsed from until the openings are changed by UpdateFloatArray.
+type GateOpening struct {
+	Gate       string
+	RowCount   int
+	Openings   []FlowData
+	ExtraLines []string
+	rows       []string //rows the block was parsed from
+	changed    bool     //the openings were updated since they were parsed
+}
+
+func InitGateOpening(rows []string) (*GateOpening, error) {
+	rowCount, openings, extraLines, err := parseOrdinateRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &GateOpening{
+		Gate:       strings.TrimPrefix(rows[0], GATE_HEADER),
+		RowCount:   rowCount,
+		Openings:   openings,
+		ExtraLines: extraLines,
+		rows:       rows,
+	}, nil
+}
+
+func (g *GateOpening) Header() string {
+	return GATE_HEADER + g.Gate
+}
+
+func (g *GateOpening) UpdateFloat(value float64) error {
+	return errors.New("cannot update float on a gate opening time series")
+}
+
+// UpdateFloatArray replaces the gate openings, keeping the existing times
+func (g *GateOpening) UpdateFloatArray(values []float32) error {
+	if len(g.Openings) != len(values) {
+		return errors.New("gate openings were not the same length as the target in the b file")
+	}
+	for idx, fd := range g.Openings {
+		g.Openings[idx] = FlowData{fd.Index, values[idx]}
+	}
+	g.changed = true
+	return nil
+}
+
+func (g *GateOpening) ToBytes() ([]byte, error) {
+	return blockBytes(g)
+}
+
+func (g *GateOpening) WriteTo(w io.Writer) (int64, error) {
+	if !g.changed && g.rows != nil {
+		return writeRows(w, g.rows)
+	}
+	return writeOrdinates(w, g.Header(), g.RowCount, g.Openings, g.ExtraLines)
+}
@@ -0,0 +1,91 @@
+package ras
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// InitialConditions is the restart file block of the b-file
+//
+//	Initial Conditions (use restart file?)
+//	       F
+//
+// Rows after the flag are kept in ExtraLines.  Parsed blocks are written as the rows they were parsed from until the
+// flag is changed by SetUseRestartFile.
+type InitialConditions struct {
+	UseRestartFile bool
+	ExtraLines     []string
+	rows           []string //rows the block was parsed from
+	changed        bool     //the flag was updated since it was parsed
+}
+
+func InitInitialConditions(rows []string) (*InitialConditions, error) {
+	if len(rows) < 2 {
+		return nil, errors.New("missing the restart file flag")
+	}
+	useRestartFile, err := parseBfileBool(rows[1])
+	if err != nil {
+		return nil, err
+	}
+	extraLines := make([]string, len(rows)-2)
+	copy(extraLines, rows[2:])
+	return &InitialConditions{
+		UseRestartFile: useRestartFile,
+		ExtraLines:     extraLines,
+		rows:           rows,
+	}, nil
+}
+
+func (ic *InitialConditions) Header() string {
+	return INITIAL_CONDITIONS_HEADER
+}
+
+func (ic *InitialConditions) UpdateFloat(value float64) error {
+	return errors.New("cannot update float on initial conditions")
+}
+
+func (ic *InitialConditions) UpdateFloatArray(values []float32) error {
+	return errors.New("cannot update float array on initial conditions")
+}
+
+// SetUseRestartFile sets whether the simulation starts from a restart file
+func (ic *InitialConditions) SetUseRestartFile(use bool) {
+	ic.changed = ic.changed || ic.UseRestartFile != use
+	ic.UseRestartFile = use
+}
+
+func (ic *InitialConditions) ToBytes() ([]byte, error) {
+	return blockBytes(ic)
+}
+
+func (ic *InitialConditions) WriteTo(w io.Writer) (int64, error) {
+	if !ic.changed && ic.rows != nil {
+		return writeRows(w, ic.rows)
+	}
+	rw := rowWriter{w: w}
+	rw.row(INITIAL_CONDITIONS_HEADER)
+	rw.row(cellsToRow([]string{formatBfileBool(ic.UseRestartFile)}))
+	rw.rows(ic.ExtraLines)
+	return rw.n, rw.err
+}
+
+// parseBfileBool reads a T or F cell
+func parseBfileBool(cell string) (bool, error) {
+	switch strings.TrimSpace(cell) {
+	case "T":
+		return true, nil
+	case "F":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid b-file flag %s", cell)
+	}
+}
+
+func formatBfileBool(val bool) string {
+	if val {
+		return "T"
+	}
+	return "F"
+}
@@ -0,0 +1,23 @@
+package ras
+
+import (
+	"fmt"
+	"sort"
+)
+
+// tolerance used when matching ordinate times
+const INTERPOLATION_TOLERANCE float64 = 0.000001
+
+// Interpolate linearly interpolates the value at time t.  times must be sorted in ascending order and t must be
+// within the range of times.
+func Interpolate(times []float64, values []float64, t float64) (float64, error) {
+	i := sort.SearchFloat64s(times, t-INTERPOLATION_TOLERANCE)
+	if i == len(times) || (i == 0 && t < times[0]-INTERPOLATION_TOLERANCE) {
+		return 0, fmt.Errorf("time %f is outside of the source time series", t)
+	}
+	if i == 0 || times[i]-t < INTERPOLATION_TOLERANCE {
+		return values[i], nil
+	}
+	weight := (t - times[i-1]) / (times[i] - times[i-1])
+	return values[i-1] + weight*(values[i]-values[i-1]), nil
+}
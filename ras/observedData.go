@@ -0,0 +1,64 @@
+package ras
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ObservedData is the internal observed stage and flow boundary block of the b-file
+//
+//	Internal Observed Stage/Flow Boundaries
+//	       0
+//
+// Count is the number of observed boundaries and the rows describing each boundary are kept in Rows.  Parsed blocks are
+// written as the rows they were parsed from.
+type ObservedData struct {
+	Count int
+	Rows  []string
+	rows  []string //rows the block was parsed from
+}
+
+func InitObservedData(rows []string) (*ObservedData, error) {
+	if len(rows) < 2 {
+		return nil, errors.New("missing the observed boundary count")
+	}
+	count, err := getIntFromCellValue(rows[1])
+	if err != nil {
+		return nil, err
+	}
+	dataRows := make([]string, len(rows)-2)
+	copy(dataRows, rows[2:])
+	return &ObservedData{
+		Count: count,
+		Rows:  dataRows,
+		rows:  rows,
+	}, nil
+}
+
+func (od *ObservedData) Header() string {
+	return OBSERVED_DATA_HEADER
+}
+
+func (od *ObservedData) UpdateFloat(value float64) error {
+	return errors.New("cannot update float on observed data")
+}
+
+func (od *ObservedData) UpdateFloatArray(values []float32) error {
+	return errors.New("cannot update float array on observed data")
+}
+
+func (od *ObservedData) ToBytes() ([]byte, error) {
+	return blockBytes(od)
+}
+
+func (od *ObservedData) WriteTo(w io.Writer) (int64, error) {
+	if od.rows != nil {
+		return writeRows(w, od.rows)
+	}
+	rw := rowWriter{w: w}
+	rw.row(OBSERVED_DATA_HEADER)
+	rw.row(fmt.Sprintf("%8d", od.Count))
+	rw.rows(od.Rows)
+	return rw.n, rw.err
+}
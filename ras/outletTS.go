@@ -3,17 +3,25 @@ package ras
 import (
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 )
 
 const EndOfFlow = " 3.4E+38"
 
+// number of ordinate pairs written on each b-file row
+const ORDINATES_PER_ROW int = 5
+
+// OutletTS is an outlet time series block.  Parsed blocks are written as the rows they were parsed from until the
+// series is changed by UpdateFloatArray, ResampleFlows or UpdateTimeSeries.
 type OutletTS struct {
 	Name       string
 	RowCount   int
 	TimeSeries []FlowData
 	ExtraLines []string
+	rows       []string //rows the block was parsed from
+	changed    bool     //the series was updated since it was parsed
 }
 type FlowData struct {
 	Index float32
@@ -22,40 +30,69 @@ type FlowData struct {
 
 func InitOutletTS(rows []string) (*OutletTS, error) {
 	name := rows[0][len(TS_OUTFLOW_HEADER):len(rows[0])]
-	output := OutletTS{Name: name}
-	stringLines := make([]string, 0)
-	rowCount, err := strconv.Atoi(strings.TrimLeft(rows[1], " "))
+	output := OutletTS{Name: name, rows: rows}
+	rowCount, flowdata, extraLines, err := parseOrdinateRows(rows)
 	if err != nil {
 		return &output, err
 	}
+	// for multiple dams the rows after the flow data, starting with the end of flow marker, are kept as extra lines
 	output.RowCount = rowCount
-	flowdata := make([]FlowData, rowCount)
-	hasReachedEndOfFlow := false
-	for idx, rowstring := range rows {
-		if idx != 0 && idx != 1 {
-			// for multiple dams the last line in the returned block is actually the first line of the incoming block - it has text in it.
-			if hasReachedEndOfFlow {
-				stringLines = append(stringLines, rowstring)
-			} else {
-				if rowstring == EndOfFlow {
-					hasReachedEndOfFlow = true
-					stringLines = append(stringLines, rowstring)
-				} else {
-					tmpFlowData, err := parseRowString(rowstring)
-					if err != nil {
-						return &output, err
-					}
-					for jdx, fd := range tmpFlowData {
-						flowdata[(idx-2)*5+jdx] = fd
-					}
-				}
-			}
-		}
-	}
 	output.TimeSeries = flowdata
-	output.ExtraLines = stringLines
+	output.ExtraLines = extraLines
 	return &output, nil
 }
+
+// parseOrdinateRows reads a time series block: a header row, a row with the number of ordinates, and then the
+// ordinates written as pairs of 8 character cells, five pairs to a row.  Rows after the ordinates are returned as
+// extra lines.
+func parseOrdinateRows(rows []string) (int, []FlowData, []string, error) {
+	if len(rows) < 2 {
+		return 0, nil, nil, errors.New("missing the ordinate count row")
+	}
+	rowCount, err := strconv.Atoi(strings.TrimSpace(rows[1]))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	dataRows := (rowCount + ORDINATES_PER_ROW - 1) / ORDINATES_PER_ROW
+	if len(rows) < 2+dataRows {
+		return 0, nil, nil, fmt.Errorf("expected %d ordinate rows, found %d", dataRows, len(rows)-2)
+	}
+	ordinates := make([]FlowData, 0, rowCount)
+	for _, rowstring := range rows[2 : 2+dataRows] {
+		tmpFlowData, err := parseRowString(rowstring)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		ordinates = append(ordinates, tmpFlowData...)
+	}
+	if len(ordinates) != rowCount {
+		return 0, nil, nil, fmt.Errorf("expected %d ordinates, found %d", rowCount, len(ordinates))
+	}
+	extraLines := make([]string, len(rows)-2-dataRows)
+	copy(extraLines, rows[2+dataRows:])
+	return rowCount, ordinates, extraLines, nil
+}
+
+// writeOrdinates writes a time series block in the layout read by parseOrdinateRows
+func writeOrdinates(w io.Writer, header string, rowCount int, ordinates []FlowData, extraLines []string) (int64, error) {
+	rw := rowWriter{w: w}
+	rw.row(header)
+	rw.row(fmt.Sprintf("%*d", 8, rowCount))
+	//write out 5 pairs then newline
+	for idx, fd := range ordinates {
+		if idx != 0 && idx%ORDINATES_PER_ROW == 0 {
+			rw.write("\n") //zero based will return on the 6th element (after the 5th) before writing the 6th
+		}
+		rw.write(convertFloatToBfileCellValue(float64(fd.Index)))
+		rw.write(convertFloatToBfileCellValue(float64(fd.Flow)))
+	}
+	if len(ordinates) > 0 {
+		rw.write("\n")
+	}
+	rw.rows(extraLines)
+	return rw.n, rw.err
+}
+
 func parseRowString(rowString string) ([]FlowData, error) {
 	valueLength := 8
 	rowLength := len(rowString)
@@ -102,27 +139,55 @@ func (ots *OutletTS) UpdateFloatArray(values []float32) error {
 	for idx, fd := range ots.TimeSeries {
 		ots.TimeSeries[idx] = FlowData{fd.Index, values[idx]}
 	}
+	ots.changed = true
 	return nil
 }
 
 func (ots *OutletTS) ToBytes() ([]byte, error) {
-	result := make([]byte, 0)
-	result = append(result, fmt.Sprintf("%v%v\n", TS_OUTFLOW_HEADER, ots.Name)...)
-	result = append(result, fmt.Sprintf("%*d\n", 8, ots.RowCount)...)
-	//write out 5 pairs then newline
+	return blockBytes(ots)
+}
+
+func (ots *OutletTS) WriteTo(w io.Writer) (int64, error) {
+	if !ots.changed && ots.rows != nil {
+		return writeRows(w, ots.rows)
+	}
+	return writeOrdinates(w, TS_OUTFLOW_HEADER+ots.Name, ots.RowCount, ots.TimeSeries, ots.ExtraLines)
+}
+
+// ResampleFlows interpolates a source flow series onto the index ordinates of the outlet time series.  times must be
+// in the units of the index ordinates (hours), sorted ascending, and cover every index ordinate.
+func (ots *OutletTS) ResampleFlows(times []float64, flows []float64) error {
+	if len(times) != len(flows) {
+		return fmt.Errorf("source has %d times and %d flows", len(times), len(flows))
+	}
+	resampled := make([]FlowData, len(ots.TimeSeries))
 	for idx, fd := range ots.TimeSeries {
-		if idx != 0 {
-			if idx%5 == 0 {
-				result = append(result, "\n"...) //zero based will return on the 6th element (after the 5th) before writing the 6th
-			}
+		flow, err := Interpolate(times, flows, float64(fd.Index))
+		if err != nil {
+			return err
 		}
-		result = append(result, fmt.Sprintf("%s%s", convertFloatToBfileCellValue(float64(fd.Index)), convertFloatToBfileCellValue(float64(fd.Flow)))...) //
+		resampled[idx] = FlowData{fd.Index, float32(flow)}
 	}
-	result = append(result, "\n"...)
+	ots.TimeSeries = resampled
+	ots.changed = true
+	return nil
+}
 
-	for _, r := range ots.ExtraLines {
-		result = append(result, fmt.Sprintf("%s\n", r)...)
+// UpdateTimeSeries replaces the ordinates of the outlet time series, rewriting the row count and index.  The extra
+// lines, including the end of flow marker, are kept.
+func (ots *OutletTS) UpdateTimeSeries(times []float32, flows []float32) error {
+	if len(times) != len(flows) {
+		return fmt.Errorf("source has %d times and %d flows", len(times), len(flows))
 	}
-	//
-	return result, nil
+	timeSeries := make([]FlowData, len(times))
+	for idx := range times {
+		if idx > 0 && times[idx] <= times[idx-1] {
+			return fmt.Errorf("source times are not increasing at ordinate %d", idx)
+		}
+		timeSeries[idx] = FlowData{times[idx], flows[idx]}
+	}
+	ots.RowCount = len(timeSeries)
+	ots.TimeSeries = timeSeries
+	ots.changed = true
+	return nil
 }
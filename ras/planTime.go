@@ -0,0 +1,75 @@
+package ras
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/usace-cloud-compute/go-hdf5"
+)
+
+const (
+	PLAN_INFORMATION_PATH string = "/Plan Data/Plan Information"
+	SIMULATION_START_ATTR string = "Simulation Start Time"
+)
+
+// RAS writes date times in several layouts depending on version and context
+var rasDateTimeLayouts []string = []string{
+	"02Jan2006 15:04:05",
+	"02Jan2006 15:04",
+	"02Jan2006 1504",
+	"02Jan2006",
+}
+
+// ParseRasDateTime parses a RAS date time string such as "01Jan2000 12:00:00" or "01Jan2000 1200".
+// RAS uses hour 24 for the end of a day, which is returned as midnight of the following day.
+func ParseRasDateTime(val string) (time.Time, error) {
+	val = strings.TrimSpace(val)
+	endOfDay := false
+	for _, midnight := range []string{" 24:00:00", " 24:00", " 2400"} {
+		if strings.HasSuffix(val, midnight) {
+			val = strings.TrimSuffix(val, midnight)
+			endOfDay = true
+			break
+		}
+	}
+	for _, layout := range rasDateTimeLayouts {
+		t, err := time.Parse(layout, val)
+		if err == nil {
+			if endOfDay {
+				t = t.AddDate(0, 0, 1)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid RAS date time: %s", val)
+}
+
+// ReadSimulationStartTime reads the simulation start time from the plan information in a plan HDF file.
+// Boundary condition time ordinates are in days from the simulation start time.
+func ReadSimulationStartTime(f *hdf5.File) (time.Time, error) {
+	group, err := f.OpenGroup(PLAN_INFORMATION_PATH)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to open '%s': %s", PLAN_INFORMATION_PATH, err)
+	}
+	defer group.Close()
+	if !group.AttributeExists(SIMULATION_START_ATTR) {
+		return time.Time{}, fmt.Errorf("plan information does not include a '%s'", SIMULATION_START_ATTR)
+	}
+	attr, err := group.OpenAttribute(SIMULATION_START_ATTR)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer attr.Close()
+	var start string
+	err = attr.Read(&start, hdf5.T_GO_STRING)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ParseRasDateTime(start)
+}
+
+// DaysSince converts a time to the fractional days since a simulation start time.
+func DaysSince(start time.Time, t time.Time) float64 {
+	return t.Sub(start).Hours() / 24
+}
@@ -0,0 +1,102 @@
+package ras
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+)
+
+// StructureCatalogEntry is a structure with its breach status.  BreachEnabled is only set when the catalog was built
+// with a b-file, a structure is breach enabled when the b-file has breach data for its SNET ID.
+type StructureCatalogEntry struct {
+	Structure
+	BreachEnabled *bool `json:"breach_enabled,omitempty"`
+}
+
+// structure attributes written as CSV columns after the identifying fields
+var structureCatalogCsvAttributes []string = []string{"US SA/2D", "DS SA/2D", "Weir Min Elevation", "Weir Width"}
+
+// BreachSNetIDs returns the SNET IDs of the structures with breach data
+func (bf *Bfile) BreachSNetIDs() map[int]bool {
+	ids := map[int]bool{}
+	for _, block := range bf.BfileBlocks {
+		if bd, ok := block.(*BreachData); ok {
+			ids[bd.SNetID] = true
+		}
+	}
+	return ids
+}
+
+// NewStructureCatalog builds the catalog of structures.  bf is optional and sets the breach status of each structure.
+func NewStructureCatalog(structures []Structure, bf *Bfile) []StructureCatalogEntry {
+	var breachIDs map[int]bool
+	if bf != nil {
+		breachIDs = bf.BreachSNetIDs()
+	}
+	catalog := make([]StructureCatalogEntry, len(structures))
+	for idx, structure := range structures {
+		catalog[idx] = StructureCatalogEntry{Structure: structure}
+		if breachIDs != nil {
+			enabled := breachIDs[structure.SNetID]
+			catalog[idx].BreachEnabled = &enabled
+		}
+	}
+	return catalog
+}
+
+// WriteStructureCatalogCsv writes the catalog with one row per structure.  Missing attributes and NaN values are
+// written as empty cells.
+func WriteStructureCatalogCsv(w io.Writer, catalog []StructureCatalogEntry) error {
+	writer := csv.NewWriter(w)
+	header := []string{"index", "type", "river", "reach", "rs", "connection", "us_sa2d", "ds_sa2d", "weir_min_elevation", "weir_width", "snet_id", "breach_enabled"}
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+	for _, entry := range catalog {
+		row := []string{
+			strconv.Itoa(entry.Index),
+			entry.Type,
+			entry.River,
+			entry.Reach,
+			entry.RS,
+			entry.Connection,
+		}
+		for _, name := range structureCatalogCsvAttributes {
+			row = append(row, formatStructureAttribute(entry.Attributes[name]))
+		}
+		row = append(row, strconv.Itoa(entry.SNetID))
+		if entry.BreachEnabled != nil {
+			row = append(row, strconv.FormatBool(*entry.BreachEnabled))
+		} else {
+			row = append(row, "")
+		}
+		err = writer.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatStructureAttribute(val any) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case Hdf5Float32:
+		if math.IsNaN(float64(v)) {
+			return ""
+		}
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case Hdf5Float64:
+		if math.IsNaN(float64(v)) {
+			return ""
+		}
+		return strconv.FormatFloat(float64(v), 'f', -1, 64)
+	}
+	return ""
+}
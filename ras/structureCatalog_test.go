@@ -0,0 +1,57 @@
+package ras
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestStructureCatalog(t *testing.T) {
+	structures := []Structure{
+		{Index: 0, Type: "Connection", Connection: "Dam", SNetID: 3, Attributes: StructureAttributes{
+			"US SA/2D": "Reservoir", "DS SA/2D": "Perimeter 1", "Weir Min Elevation": Hdf5Float32(680.5), "Weir Width": Hdf5Float32(float32(math.NaN())),
+		}},
+		{Index: 1, Type: "Inline", River: "Bald Eagle", Reach: "Lock Haven", RS: "81084.18", SNetID: 4, Attributes: StructureAttributes{}},
+	}
+	bf, err := InitBFile(MULTI_BREACH_FILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := NewStructureCatalog(structures, bf)
+	if !*catalog[0].BreachEnabled || *catalog[1].BreachEnabled {
+		t.Errorf("unexpected breach status %v %v", *catalog[0].BreachEnabled, *catalog[1].BreachEnabled)
+	}
+	if NewStructureCatalog(structures, nil)[0].BreachEnabled != nil {
+		t.Error("breach status should not be set without a b-file")
+	}
+
+	var buf bytes.Buffer
+	err = WriteStructureCatalogCsv(&buf, catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"index,type,river,reach,rs,connection,us_sa2d,ds_sa2d,weir_min_elevation,weir_width,snet_id,breach_enabled",
+		"0,Connection,,,,Dam,Reservoir,Perimeter 1,680.5,,3,true",
+		"1,Inline,Bald Eagle,Lock Haven,81084.18,,,,,,4,false",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected csv:\n%s", buf.String())
+	}
+
+	b, err := json.Marshal(catalog[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	err = json.Unmarshal(b, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded["connection"] != "Dam" || decoded["breach_enabled"] != true || decoded["attributes"] == nil {
+		t.Errorf("unexpected json %s", b)
+	}
+}
@@ -0,0 +1,144 @@
+package ras
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrStructureNotFound  = errors.New("structure not found")
+	ErrAmbiguousStructure = errors.New("structure identifier matches more than one structure")
+)
+
+// StructureIdentifier identifies a structure by SNET ID, by river, reach and RS, or by connection name.  When more
+// than one scheme is set they must identify the same structure.
+type StructureIdentifier struct {
+	SNetID     *int   `json:"snet_id,omitempty"`
+	River      string `json:"river,omitempty"`
+	Reach      string `json:"reach,omitempty"`
+	RS         string `json:"rs,omitempty"`
+	Connection string `json:"connection,omitempty"`
+}
+
+func (id StructureIdentifier) String() string {
+	parts := []string{}
+	if id.Connection != "" {
+		parts = append(parts, id.Connection)
+	}
+	if id.River != "" || id.Reach != "" || id.RS != "" {
+		parts = append(parts, fmt.Sprintf("%s/%s/%s", id.River, id.Reach, id.RS))
+	}
+	if id.SNetID != nil {
+		parts = append(parts, fmt.Sprintf("SNET ID %d", *id.SNetID))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Name is the connection name of the structure, or its river, reach and RS when it is not a connection
+func (s Structure) Name() string {
+	if s.Connection != "" {
+		return s.Connection
+	}
+	if s.River != "" || s.Reach != "" || s.RS != "" {
+		return fmt.Sprintf("%s %s %s", s.River, s.Reach, s.RS)
+	}
+	return ""
+}
+
+// StructureResolver resolves structure identifiers to structures.  A resolver without structures, such as one built
+// when there is no geometry, only resolves SNET IDs, which are returned without a name.
+type StructureResolver struct {
+	structures     []Structure
+	bySNetID       map[int][]int
+	byRiverReachRS map[[3]string][]int
+	byConnection   map[string][]int
+}
+
+// NewStructureResolver indexes structures by each identifier scheme.  structures may be nil.
+func NewStructureResolver(structures []Structure) *StructureResolver {
+	sr := StructureResolver{
+		structures:     structures,
+		bySNetID:       map[int][]int{},
+		byRiverReachRS: map[[3]string][]int{},
+		byConnection:   map[string][]int{},
+	}
+	for idx, s := range structures {
+		sr.bySNetID[s.SNetID] = append(sr.bySNetID[s.SNetID], idx)
+		if s.River != "" || s.Reach != "" || s.RS != "" {
+			key := [3]string{s.River, s.Reach, s.RS}
+			sr.byRiverReachRS[key] = append(sr.byRiverReachRS[key], idx)
+		}
+		if s.Connection != "" {
+			sr.byConnection[s.Connection] = append(sr.byConnection[s.Connection], idx)
+		}
+	}
+	return &sr
+}
+
+// Resolve finds the structure of an identifier.  Errors wrap ErrStructureNotFound when a scheme matches no structure
+// and ErrAmbiguousStructure when a scheme matches more than one.
+func (sr *StructureResolver) Resolve(id StructureIdentifier) (Structure, error) {
+	if sr.structures == nil {
+		if id.SNetID == nil {
+			return Structure{}, fmt.Errorf("%s can not be resolved without structures from a geometry: %w", id, ErrStructureNotFound)
+		}
+		return Structure{Index: -1, SNetID: *id.SNetID}, nil
+	}
+	matches := [][]int{}
+	if id.SNetID != nil {
+		matches = append(matches, sr.bySNetID[*id.SNetID])
+	}
+	if id.River != "" || id.Reach != "" || id.RS != "" {
+		matches = append(matches, sr.byRiverReachRS[[3]string{id.River, id.Reach, id.RS}])
+	}
+	if id.Connection != "" {
+		matches = append(matches, sr.byConnection[id.Connection])
+	}
+	if len(matches) == 0 {
+		return Structure{}, errors.New("the structure identifier is empty")
+	}
+	found := -1
+	for _, match := range matches {
+		switch {
+		case len(match) == 0:
+			return Structure{}, fmt.Errorf("%s: %w", id, ErrStructureNotFound)
+		case len(match) > 1:
+			return Structure{}, fmt.Errorf("%s: %w", id, ErrAmbiguousStructure)
+		case found >= 0 && found != match[0]:
+			return Structure{}, fmt.Errorf("%s identifies different structures", id)
+		}
+		found = match[0]
+	}
+	return sr.structures[found], nil
+}
+
+// Structure returns the structure with an SNET ID
+func (sr *StructureResolver) Structure(snetID int) (Structure, bool) {
+	matches := sr.bySNetID[snetID]
+	if len(matches) != 1 {
+		return Structure{}, false
+	}
+	return sr.structures[matches[0]], true
+}
+
+// NameBreachData sets the name of the breach data of each structure the resolver knows
+func (bf *Bfile) NameBreachData(sr *StructureResolver) {
+	for _, block := range bf.BfileBlocks {
+		if bd, ok := block.(*BreachData); ok {
+			if s, ok := sr.Structure(bd.SNetID); ok {
+				bd.Name = s.Name()
+			}
+		}
+	}
+}
+
+// FindBreachDataBySNetID returns the breach data of a structure by SNET ID
+func (bf *Bfile) FindBreachDataBySNetID(snetID int) (*BreachData, error) {
+	for _, block := range bf.BfileBlocks {
+		if bd, ok := block.(*BreachData); ok && bd.SNetID == snetID {
+			return bd, nil
+		}
+	}
+	return nil, fmt.Errorf("SNET ID %d does not have breach data in the bFile", snetID)
+}
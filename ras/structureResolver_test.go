@@ -0,0 +1,59 @@
+package ras
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStructureResolver(t *testing.T) {
+	structures := []Structure{
+		{Index: 0, Type: "Connection", Connection: "Dam", SNetID: 3},
+		{Index: 1, Type: "Inline", River: "Bald Eagle", Reach: "Lock Haven", RS: "81500", SNetID: 6},
+		{Index: 2, Type: "Connection", Connection: "Levee", SNetID: 8},
+		{Index: 3, Type: "Connection", Connection: "Levee", SNetID: 9},
+	}
+	resolver := NewStructureResolver(structures)
+	damID, inlineID, missingID := 3, 6, 12
+	found := []struct {
+		id   StructureIdentifier
+		want int
+	}{
+		{StructureIdentifier{Connection: "Dam"}, 3},
+		{StructureIdentifier{SNetID: &damID}, 3},
+		{StructureIdentifier{SNetID: &damID, Connection: "Dam"}, 3},
+		{StructureIdentifier{River: "Bald Eagle", Reach: "Lock Haven", RS: "81500"}, 6},
+		{StructureIdentifier{SNetID: &inlineID, River: "Bald Eagle", Reach: "Lock Haven", RS: "81500"}, 6},
+	}
+	for _, c := range found {
+		s, err := resolver.Resolve(c.id)
+		if err != nil || s.SNetID != c.want {
+			t.Errorf("Resolve(%s) = %d %v, want %d", c.id, s.SNetID, err, c.want)
+		}
+	}
+	if s, _ := resolver.Structure(6); s.Name() != "Bald Eagle Lock Haven 81500" {
+		t.Errorf("unexpected inline structure name %q", s.Name())
+	}
+
+	if _, err := resolver.Resolve(StructureIdentifier{Connection: "Levee"}); !errors.Is(err, ErrAmbiguousStructure) {
+		t.Errorf("expected an ambiguous structure error, got %v", err)
+	}
+	for _, id := range []StructureIdentifier{{Connection: "Spillway"}, {SNetID: &missingID}, {SNetID: &damID, Connection: "Spillway"}} {
+		if _, err := resolver.Resolve(id); !errors.Is(err, ErrStructureNotFound) {
+			t.Errorf("expected %s to not be found, got %v", id, err)
+		}
+	}
+	if _, err := resolver.Resolve(StructureIdentifier{SNetID: &inlineID, Connection: "Dam"}); err == nil {
+		t.Error("expected an error for identifiers of different structures")
+	}
+	if _, err := resolver.Resolve(StructureIdentifier{}); err == nil {
+		t.Error("expected an error for an empty identifier")
+	}
+
+	geometryFree := NewStructureResolver(nil)
+	if s, err := geometryFree.Resolve(StructureIdentifier{SNetID: &missingID}); err != nil || s.SNetID != 12 {
+		t.Errorf("expected SNET IDs to resolve without structures, got %+v %v", s, err)
+	}
+	if _, err := geometryFree.Resolve(StructureIdentifier{Connection: "Dam"}); !errors.Is(err, ErrStructureNotFound) {
+		t.Errorf("expected names to not resolve without structures, got %v", err)
+	}
+}
@@ -1,14 +1,14 @@
 package ras
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"log"
 	"math"
 	"strings"
 
 	"github.com/usace-cloud-compute/go-hdf5"
-	"github.com/usace-cloud-compute/go-hdf5/util"
 )
 
 type Hdf5Float32 float32
@@ -21,256 +21,190 @@ func (value Hdf5Float32) MarshalJSON() ([]byte, error) {
 	return json.Marshal(f)
 }
 
+type Hdf5Float64 float64
+
+func (value Hdf5Float64) MarshalJSON() ([]byte, error) {
+	f := float64(value)
+	if math.IsNaN(f) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f)
+}
+
 const STRUCTURE_DATA_PATH string = "Geometry/Structures/Attributes/"
 
-// nominated for an ig-nobel award in data structure science.......
-type structuresAttr struct {
-	Type                     string      `strlen:"16" hdf:"Type"`
-	Mode                     string      `strlen:"18" hdf:"Mode"`
-	River                    string      `strlen:"16" hdf:"River"`
-	Reach                    string      `strlen:"16" hdf:"Reach"`
-	RS                       string      `strlen:"8" hdf:"RS"`
-	Connection               string      `strlen:"16" hdf:"Connection"`
-	GroupName                string      `strlen:"45" hdf:"Groupname"`
-	UsType                   string      `strlen:"16" hdf:"US Type"`
-	UsRiver                  string      `strlen:"16" hdf:"US River"`
-	UsReach                  string      `strlen:"16" hdf:"US Reach"`
-	UsRs                     string      `strlen:"8" hdf:"US RS"`
-	UsSa2d                   string      `strlen:"16" hdf:"US SA/2D"`
-	DsType                   string      `strlen:"16" hdf:"DS Type"`
-	DsRiver                  string      `strlen:"16" hdf:"DS River"`
-	DsReach                  string      `strlen:"16" hdf:"DS Reach"`
-	DsRs                     string      `strlen:"8" hdf:"DS RS"`
-	DsSa2d                   string      `strlen:"16" hdf:"DS SA/2D"`
-	NodeName                 string      `strlen:"16" hdf:"Node Name"`
-	Description              string      `strlen:"512" hdf:"Description"`
-	LastEdited               string      `strlen:"18" hdf:"Last Edited"`
-	UpstreamDistance         Hdf5Float32 `hdf:"Upstream Distance"`
-	WeirWidth                Hdf5Float32 `hdf:"Weir Width"`
-	WeirMaxSubmergence       Hdf5Float32 `hdf:"Weir Max Submergence"`
-	WeirMinElev              Hdf5Float32 `hdf:"Weir Min Elevation"`
-	WeirCoef                 Hdf5Float32 `hdf:"Weir Coef"`
-	WeirShape                string      `strlen:"16" hdf:"Weir Shape"`
-	WeirDesignEgHead         Hdf5Float32 `hdf:"Weir Design EG Head"`
-	WeirDesignSpillHt        Hdf5Float32 `hdf:"Weir Design Spillway HT"`
-	WeirUsSlope              Hdf5Float32 `hdf:"Weir US Slope"`
-	WeirDsSlope              Hdf5Float32 `hdf:"Weir DS Slope"`
-	LinearRoutingPosCoef     Hdf5Float32 `hdf:"Linear Routing Positive Coef"`
-	LinearRoutingNegCoef     Hdf5Float32 `hdf:"Linear Routing Negative Coef"`
-	LinearRoutingElev        Hdf5Float32 `hdf:"Linear Routing Elevation"`
-	LwHwPosition             int32       `hdf:"LW HW Position"`
-	LwTwPosition             int32       `hdf:"LW TW Position"`
-	LwHwDistance             Hdf5Float32 `hdf:"LW HW Distance"`
-	LwTwDistance             Hdf5Float32 `hdf:"LW TW Distance"`
-	LwSpanMultiple           uint8       `hdf:"LW Span Multiple"`
-	Use2DForOverflow         uint8       `hdf:"Use 2D for Overflow"`
-	UseVelocityInto2D        uint8       `hdf:"Use Velocity into 2D"`
-	HagarsWeirCoef           Hdf5Float32 `hdf:"Hagers Weir Coef"`
-	HagarsHeight             Hdf5Float32 `hdf:"Hagers Height"`
-	HagarsSlope              Hdf5Float32 `hdf:"Hagers Slope"`
-	HagarsAngle              Hdf5Float32 `hdf:"Hagers Angle"`
-	HagarsRadius             Hdf5Float32 `hdf:"Hagers Radius"`
-	UseWsForWeirRef          uint8       `hdf:"Use WS for Weir Reference"`
-	PilotFlow                Hdf5Float32 `hdf:"Pilot Flow"`
-	CulvertGroups            int32       `hdf:"Culvert Groups"`
-	CulvertsFlapGates        int32       `hdf:"Culverts Flap Gates"`
-	GateGroups               int32       `hdf:"Gate Groups"`
-	HtabFfPoints             int32       `hdf:"HTAB FF Points"`
-	HtabRcCounts             int32       `hdf:"HTAB RC Count"`
-	HtabRcPoints             int32       `hdf:"HTAB RC Points"`
-	HtabHwMax                Hdf5Float32 `hdf:"HTAB HW Max"`
-	HtabTwMax                Hdf5Float32 `hdf:"HTAB TW Max"`
-	HtabMaxFlow              Hdf5Float32 `hdf:"HTAB Max Flow"`
-	CellSpacingNear          float32     `hdf:"Cell Spacing Near"`
-	CellSpacingFar           float32     `hdf:"Cell Spacing Far"`
-	NearRepeats              int32       `hdf:"Near Repeats"`
-	ProtectionRadius         uint8       `hdf:"Protection Radius"`
-	UseFrictionInMomentum    uint8       `hdf:"Use Friction in Momentum"`
-	UseWeightInMomentum      uint8       `hdf:"Use Weight in Momentum"`
-	UseCriticalUs            uint8       `hdf:"Use Critical US"`
-	UseEgforPressureCriteria uint8       `hdf:"Use EG for Pressure Criteria"`
-	IceOption                int32       `hdf:"Ice Option"`
-	WeirSkew                 Hdf5Float32 `hdf:"Weir Skew"`
-	PierSkew                 Hdf5Float32 `hdf:"Pier Skew"`
-	BrContraction            Hdf5Float32 `hdf:"BR Contraction"`
-	BrExpansion              Hdf5Float32 `hdf:"BR Expansion"`
-	BrUsLeftBank             Hdf5Float32 `hdf:"BR US Left Bank"`
-	BrUsRightBank            Hdf5Float32 `hdf:"BR US Right Bank"`
-	BrDsLeftBank             Hdf5Float32 `hdf:"BR DS Left Bank"`
-	BrDsrightBank            Hdf5Float32 `hdf:"BR DS Right Bank"`
-	XsUsLeftBank             Hdf5Float32 `hdf:"XS US Left Bank"`
-	XsUsRightBank            Hdf5Float32 `hdf:"XS US Right Bank"`
-	XsDsLeftBank             Hdf5Float32 `hdf:"XS DS Left Bank"`
-	XsDsRightBank            Hdf5Float32 `hdf:"XS DS Right Bank"`
-	UsIneffLeftSta           Hdf5Float32 `hdf:"US Ineff Left Sta"`
-	UsIneffLeftElev          Hdf5Float32 `hdf:"US Ineff Left Elev"`
-	UsIneffRightSta          Hdf5Float32 `hdf:"US Ineff Right Sta"`
-	UsIneffRightElev         Hdf5Float32 `hdf:"US Ineff Right Elev"`
-	DsIneffLeftSta           Hdf5Float32 `hdf:"DS Ineff Left Sta"`
-	DsIneffLeftElev          Hdf5Float32 `hdf:"DS Ineff Left Elev"`
-	DsIneffRightSta          Hdf5Float32 `hdf:"DS Ineff Right Sta"`
-	DsIneffRightElev         Hdf5Float32 `hdf:"DS Ineff Right Elev"`
-	UseOverrideHwCon         uint8       `hdf:"Use Override HW Connectivity"`
-	UseOverrideTwCon         uint8       `hdf:"Use Override TW Connectivity"`
-	UseRCFamily              uint8       `hdf:"Use RC Family"` //This wasn't present in Randy's example, But is in Bald Eagle from HEC-RAS 6.5
-	UseOverideHTabIBCurves   uint8       `hdf:"Use Override HTabIBCurves"`
-	SnnID                    int32       `hdf:"SNN ID"`
-	DefaultCenterline        uint8       `hdf:"Default Centerline"`
-	Culverts                 int32       `hdf:"Culverts"` //think starting here that these are old depricated attributes from v5
-	Gates                    int32       `hdf:"Gates"`
-	LwVelocityInto2D         uint8       `hdf:"LW Velocity Into 2D"`
+// StructureAttributes are the values of a row of the structure attributes table keyed by the compound member names
+// of the geometry file.  Strings are trimmed, integers are int64 and floats are Hdf5Float32 or Hdf5Float64.
+type StructureAttributes map[string]any
+
+func (sa StructureAttributes) String(name string) string {
+	val, _ := sa[name].(string)
+	return val
+}
+
+func (sa StructureAttributes) Int(name string) (int, bool) {
+	val, ok := sa[name].(int64)
+	return int(val), ok
 }
-type structuresAttr66 struct {
-	Type                     string      `strlen:"16" hdf:"Type"`
-	Mode                     string      `strlen:"18" hdf:"Mode"`
-	River                    string      `strlen:"16" hdf:"River"`
-	Reach                    string      `strlen:"16" hdf:"Reach"`
-	RS                       string      `strlen:"8" hdf:"RS"`
-	Connection               string      `strlen:"16" hdf:"Connection"`
-	GroupName                string      `strlen:"45" hdf:"Groupname"`
-	UsType                   string      `strlen:"16" hdf:"US Type"`
-	UsRiver                  string      `strlen:"16" hdf:"US River"`
-	UsReach                  string      `strlen:"16" hdf:"US Reach"`
-	UsRs                     string      `strlen:"8" hdf:"US RS"`
-	UsSa2d                   string      `strlen:"16" hdf:"US SA/2D"`
-	DsType                   string      `strlen:"16" hdf:"DS Type"`
-	DsRiver                  string      `strlen:"16" hdf:"DS River"`
-	DsReach                  string      `strlen:"16" hdf:"DS Reach"`
-	DsRs                     string      `strlen:"8" hdf:"DS RS"`
-	DsSa2d                   string      `strlen:"16" hdf:"DS SA/2D"`
-	NodeName                 string      `strlen:"16" hdf:"Node Name"`
-	Description              string      `strlen:"512" hdf:"Description"`
-	LastEdited               string      `strlen:"18" hdf:"Last Edited"`
-	UpstreamDistance         Hdf5Float32 `hdf:"Upstream Distance"`
-	WeirWidth                Hdf5Float32 `hdf:"Weir Width"`
-	WeirMaxSubmergence       Hdf5Float32 `hdf:"Weir Max Submergence"`
-	WeirMinElev              Hdf5Float32 `hdf:"Weir Min Elevation"`
-	WeirCoef                 Hdf5Float32 `hdf:"Weir Coef"`
-	WeirShape                string      `strlen:"16" hdf:"Weir Shape"`
-	WeirDesignEgHead         Hdf5Float32 `hdf:"Weir Design EG Head"`
-	WeirDesignSpillHt        Hdf5Float32 `hdf:"Weir Design Spillway HT"`
-	WeirUsSlope              Hdf5Float32 `hdf:"Weir US Slope"`
-	WeirDsSlope              Hdf5Float32 `hdf:"Weir DS Slope"`
-	LinearRoutingPosCoef     Hdf5Float32 `hdf:"Linear Routing Positive Coef"`
-	LinearRoutingNegCoef     Hdf5Float32 `hdf:"Linear Routing Negative Coef"`
-	LinearRoutingElev        Hdf5Float32 `hdf:"Linear Routing Elevation"`
-	LwHwPosition             int32       `hdf:"LW HW Position"`
-	LwTwPosition             int32       `hdf:"LW TW Position"`
-	LwHwDistance             Hdf5Float32 `hdf:"LW HW Distance"`
-	LwTwDistance             Hdf5Float32 `hdf:"LW TW Distance"`
-	LwSpanMultiple           uint8       `hdf:"LW Span Multiple"`
-	Use2DForOverflow         uint8       `hdf:"Use 2D for Overflow"`
-	UseVelocityInto2D        uint8       `hdf:"Use Velocity into 2D"`
-	HagarsWeirCoef           Hdf5Float32 `hdf:"Hagers Weir Coef"`
-	HagarsHeight             Hdf5Float32 `hdf:"Hagers Height"`
-	HagarsSlope              Hdf5Float32 `hdf:"Hagers Slope"`
-	HagarsAngle              Hdf5Float32 `hdf:"Hagers Angle"`
-	HagarsRadius             Hdf5Float32 `hdf:"Hagers Radius"`
-	UseWsForWeirRef          uint8       `hdf:"Use WS for Weir Reference"`
-	PilotFlow                Hdf5Float32 `hdf:"Pilot Flow"`
-	CulvertGroups            int32       `hdf:"Culvert Groups"`
-	CulvertsFlapGates        int32       `hdf:"Culverts Flap Gates"`
-	GateGroups               int32       `hdf:"Gate Groups"`
-	HtabFfPoints             int32       `hdf:"HTAB FF Points"`
-	HtabRcCounts             int32       `hdf:"HTAB RC Count"`
-	HtabRcPoints             int32       `hdf:"HTAB RC Points"`
-	HtabHwMax                Hdf5Float32 `hdf:"HTAB HW Max"`
-	HtabTwMax                Hdf5Float32 `hdf:"HTAB TW Max"`
-	HtabMaxFlow              Hdf5Float32 `hdf:"HTAB Max Flow"`
-	CellSpacingNear          float32     `hdf:"Cell Spacing Near"`
-	CellSpacingFar           float32     `hdf:"Cell Spacing Far"`
-	NearRepeats              int32       `hdf:"Near Repeats"`
-	ProtectionRadius         uint8       `hdf:"Protection Radius"`
-	UseFrictionInMomentum    uint8       `hdf:"Use Friction in Momentum"`
-	UseWeightInMomentum      uint8       `hdf:"Use Weight in Momentum"`
-	UseCriticalUs            uint8       `hdf:"Use Critical US"`
-	UseEgforPressureCriteria uint8       `hdf:"Use EG for Pressure Criteria"`
-	IceOption                int32       `hdf:"Ice Option"`
-	WeirSkew                 Hdf5Float32 `hdf:"Weir Skew"`
-	PierSkew                 Hdf5Float32 `hdf:"Pier Skew"`
-	BrContraction            Hdf5Float32 `hdf:"BR Contraction"`
-	BrExpansion              Hdf5Float32 `hdf:"BR Expansion"`
-	BrPierK                  Hdf5Float32 `hdf:"BR Pier K"`
-	BrPierElev               Hdf5Float32 `hdf:"BR Pier Elev"`
-	BrStructK                Hdf5Float32 `hdf:"BR Struct K"`
-	BrStructElev             Hdf5Float32 `hdf:"BR Struct Elev"`
-	BrStructMann             Hdf5Float32 `hdf:"BR Struct Mann"`
-	BrUsLeftBank             Hdf5Float32 `hdf:"BR US Left Bank"`
-	BrUsRightBank            Hdf5Float32 `hdf:"BR US Right Bank"`
-	BrDsLeftBank             Hdf5Float32 `hdf:"BR DS Left Bank"`
-	BrDsrightBank            Hdf5Float32 `hdf:"BR DS Right Bank"`
-	XsUsLeftBank             Hdf5Float32 `hdf:"XS US Left Bank"`
-	XsUsRightBank            Hdf5Float32 `hdf:"XS US Right Bank"`
-	XsDsLeftBank             Hdf5Float32 `hdf:"XS DS Left Bank"`
-	XsDsRightBank            Hdf5Float32 `hdf:"XS DS Right Bank"`
-	UsIneffLeftSta           Hdf5Float32 `hdf:"US Ineff Left Sta"`
-	UsIneffLeftElev          Hdf5Float32 `hdf:"US Ineff Left Elev"`
-	UsIneffRightSta          Hdf5Float32 `hdf:"US Ineff Right Sta"`
-	UsIneffRightElev         Hdf5Float32 `hdf:"US Ineff Right Elev"`
-	DsIneffLeftSta           Hdf5Float32 `hdf:"DS Ineff Left Sta"`
-	DsIneffLeftElev          Hdf5Float32 `hdf:"DS Ineff Left Elev"`
-	DsIneffRightSta          Hdf5Float32 `hdf:"DS Ineff Right Sta"`
-	DsIneffRightElev         Hdf5Float32 `hdf:"DS Ineff Right Elev"`
-	UseOverrideHwCon         uint8       `hdf:"Use Override HW Connectivity"`
-	UseOverrideTwCon         uint8       `hdf:"Use Override TW Connectivity"`
-	UseRCFamily              uint8       `hdf:"Use RC Family"` //This wasn't present in Randy's example, But is in Bald Eagle from HEC-RAS 6.5
-	UseOverideHTabIBCurves   uint8       `hdf:"Use Override HTabIBCurves"`
-	SnnID                    int32       `hdf:"SNN ID"`
-	DefaultCenterline        uint8       `hdf:"Default Centerline"`
+
+func (sa StructureAttributes) Float(name string) (float64, bool) {
+	switch val := sa[name].(type) {
+	case Hdf5Float32:
+		return float64(val), true
+	case Hdf5Float64:
+		return float64(val), true
+	}
+	return 0, false
 }
 
-func ReadSNetIDToNameFromGeoHDF(filePath string) (map[string]int, error) {
+// Structure is a structure from the Geometry/Structures/Attributes table.  The identifying attributes are copied to
+// fields, every attribute in the file is in Attributes.
+type Structure struct {
+	Index      int                 `json:"index"`
+	Type       string              `json:"type"`
+	River      string              `json:"river,omitempty"`
+	Reach      string              `json:"reach,omitempty"`
+	RS         string              `json:"rs,omitempty"`
+	Connection string              `json:"connection,omitempty"`
+	SNetID     int                 `json:"snet_id"`
+	Attributes StructureAttributes `json:"attributes"`
+}
 
-	f, err := util.OpenFile(filePath)
+// compoundMember is the layout of a member of a compound datatype
+type compoundMember struct {
+	name   string
+	offset int
+	size   int
+	class  hdf5.TypeClass
+}
+
+// ReadStructureCatalog reads every structure in a geometry hdf file.  The compound members of the structure
+// attributes table are read from the file, so the table layout of any RAS version can be read.
+func ReadStructureCatalog(filePath string) ([]Structure, error) {
+	f, err := hdf5.OpenFile(filePath, hdf5.F_ACC_RDONLY)
 	if err != nil {
-		log.Fatalln(err) //does this actually return error? if not, why have the method return error?
+		return nil, fmt.Errorf("unable to open the geometry hdf file %s: %s", filePath, err)
 	}
 	defer f.Close()
+	return ReadStructures(f)
+}
 
-	isversion66 := checkVersion(f)
-
-	if isversion66 {
-		data := []structuresAttr66{}
-		err = util.ReadCompoundAttributes(f, STRUCTURE_DATA_PATH, &data, nil)
+// ReadStructures reads every structure in an open geometry hdf file
+func ReadStructures(f *hdf5.File) ([]Structure, error) {
+	dset, err := f.OpenDataset(STRUCTURE_DATA_PATH)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the structure attributes %s: %s", STRUCTURE_DATA_PATH, err)
+	}
+	defer dset.Close()
+	dtype, err := dset.Datatype()
+	if err != nil {
+		return nil, err
+	}
+	defer dtype.Close()
+	if dtype.Class() != hdf5.T_COMPOUND {
+		return nil, fmt.Errorf("the structure attributes %s are not a compound dataset", STRUCTURE_DATA_PATH)
+	}
+	ctype := hdf5.CompoundType{Datatype: *dtype}
+	members := make([]compoundMember, ctype.NMembers())
+	for i := range members {
+		mtype, err := ctype.MemberType(i)
 		if err != nil {
-			log.Fatalln(err)
+			return nil, fmt.Errorf("unable to read the type of structure attribute %s: %s", ctype.MemberName(i), err)
 		}
-		snetToName := make(map[string]int, len(data))
-		for _, structure := range data {
-			fmt.Println(structure.Connection)
-			fmt.Println(structure.SnnID)
-			snetToName[structure.Connection] = int(structure.SnnID)
+		members[i] = compoundMember{
+			name:   strings.TrimSpace(ctype.MemberName(i)),
+			offset: ctype.MemberOffset(i),
+			size:   int(mtype.Size()),
+			class:  mtype.Class(),
 		}
-		return snetToName, nil
-	} else {
-		data := []structuresAttr{}
-		err = util.ReadCompoundAttributes(f, STRUCTURE_DATA_PATH, &data, nil)
+		mtype.Close()
+	}
+	space := dset.Space()
+	defer space.Close()
+	rowSize := int(dtype.Size())
+	raw := make([]byte, rowSize*space.SimpleExtentNPoints())
+	if len(raw) > 0 {
+		err = dset.Read(&raw)
 		if err != nil {
-			log.Fatalln(err)
+			return nil, fmt.Errorf("unable to read the structure attributes: %s", err)
 		}
-		snetToName := make(map[string]int, len(data))
-		for _, structure := range data {
-			fmt.Println(structure.Connection)
-			fmt.Println(structure.SnnID)
-			snetToName[structure.Connection] = int(structure.SnnID)
-		}
-		return snetToName, nil
 	}
+	return decodeStructures(members, rowSize, raw)
 }
-func checkVersion(f *hdf5.File) bool {
-	srcrootgroup, err := f.OpenGroup("/")
-	if err != nil {
-		log.Fatalln(err)
+
+// decodeStructures decodes rows of the structure attributes table
+func decodeStructures(members []compoundMember, rowSize int, raw []byte) ([]Structure, error) {
+	if rowSize <= 0 || len(raw)%rowSize != 0 {
+		return nil, fmt.Errorf("structure attribute data of %d bytes is not a whole number of %d byte rows", len(raw), rowSize)
 	}
-	defer srcrootgroup.Close()
-	attr, err := srcrootgroup.OpenAttribute("File Version")
-	if err != nil {
-		log.Fatalln(err)
+	structures := make([]Structure, len(raw)/rowSize)
+	for idx := range structures {
+		row := raw[idx*rowSize : (idx+1)*rowSize]
+		attrs := make(StructureAttributes, len(members))
+		for _, member := range members {
+			if member.offset+member.size > rowSize {
+				return nil, fmt.Errorf("structure attribute %s is outside of the row", member.name)
+			}
+			val, err := decodeMember(member, row[member.offset:member.offset+member.size])
+			if err != nil {
+				return nil, err
+			}
+			attrs[member.name] = val
+		}
+		snetID, _ := attrs.Int("SNN ID")
+		structures[idx] = Structure{
+			Index:      idx,
+			Type:       attrs.String("Type"),
+			River:      attrs.String("River"),
+			Reach:      attrs.String("Reach"),
+			RS:         attrs.String("RS"),
+			Connection: attrs.String("Connection"),
+			SNetID:     snetID,
+			Attributes: attrs,
+		}
 	}
-	defer attr.Close()
+	return structures, nil
+}
 
-	var attrdata string
-	attr.Read(&attrdata, hdf5.T_GO_STRING)
-	return strings.Contains(attrdata, "6.6")
+// decodeMember decodes a little endian compound member.  One byte integers are the unsigned flags written by RAS,
+// larger integers are signed.  Members of other classes are kept as raw bytes.
+func decodeMember(member compoundMember, b []byte) (any, error) {
+	switch member.class {
+	case hdf5.T_STRING:
+		return strings.TrimSpace(string(bytes.TrimRight(b, "\x00"))), nil
+	case hdf5.T_INTEGER:
+		switch member.size {
+		case 1:
+			return int64(b[0]), nil
+		case 2:
+			return int64(int16(binary.LittleEndian.Uint16(b))), nil
+		case 4:
+			return int64(int32(binary.LittleEndian.Uint32(b))), nil
+		case 8:
+			return int64(binary.LittleEndian.Uint64(b)), nil
+		}
+	case hdf5.T_FLOAT:
+		switch member.size {
+		case 4:
+			return Hdf5Float32(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+		case 8:
+			return Hdf5Float64(math.Float64frombits(binary.LittleEndian.Uint64(b))), nil
+		}
+	default:
+		return append([]byte{}, b...), nil
+	}
+	return nil, fmt.Errorf("unsupported %d byte size for structure attribute %s", member.size, member.name)
+}
 
+// ReadSNetIDToNameFromGeoHDF maps the connection name of each structure to its SNET ID
+func ReadSNetIDToNameFromGeoHDF(filePath string) (map[string]int, error) {
+	structures, err := ReadStructureCatalog(filePath)
+	if err != nil {
+		return nil, err
+	}
+	snetToName := make(map[string]int, len(structures))
+	for _, structure := range structures {
+		snetToName[structure.Connection] = structure.SNetID
+	}
+	return snetToName, nil
 }
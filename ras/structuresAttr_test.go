@@ -1,6 +1,13 @@
 package ras
 
-import "testing"
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/usace-cloud-compute/go-hdf5"
+)
 
 func Test(t *testing.T) {
 	actual, err := ReadSNetIDToNameFromGeoHDF("/workspaces/cc-ras-runner/testData/Duwamish_17110013.g01.hdf")
@@ -14,3 +21,78 @@ func Test(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestDecodeStructures(t *testing.T) {
+	// two layouts of the same structure, the second with an added float member as in newer RAS versions
+	layouts := [][]compoundMember{
+		{
+			{name: "Type", offset: 0, size: 16, class: hdf5.T_STRING},
+			{name: "Connection", offset: 16, size: 16, class: hdf5.T_STRING},
+			{name: "Weir Coef", offset: 32, size: 4, class: hdf5.T_FLOAT},
+			{name: "SNN ID", offset: 36, size: 4, class: hdf5.T_INTEGER},
+			{name: "Default Centerline", offset: 40, size: 1, class: hdf5.T_INTEGER},
+		},
+		{
+			{name: "Type", offset: 0, size: 16, class: hdf5.T_STRING},
+			{name: "Connection", offset: 16, size: 16, class: hdf5.T_STRING},
+			{name: "Weir Coef", offset: 32, size: 4, class: hdf5.T_FLOAT},
+			{name: "BR Pier K", offset: 36, size: 4, class: hdf5.T_FLOAT},
+			{name: "SNN ID", offset: 40, size: 4, class: hdf5.T_INTEGER},
+			{name: "Default Centerline", offset: 44, size: 1, class: hdf5.T_INTEGER},
+		},
+	}
+	for _, members := range layouts {
+		last := members[len(members)-1]
+		rowSize := last.offset + last.size
+		raw := make([]byte, rowSize*2)
+		for row, name := range []string{"Highway 120", "Dam"} {
+			b := raw[row*rowSize : (row+1)*rowSize]
+			for _, member := range members {
+				field := b[member.offset : member.offset+member.size]
+				switch member.name {
+				case "Type":
+					copy(field, "Connection")
+				case "Connection":
+					copy(field, name)
+				case "Weir Coef":
+					binary.LittleEndian.PutUint32(field, math.Float32bits(2.6))
+				case "BR Pier K":
+					binary.LittleEndian.PutUint32(field, math.Float32bits(float32(math.NaN())))
+				case "SNN ID":
+					binary.LittleEndian.PutUint32(field, uint32(row+2))
+				case "Default Centerline":
+					field[0] = 1
+				}
+			}
+		}
+		structures, err := decodeStructures(members, rowSize, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(structures) != 2 {
+			t.Fatalf("expected 2 structures, found %d", len(structures))
+		}
+		highway := structures[0]
+		if highway.Connection != "Highway 120" || highway.SNetID != 2 || highway.Type != "Connection" {
+			t.Errorf("unexpected structure %+v", highway)
+		}
+		if coef, ok := highway.Attributes.Float("Weir Coef"); !ok || math.Abs(coef-2.6) > 1e-6 {
+			t.Errorf("unexpected weir coefficient %v", highway.Attributes["Weir Coef"])
+		}
+		if centerline, ok := highway.Attributes.Int("Default Centerline"); !ok || centerline != 1 {
+			t.Errorf("unexpected default centerline %v", highway.Attributes["Default Centerline"])
+		}
+		if len(highway.Attributes) != len(members) {
+			t.Errorf("expected %d attributes, found %d", len(members), len(highway.Attributes))
+		}
+		if _, err := json.Marshal(structures); err != nil {
+			t.Errorf("unable to marshal structures with NaN attributes: %s", err)
+		}
+		if structures[1].Connection != "Dam" || structures[1].SNetID != 3 {
+			t.Errorf("unexpected structure %+v", structures[1])
+		}
+	}
+	if _, err := decodeStructures(layouts[0], 41, make([]byte, 50)); err == nil {
+		t.Error("expected an error for a partial row")
+	}
+}
@@ -0,0 +1,283 @@
+package zarr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"path"
+	"slices"
+)
+
+/*
+zarr builds Zarr v2 stores in memory.  A Store is the keys and contents of a Zarr directory tree: the .zgroup, .zarray
+and .zattrs metadata documents and the chunks of each array.  Arrays are written in C order with little endian values
+and the default "." chunk key separator.  Edge chunks are padded with the fill value to the full chunk shape as the
+specification requires.
+
+Chunks are compressed with the zlib or gzip codecs of numcodecs, or are not compressed when the compressor is nil.
+*/
+
+const ZARR_FORMAT int = 2
+
+// Compressor is the numcodecs codec of the chunks of an array
+type Compressor struct {
+	ID    string `json:"id"`
+	Level int    `json:"level"`
+}
+
+// supported compressor ids
+const (
+	ZLIB string = "zlib"
+	GZIP string = "gzip"
+)
+
+// Number is the value types of arrays
+type Number interface {
+	int8 | int16 | int32 | int64 | float32 | float64
+}
+
+type zarrayMetadata struct {
+	ZarrFormat int         `json:"zarr_format"`
+	Shape      []int       `json:"shape"`
+	Chunks     []int       `json:"chunks"`
+	Dtype      string      `json:"dtype"`
+	Compressor *Compressor `json:"compressor"`
+	FillValue  any         `json:"fill_value"`
+	Order      string      `json:"order"`
+	Filters    []any       `json:"filters"`
+}
+
+// Store is the contents of a Zarr directory tree by key
+type Store map[string][]byte
+
+// NewStore creates a store with a root group
+func NewStore(attrs map[string]any) (Store, error) {
+	s := Store{}
+	err := s.AddGroup("", attrs)
+	return s, err
+}
+
+// Keys are the keys of the store in sorted order
+func (s Store) Keys() []string {
+	return slices.Sorted(maps.Keys(s))
+}
+
+// AddGroup adds a group and its attributes.  The root group has an empty path.
+func (s Store) AddGroup(groupPath string, attrs map[string]any) error {
+	err := s.putJson(path.Join(groupPath, ".zgroup"), map[string]int{"zarr_format": ZARR_FORMAT})
+	if err != nil {
+		return err
+	}
+	return s.UpdateAttributes(groupPath, attrs)
+}
+
+// UpdateAttributes adds attributes to the .zattrs of a group or array, replacing attributes with the same names
+func (s Store) UpdateAttributes(nodePath string, attrs map[string]any) error {
+	if len(attrs) == 0 {
+		return nil
+	}
+	key := path.Join(nodePath, ".zattrs")
+	current := map[string]any{}
+	if content, ok := s[key]; ok {
+		err := json.Unmarshal(content, &current)
+		if err != nil {
+			return fmt.Errorf("invalid attributes %s: %s", key, err)
+		}
+	}
+	maps.Copy(current, attrs)
+	return s.putJson(key, current)
+}
+
+func (s Store) putJson(key string, val any) error {
+	content, err := json.MarshalIndent(val, "", "    ")
+	if err != nil {
+		return err
+	}
+	s[key] = content
+	return nil
+}
+
+// AddArray adds an array with values in C order of the shape, split in chunks of the chunk shape.  Float arrays have
+// a NaN fill value and integer arrays a zero fill value.
+func AddArray[T Number](s Store, arrayPath string, values []T, shape []int, chunks []int, compressor *Compressor, attrs map[string]any) error {
+	if len(shape) != len(chunks) {
+		return fmt.Errorf("array %s has %d dimensions and %d chunk dimensions", arrayPath, len(shape), len(chunks))
+	}
+	count := 1
+	for i := range shape {
+		if chunks[i] <= 0 {
+			return fmt.Errorf("array %s chunk dimension %d is not positive", arrayPath, i)
+		}
+		count *= shape[i]
+	}
+	if count != len(values) {
+		return fmt.Errorf("array %s has %d values for shape %v", arrayPath, len(values), shape)
+	}
+	if compressor != nil && compressor.ID != ZLIB && compressor.ID != GZIP {
+		return fmt.Errorf("unsupported compressor %q", compressor.ID)
+	}
+	dtype, fill := arrayType[T]()
+	meta := zarrayMetadata{
+		ZarrFormat: ZARR_FORMAT,
+		Shape:      shape,
+		Chunks:     chunks,
+		Dtype:      dtype,
+		Compressor: compressor,
+		FillValue:  fill,
+		Order:      "C",
+		Filters:    nil,
+	}
+	err := s.putJson(path.Join(arrayPath, ".zarray"), meta)
+	if err != nil {
+		return err
+	}
+	err = s.UpdateAttributes(arrayPath, attrs)
+	if err != nil {
+		return err
+	}
+
+	grid := make([]int, len(shape))
+	for i := range shape {
+		grid[i] = (shape[i] + chunks[i] - 1) / chunks[i]
+	}
+	for _, chunk := range gridIndexes(grid) {
+		content, err := encodeChunk(chunkValues(values, shape, chunks, chunk), compressor)
+		if err != nil {
+			return err
+		}
+		s[path.Join(arrayPath, chunkKey(chunk))] = content
+	}
+	return nil
+}
+
+// arrayType is the Zarr dtype and fill value of a value type
+func arrayType[T Number]() (string, any) {
+	switch any(*new(T)).(type) {
+	case int8:
+		return "|i1", 0
+	case int16:
+		return "<i2", 0
+	case int32:
+		return "<i4", 0
+	case int64:
+		return "<i8", 0
+	case float32:
+		return "<f4", "NaN"
+	}
+	return "<f8", "NaN"
+}
+
+// gridIndexes lists the indexes of a grid in C order
+func gridIndexes(grid []int) [][]int {
+	indexes := [][]int{{}}
+	for _, n := range grid {
+		next := make([][]int, 0, len(indexes)*n)
+		for _, idx := range indexes {
+			for i := range n {
+				next = append(next, append(slices.Clone(idx), i))
+			}
+		}
+		indexes = next
+	}
+	return indexes
+}
+
+// chunkValues copies the values of a chunk, padding the values outside of the array with the fill value.  Values are
+// copied a row of the last dimension at a time.
+func chunkValues[T Number](values []T, shape []int, chunks []int, chunk []int) []T {
+	if len(shape) == 0 {
+		return values
+	}
+	last := len(shape) - 1
+	fill := fillValue[T]()
+	out := make([]T, 0, product(chunks))
+	for _, offset := range gridIndexes(chunks[:last]) {
+		idx := 0
+		inside := true
+		for d := range last {
+			pos := chunk[d]*chunks[d] + offset[d]
+			if pos >= shape[d] {
+				inside = false
+				break
+			}
+			idx = idx*shape[d] + pos
+		}
+		n := 0
+		if inside {
+			start := chunk[last] * chunks[last]
+			n = min(chunks[last], shape[last]-start)
+			out = append(out, values[idx*shape[last]+start:idx*shape[last]+start+n]...)
+		}
+		for range chunks[last] - n {
+			out = append(out, fill)
+		}
+	}
+	return out
+}
+
+func fillValue[T Number]() T {
+	var fill T
+	switch v := any(&fill).(type) {
+	case *float32:
+		*v = float32(math.NaN())
+	case *float64:
+		*v = math.NaN()
+	}
+	return fill
+}
+
+func product(vals []int) int {
+	p := 1
+	for _, v := range vals {
+		p *= v
+	}
+	return p
+}
+
+func chunkKey(chunk []int) string {
+	key := ""
+	for i, c := range chunk {
+		if i > 0 {
+			key += "."
+		}
+		key += fmt.Sprint(c)
+	}
+	if key == "" {
+		return "0"
+	}
+	return key
+}
+
+func encodeChunk[T Number](values []T, compressor *Compressor) ([]byte, error) {
+	var raw bytes.Buffer
+	err := binary.Write(&raw, binary.LittleEndian, values)
+	if err != nil {
+		return nil, err
+	}
+	if compressor == nil {
+		return raw.Bytes(), nil
+	}
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch compressor.ID {
+	case ZLIB:
+		w, err = zlib.NewWriterLevel(&buf, compressor.Level)
+	case GZIP:
+		w, err = gzip.NewWriterLevel(&buf, compressor.Level)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(raw.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	return buf.Bytes(), err
+}
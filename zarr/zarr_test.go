@@ -0,0 +1,78 @@
+package zarr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestAddArray(t *testing.T) {
+	s, err := NewStore(map[string]any{"block": "refline-series"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := []float32{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	err = AddArray(s, "Flow/data", values, []int{3, 3}, []int{2, 2}, nil, map[string]any{"_ARRAY_DIMENSIONS": []string{"time", "column"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".zattrs", ".zgroup", "Flow/data/.zarray", "Flow/data/.zattrs", "Flow/data/0.0", "Flow/data/0.1", "Flow/data/1.0", "Flow/data/1.1"}
+	if keys := s.Keys(); !reflect.DeepEqual(keys, want) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+	var meta map[string]any
+	err = json.Unmarshal(s["Flow/data/.zarray"], &meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta["dtype"] != "<f4" || meta["fill_value"] != "NaN" || meta["order"] != "C" || meta["compressor"] != nil || meta["zarr_format"] != 2.0 {
+		t.Errorf("unexpected array metadata %v", meta)
+	}
+	//the edge chunk is padded with the fill value
+	chunk := make([]float32, 4)
+	binary.Read(bytes.NewReader(s["Flow/data/1.1"]), binary.LittleEndian, chunk)
+	if chunk[0] != 9 || !math.IsNaN(float64(chunk[1])) || !math.IsNaN(float64(chunk[2])) || !math.IsNaN(float64(chunk[3])) {
+		t.Errorf("unexpected edge chunk %v", chunk)
+	}
+	binary.Read(bytes.NewReader(s["Flow/data/0.1"]), binary.LittleEndian, chunk)
+	if chunk[0] != 3 || chunk[2] != 6 {
+		t.Errorf("unexpected chunk %v", chunk)
+	}
+
+	err = s.UpdateAttributes("", map[string]any{"event_identifier": "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attrs map[string]any
+	json.Unmarshal(s[".zattrs"], &attrs)
+	if attrs["block"] != "refline-series" || attrs["event_identifier"] != "7" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
+
+func TestCompressedArray(t *testing.T) {
+	s := Store{}
+	err := AddArray(s, "max", []int32{4, 5}, []int{2}, []int{2}, &Compressor{ID: ZLIB, Level: 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zlib.NewReader(bytes.NewReader(s["max/0"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(r)
+	if !bytes.Equal(raw, []byte{4, 0, 0, 0, 5, 0, 0, 0}) {
+		t.Errorf("unexpected chunk %v", raw)
+	}
+	if err = AddArray(s, "min", []int32{4, 5}, []int{2}, []int{2}, &Compressor{ID: "blosc"}, nil); err == nil {
+		t.Error("expected an error for an unsupported compressor")
+	}
+	if err = AddArray(s, "min", []int32{4, 5, 6}, []int{2}, []int{2}, nil, nil); err == nil {
+		t.Error("expected an error for values that do not match the shape")
+	}
+}